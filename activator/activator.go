@@ -0,0 +1,203 @@
+package activator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Report is posted by the activator to the activity endpoint of the controller manager
+type Report struct {
+	Namespace    string    `json:"namespace"`
+	Name         string    `json:"name"`
+	LastActivity time.Time `json:"lastActivity"`
+}
+
+// Options bulabula
+type Options struct {
+	// Backend is the private service of runtime
+	Backend *url.URL
+
+	// Runtime is reported along with the activity
+	Runtime types.NamespacedName
+
+	// ActivityURL is the activity endpoint of the controller manager
+	ActivityURL string
+
+	// TokenFile holds the service account token the activity is reported with, read on every report
+	// since the kubelet rotates it
+	TokenFile string
+
+	// ActivationTimeout bounds how long a request is buffered while runtime is scaled up from zero
+	ActivationTimeout time.Duration
+
+	// ReportInterval is how often the last activity is reported while runtime receives requests
+	ReportInterval time.Duration
+
+	// RetryInterval is how often the backend is dialed again while runtime is scaled up from zero
+	RetryInterval time.Duration
+}
+
+// Activator proxies requests to runtime, buffering them until the backend accepts connections
+// and reporting the activity so that runtime is scaled up from zero and down again once idle
+type Activator struct {
+	opts   Options
+	log    logr.Logger
+	proxy  *httputil.ReverseProxy
+	client *http.Client
+
+	mu           sync.Mutex
+	lastActivity time.Time
+	reported     time.Time
+	activate     chan struct{}
+}
+
+// New bulabula
+func New(opts Options, log logr.Logger) *Activator {
+	a := &Activator{
+		opts:     opts,
+		log:      log,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		activate: make(chan struct{}, 1),
+	}
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}
+	a.proxy = httputil.NewSingleHostReverseProxy(opts.Backend)
+	a.proxy.Transport = &http.Transport{
+		DialContext:     a.dialContext(dialer),
+		MaxIdleConns:    100,
+		IdleConnTimeout: 90 * time.Second,
+	}
+	a.proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		a.log.Error(err, "unable to proxy request", "path", req.URL.Path)
+		w.WriteHeader(http.StatusBadGateway)
+	}
+
+	return a
+}
+
+// ServeHTTP bulabula
+func (a *Activator) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	a.touch(time.Now())
+	a.proxy.ServeHTTP(w, req)
+}
+
+// Run reports the activity until ctx is done
+func (a *Activator) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.opts.ReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-a.activate:
+		}
+		if err := a.report(ctx); err != nil {
+			a.log.Error(err, "unable to report activity")
+		}
+	}
+}
+
+func (a *Activator) touch(now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if now.After(a.lastActivity) {
+		a.lastActivity = now
+	}
+}
+
+// requestActivation wakes up Run to report the activity right away
+func (a *Activator) requestActivation() {
+	select {
+	case a.activate <- struct{}{}:
+	default:
+	}
+}
+
+// dialContext retries dialing the backend until it accepts connections or the activation times out,
+// the private service refuses connections while runtime has no ready pod
+func (a *Activator) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		deadline := time.Now().Add(a.opts.ActivationTimeout)
+		for {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err == nil {
+				return conn, nil
+			}
+
+			a.requestActivation()
+			if time.Now().After(deadline) {
+				return nil, fmt.Errorf("runtime %s is not activated within %s: %w", a.opts.Runtime, a.opts.ActivationTimeout, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(a.opts.RetryInterval):
+			}
+		}
+	}
+}
+
+func (a *Activator) report(ctx context.Context) error {
+	a.mu.Lock()
+	lastActivity, reported := a.lastActivity, a.reported
+	a.mu.Unlock()
+
+	if !lastActivity.After(reported) {
+		return nil
+	}
+
+	body, err := json.Marshal(Report{
+		Namespace:    a.opts.Runtime.Namespace,
+		Name:         a.opts.Runtime.Name,
+		LastActivity: lastActivity,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.opts.ActivityURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.opts.TokenFile != "" {
+		token, err := ioutil.ReadFile(a.opts.TokenFile)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("activity endpoint responded with %s", resp.Status)
+	}
+
+	a.mu.Lock()
+	if lastActivity.After(a.reported) {
+		a.reported = lastActivity
+	}
+	a.mu.Unlock()
+
+	return nil
+}
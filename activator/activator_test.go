@@ -0,0 +1,133 @@
+package activator
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestActivatorBuffersUntilBackendIsUp(t *testing.T) {
+	// Reserve an address for the backend which refuses connections until it is started
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	dir, err := ioutil.TempDir("", "kess-activator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	token := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(token, []byte("activator-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	reports := make(chan Report, 10)
+	activity := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if auth := req.Header.Get("Authorization"); auth != "Bearer activator-token" {
+			t.Errorf("activity must be reported with the service account token, got %q", auth)
+		}
+		var report Report
+		if err := json.NewDecoder(req.Body).Decode(&report); err != nil {
+			t.Error(err)
+		}
+		reports <- report
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer activity.Close()
+
+	a := New(Options{
+		Backend:           &url.URL{Scheme: "http", Host: addr},
+		Runtime:           types.NamespacedName{Name: "sample", Namespace: "kess-samples"},
+		ActivityURL:       activity.URL,
+		TokenFile:         token,
+		ActivationTimeout: 5 * time.Second,
+		ReportInterval:    time.Hour,
+		RetryInterval:     10 * time.Millisecond,
+	}, ctrl.Log)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.Run(ctx)
+
+	proxy := httptest.NewServer(a)
+	defer proxy.Close()
+
+	// The controller scales runtime up once the activation is reported
+	go func() {
+		report := <-reports
+		if report.Name != "sample" || report.Namespace != "kess-samples" || report.LastActivity.IsZero() {
+			t.Errorf("unexpected report %+v", report)
+		}
+
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		backend := &httptest.Server{
+			Listener: listener,
+			Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Write([]byte("hello " + req.URL.Path))
+			})},
+		}
+		backend.Start()
+		go func() {
+			<-ctx.Done()
+			backend.Close()
+		}()
+	}()
+
+	resp, err := http.Get(proxy.URL + "/fn/sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "hello /fn/sample" {
+		t.Fatalf("unexpected response %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestActivatorTimesOut(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	a := New(Options{
+		Backend:           &url.URL{Scheme: "http", Host: addr},
+		Runtime:           types.NamespacedName{Name: "sample", Namespace: "kess-samples"},
+		ActivationTimeout: 50 * time.Millisecond,
+		ReportInterval:    time.Hour,
+		RetryInterval:     10 * time.Millisecond,
+	}, ctrl.Log)
+
+	proxy := httptest.NewServer(a)
+	defer proxy.Close()
+
+	resp, err := http.Get(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected bad gateway once the activation times out, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,152 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+)
+
+// Metric labels identifying the function version of a request
+const (
+	LabelFunction = "function"
+	LabelVersion  = "version"
+	LabelCode     = "code"
+)
+
+// Scraper collects the request metrics of a function version from the metrics endpoints of runtime pods
+type Scraper struct {
+	Client *http.Client
+}
+
+// NewScraper bulabula
+func NewScraper(timeout time.Duration) *Scraper {
+	return &Scraper{Client: &http.Client{Timeout: timeout}}
+}
+
+// Scrape sums the metrics of function version exposed at urls, failing endpoints are skipped, the drop of
+// the sum is then taken as a counter reset, the scrape only fails when every endpoint failed
+func (s *Scraper) Scrape(ctx context.Context, urls []string, function, version string, analysis *corev1.RolloutAnalysis) (corev1.RolloutMetrics, error) {
+	var (
+		total   corev1.RolloutMetrics
+		lastErr error
+		scraped int
+	)
+
+	for _, url := range urls {
+		metrics, err := s.scrape(ctx, url, function, version, analysis)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		scraped++
+		total.Requests += metrics.Requests
+		total.Errors += metrics.Errors
+		total.LatencyMilliseconds += metrics.LatencyMilliseconds
+	}
+	if scraped == 0 && lastErr != nil {
+		return corev1.RolloutMetrics{}, fmt.Errorf("every one of %d endpoints failed, last: %w", len(urls), lastErr)
+	}
+	return total, nil
+}
+
+func (s *Scraper) scrape(ctx context.Context, url, function, version string, analysis *corev1.RolloutAnalysis) (corev1.RolloutMetrics, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return corev1.RolloutMetrics{}, err
+	}
+	resp, err := s.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return corev1.RolloutMetrics{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return corev1.RolloutMetrics{}, fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+	return Parse(resp.Body, function, version, analysis)
+}
+
+// Parse reads the metrics of function version from the Prometheus text format, requests answered with
+// a 5xx code are counted as errors
+func Parse(in io.Reader, function, version string, analysis *corev1.RolloutAnalysis) (corev1.RolloutMetrics, error) {
+	var (
+		parser  expfmt.TextParser
+		metrics corev1.RolloutMetrics
+		seconds float64
+	)
+
+	families, err := parser.TextToMetricFamilies(in)
+	if err != nil {
+		return metrics, err
+	}
+
+	for _, m := range matching(families[analysis.RequestsMetric], function, version) {
+		value := int64(counterValue(m))
+		metrics.Requests += value
+		if strings.HasPrefix(labelValue(m, LabelCode), "5") {
+			metrics.Errors += value
+		}
+	}
+
+	if family, ok := families[analysis.LatencyMetric]; ok {
+		for _, m := range matching(family, function, version) {
+			switch {
+			case m.Histogram != nil:
+				seconds += m.Histogram.GetSampleSum()
+			case m.Summary != nil:
+				seconds += m.Summary.GetSampleSum()
+			}
+		}
+	} else {
+		// Without a TYPE line the sum of a histogram or summary is parsed as an untyped family
+		for _, m := range matching(families[analysis.LatencyMetric+"_sum"], function, version) {
+			seconds += counterValue(m)
+		}
+	}
+	metrics.LatencyMilliseconds = int64(math.Round(seconds * 1000))
+
+	return metrics, nil
+}
+
+// matching returns the metrics of family labeled with function version
+func matching(family *dto.MetricFamily, function, version string) []*dto.Metric {
+	if family == nil {
+		return nil
+	}
+	var result []*dto.Metric
+	for _, m := range family.Metric {
+		if labelValue(m, LabelFunction) == function && labelValue(m, LabelVersion) == version {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, label := range m.Label {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
+
+func counterValue(m *dto.Metric) float64 {
+	switch {
+	case m.Counter != nil:
+		return m.Counter.GetValue()
+	case m.Untyped != nil:
+		return m.Untyped.GetValue()
+	case m.Gauge != nil:
+		return m.Gauge.GetValue()
+	}
+	return 0
+}
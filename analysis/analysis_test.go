@@ -0,0 +1,72 @@
+package analysis
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+)
+
+const testMetrics = `# TYPE kess_runtime_requests_total counter
+kess_runtime_requests_total{function="sample",version="v2",code="200"} 90
+kess_runtime_requests_total{function="sample",version="v2",code="503"} 10
+kess_runtime_requests_total{function="sample",version="v1",code="500"} 50
+# TYPE kess_runtime_request_duration_seconds histogram
+kess_runtime_request_duration_seconds_bucket{function="sample",version="v2",le="+Inf"} 100
+kess_runtime_request_duration_seconds_sum{function="sample",version="v2"} 12.5
+kess_runtime_request_duration_seconds_count{function="sample",version="v2"} 100
+`
+
+func testAnalysis() *corev1.RolloutAnalysis {
+	rollout := &corev1.Rollout{Spec: corev1.RolloutSpec{Analysis: &corev1.RolloutAnalysis{}}}
+	rollout.Default()
+	return rollout.Spec.Analysis
+}
+
+func TestParse(t *testing.T) {
+	metrics, err := Parse(strings.NewReader(testMetrics), "sample", "v2", testAnalysis())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := corev1.RolloutMetrics{Requests: 100, Errors: 10, LatencyMilliseconds: 12500}
+	if metrics != want {
+		t.Errorf("unexpected metrics %+v, want %+v", metrics, want)
+	}
+
+	untyped := "kess_runtime_request_duration_seconds_sum{function=\"sample\",version=\"v2\"} 1.5\n"
+	if metrics, _ := Parse(strings.NewReader(untyped), "sample", "v2", testAnalysis()); metrics.LatencyMilliseconds != 1500 {
+		t.Errorf("untyped latency sum must be read, got %+v", metrics)
+	}
+}
+
+func TestScrape(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/metrics" {
+			http.NotFound(w, req)
+			return
+		}
+		w.Write([]byte(testMetrics))
+	}))
+	defer server.Close()
+
+	scraper := NewScraper(time.Second)
+	metrics, err := scraper.Scrape(context.Background(), []string{server.URL + "/metrics", server.URL + "/metrics"}, "sample", "v2", testAnalysis())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics.Requests != 200 || metrics.Errors != 20 {
+		t.Errorf("metrics of all pods must be summed, got %+v", metrics)
+	}
+
+	metrics, err = scraper.Scrape(context.Background(), []string{server.URL + "/metrics", server.URL + "/missing"}, "sample", "v2", testAnalysis())
+	if err != nil || metrics.Requests != 100 {
+		t.Errorf("a failing endpoint must be skipped, got %+v, %v", metrics, err)
+	}
+	if _, err := scraper.Scrape(context.Background(), []string{server.URL + "/missing"}, "sample", "v2", testAnalysis()); err == nil {
+		t.Error("every endpoint failing must fail the scrape")
+	}
+}
@@ -0,0 +1,174 @@
+package v1
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Chunk Constants bulabula
+const (
+	// ChunkIndexKey is the key of a config map describing its chunked keys
+	ChunkIndexKey = ".kess-chunks.json"
+	// ChunkKey is the key of a chunk config map holding one chunk of content
+	ChunkKey = "chunk"
+	// ChunkSize bounds the content of one chunk config map
+	ChunkSize = 768 << 10
+	// ChunkConfigMapLimit bounds the content kept inline, leaving room under the 1 MiB limit of a config map
+	ChunkConfigMapLimit = 960 << 10
+)
+
+// Chunk describes a key of a config map whose content is split across chunk config maps
+type Chunk struct {
+	// The hex encoded sha256 of content
+	SHA256 string `json:"sha256"`
+	// The size of content in bytes
+	Size int `json:"size"`
+	// Whether content belongs to binary data
+	Binary bool `json:"binary,omitempty"`
+	// The chunk config maps holding content in order
+	ConfigMaps []string `json:"configMaps"`
+}
+
+// ChunkIndex returns the chunked keys of config map
+func ChunkIndex(cm *apiv1.ConfigMap) map[string]Chunk {
+	index := make(map[string]Chunk)
+	if data, ok := cm.Data[ChunkIndexKey]; ok {
+		json.Unmarshal([]byte(data), &index)
+	}
+	return index
+}
+
+// ChunkConfigMapNames returns the chunk config maps referenced by config map sorted by name
+func ChunkConfigMapNames(cm *apiv1.ConfigMap) []string {
+	var (
+		names []string
+		seen  = make(map[string]bool)
+	)
+	for _, chunk := range ChunkIndex(cm) {
+		for _, name := range chunk.ConfigMaps {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ChunkConfigMap moves the largest keys of config map into chunk config maps until the content left inline fits
+// ChunkConfigMapLimit, and returns the chunk config maps created, chunks are named after their content so
+// unchanged content keeps its chunks
+func ChunkConfigMap(cm *apiv1.ConfigMap) []apiv1.ConfigMap {
+	var (
+		index  = ChunkIndex(cm)
+		chunks []apiv1.ConfigMap
+	)
+	for configMapSize(cm) > ChunkConfigMapLimit {
+		key, content, binary := largestKey(cm)
+		if key == "" {
+			break
+		}
+
+		sum := sha256.Sum256(content)
+		chunk := Chunk{SHA256: hex.EncodeToString(sum[:]), Size: len(content), Binary: binary}
+		for i := 0; i*ChunkSize < len(content); i++ {
+			end := (i + 1) * ChunkSize
+			if end > len(content) {
+				end = len(content)
+			}
+			chunkConfigMap := newChunkConfigMap(cm, fmt.Sprintf("%s-chunk-%s-%d", cm.Name, chunk.SHA256[:12], i), content[i*ChunkSize:end])
+			chunk.ConfigMaps = append(chunk.ConfigMaps, chunkConfigMap.Name)
+			chunks = append(chunks, chunkConfigMap)
+		}
+		index[key] = chunk
+
+		delete(cm.Data, key)
+		delete(cm.BinaryData, key)
+		setChunkIndex(cm, index)
+	}
+	return chunks
+}
+
+// UnsetChunk drops key from the chunk index of config map, so content set inline again is not shadowed
+func UnsetChunk(cm *apiv1.ConfigMap, key string) {
+	index := ChunkIndex(cm)
+	if _, ok := index[key]; !ok {
+		return
+	}
+	delete(index, key)
+	setChunkIndex(cm, index)
+}
+
+func setChunkIndex(cm *apiv1.ConfigMap, index map[string]Chunk) {
+	if len(index) == 0 {
+		delete(cm.Data, ChunkIndexKey)
+		return
+	}
+	data, _ := json.Marshal(index)
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[ChunkIndexKey] = string(data)
+}
+
+func newChunkConfigMap(cm *apiv1.ConfigMap, name string, content []byte) apiv1.ConfigMap {
+	return apiv1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cm.Namespace,
+			Labels: map[string]string{
+				LabelType:      TypeChunk,
+				LabelConfigMap: cm.Name,
+			},
+		},
+		BinaryData: map[string][]byte{ChunkKey: content},
+	}
+}
+
+// configMapSize returns the size of the keys and content of config map
+func configMapSize(cm *apiv1.ConfigMap) int {
+	size := 0
+	for key, data := range cm.Data {
+		size += len(key) + len(data)
+	}
+	for key, data := range cm.BinaryData {
+		size += len(key) + len(data)
+	}
+	return size
+}
+
+// largestKey returns the largest key of config map other than the chunk index, ties are broken by key
+func largestKey(cm *apiv1.ConfigMap) (string, []byte, bool) {
+	var (
+		largest string
+		content []byte
+		binary  bool
+	)
+	for _, key := range sortedKeys(cm.Data) {
+		if key != ChunkIndexKey && (largest == "" || len(cm.Data[key]) > len(content)) {
+			largest, content, binary = key, []byte(cm.Data[key]), false
+		}
+	}
+	binaryKeys := make([]string, 0, len(cm.BinaryData))
+	for key := range cm.BinaryData {
+		binaryKeys = append(binaryKeys, key)
+	}
+	sort.Strings(binaryKeys)
+	for _, key := range binaryKeys {
+		if largest == "" || len(cm.BinaryData[key]) > len(content) {
+			largest, content, binary = key, cm.BinaryData[key], true
+		}
+	}
+	return largest, content, binary
+}
@@ -0,0 +1,72 @@
+package v1
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestChunkConfigMap(t *testing.T) {
+	var (
+		large = goldenFunction("large-v1")
+		small = goldenFunction("large-v2")
+		cm    = large.ConfigMap()
+	)
+	large.Spec.Data = strings.Repeat("#", ChunkConfigMapLimit+ChunkSize)
+	large.SetConfigMap(&cm)
+	small.SetConfigMap(&cm)
+
+	chunks := ChunkConfigMap(&cm)
+	if len(chunks) != 3 {
+		t.Fatalf("content must be split into 3 chunks, got %d", len(chunks))
+	}
+	if _, ok := cm.Data["v1.py"]; ok || cm.Data["v2.py"] != small.Spec.Data {
+		t.Fatalf("only the large key must be chunked, got keys %v", sortedKeys(cm.Data))
+	}
+	var content []byte
+	for _, chunk := range chunks {
+		if chunk.Labels[LabelType] != TypeChunk || chunk.Labels[LabelConfigMap] != cm.Name || len(chunk.BinaryData[ChunkKey]) > ChunkSize {
+			t.Errorf("unexpected chunk %s %v", chunk.Name, chunk.Labels)
+		}
+		content = append(content, chunk.BinaryData[ChunkKey]...)
+	}
+	if !bytes.Equal(content, []byte(large.Spec.Data)) {
+		t.Error("chunks must hold the content in order")
+	}
+	if names := ChunkConfigMapNames(&cm); len(names) != 3 || names[0] != chunks[0].Name {
+		t.Errorf("unexpected chunk config maps %v", names)
+	}
+
+	if again := ChunkConfigMap(&cm); len(again) != 0 {
+		t.Errorf("chunked config map must not be chunked again, got %d chunks", len(again))
+	}
+	large.SetConfigMap(&cm)
+	if again := ChunkConfigMap(&cm); len(again) != 3 || again[0].Name != chunks[0].Name {
+		t.Error("unchanged content must keep the names of its chunks")
+	}
+
+	large.UnsetConfigMap(&cm)
+	if _, ok := cm.Data[ChunkIndexKey]; ok || len(ChunkConfigMapNames(&cm)) != 0 {
+		t.Errorf("chunk index must be dropped with its last key, got keys %v", sortedKeys(cm.Data))
+	}
+}
+
+func TestRuntimeChunkedVolume(t *testing.T) {
+	rt := goldenMountedRuntime(ChunkedRuntimeVolumeType)
+	if len(rt.Status.Libraries["lib-util-v1"].Chunks) != 3 {
+		t.Fatalf("unexpected chunks %v", rt.Status.Libraries["lib-util-v1"].Chunks)
+	}
+
+	for _, volumeType := range []RuntimeVolumeType{ConfigMapRuntimeVolumeType, ProjectedRuntimeVolumeType} {
+		rt.Spec.Volume.Type = volumeType
+		if _, err := rt.Deployment(); err == nil {
+			t.Errorf("chunked config maps must not be mounted through the %s volume", volumeType)
+		}
+	}
+
+	rt.Spec.Volume.Type = ChunkedRuntimeVolumeType
+	rt.Spec.UpdateStrategy.Type = SignalRuntimeUpdateStrategyType
+	if err := rt.validate(); err == nil {
+		t.Error("chunked volume must not be signaled on content changes")
+	}
+}
@@ -0,0 +1,157 @@
+package v1
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition mirrors metav1.Condition, which is not available in apimachinery v0.18
+type Condition struct {
+	// Type of condition in CamelCase
+	// +kubebuilder:validation:Required
+	Type string `json:"type"`
+
+	// Status of the condition, one of True, False, Unknown
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=True;False;Unknown
+	Status metav1.ConditionStatus `json:"status"`
+
+	// The generation of the object the condition was set upon
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Last time the condition transitioned from one status to another
+	// +kubebuilder:validation:Required
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+
+	// The reason for the condition's last transition in CamelCase
+	// +kubebuilder:validation:Required
+	Reason string `json:"reason"`
+
+	// A human readable message indicating details about the transition
+	// +kubebuilder:validation:Optional
+	Message string `json:"message"`
+}
+
+// Condition Type Constants bulabula
+var (
+	ConditionReady           = "Ready"
+	ConditionRuntimeFound    = "RuntimeFound"
+	ConditionConfigMapSynced = "ConfigMapSynced"
+	ConditionMounted         = "Mounted"
+	ConditionRolloutComplete = "RolloutComplete"
+	ConditionResolved        = "Resolved"
+	ConditionProgressing     = "Progressing"
+	ConditionAnalysisPassed  = "AnalysisPassed"
+	ConditionConnected       = "Connected"
+	ConditionSucceeded       = "Succeeded"
+	ConditionSourceFetched   = "SourceFetched"
+)
+
+// Condition Reason Constants bulabula
+var (
+	ReasonReconciled         = "Reconciled"
+	ReasonPending            = "Pending"
+	ReasonWaitingForRuntime  = "WaitingForRuntime"
+	ReasonRuntimeNotReady    = "RuntimeNotReady"
+	ReasonConfigMapFailed    = "ConfigMapFailed"
+	ReasonNotMounted         = "NotMounted"
+	ReasonDeploymentNotFound = "DeploymentNotFound"
+	ReasonDeploymentFailed   = "DeploymentFailed"
+	ReasonRolloutInProgress  = "RolloutInProgress"
+	ReasonVersionNotFound    = "VersionNotFound"
+	ReasonAliasNotFound      = "AliasNotFound"
+	ReasonVersionNotReady    = "VersionNotReady"
+	ReasonStepProgressing    = "StepProgressing"
+	ReasonPromoted           = "Promoted"
+	ReasonAnalysisFailed     = "AnalysisFailed"
+	ReasonInconclusive       = "Inconclusive"
+	ReasonRolledBack         = "RolledBack"
+	ReasonMetricsUnavailable = "MetricsUnavailable"
+	ReasonInvalidSchedule    = "InvalidSchedule"
+	ReasonSuspended          = "Suspended"
+	ReasonSecretNotFound     = "SecretNotFound"
+	ReasonSubscribed         = "Subscribed"
+	ReasonConnectionFailed   = "ConnectionFailed"
+	ReasonWatching           = "Watching"
+	ReasonWatchFailed        = "WatchFailed"
+	ReasonInvalidWorkflow    = "InvalidWorkflow"
+	ReasonWorkflowNotFound   = "WorkflowNotFound"
+	ReasonRunning            = "Running"
+	ReasonStepFailed         = "StepFailed"
+	ReasonFetchFailed        = "FetchFailed"
+	ReasonChecksumMismatch   = "ChecksumMismatch"
+)
+
+// FindCondition returns the condition of the given type, or nil
+func FindCondition(conditions []Condition, conditionType string) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsConditionTrue reports whether the condition of the given type is True
+func IsConditionTrue(conditions []Condition, conditionType string) bool {
+	condition := FindCondition(conditions, conditionType)
+	return condition != nil && condition.Status == metav1.ConditionTrue
+}
+
+// SetCondition adds or updates a condition, LastTransitionTime only moves when the status changes
+func SetCondition(conditions *[]Condition, newCondition Condition) {
+	existing := FindCondition(*conditions, newCondition.Type)
+	if existing == nil {
+		if newCondition.LastTransitionTime.IsZero() {
+			newCondition.LastTransitionTime = metav1.Now()
+		}
+		*conditions = append(*conditions, newCondition)
+		return
+	}
+
+	if existing.Status != newCondition.Status {
+		existing.Status = newCondition.Status
+		if newCondition.LastTransitionTime.IsZero() {
+			existing.LastTransitionTime = metav1.Now()
+		} else {
+			existing.LastTransitionTime = newCondition.LastTransitionTime
+		}
+	}
+	existing.Reason = newCondition.Reason
+	existing.Message = newCondition.Message
+	existing.ObservedGeneration = newCondition.ObservedGeneration
+}
+
+// NewCondition bulabula
+func NewCondition(conditionType string, status bool, reason, message string) Condition {
+	condition := Condition{
+		Type:    conditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	}
+	if status {
+		condition.Status = metav1.ConditionTrue
+	}
+	return condition
+}
+
+// dependentReadyCondition derives the Ready condition of a function or library from its own
+// conditions and the readiness of its runtime
+func dependentReadyCondition(conditions []Condition, rt *Runtime) Condition {
+	for _, conditionType := range []string{ConditionRuntimeFound, ConditionConfigMapSynced, ConditionMounted} {
+		condition := FindCondition(conditions, conditionType)
+		if condition == nil {
+			return NewCondition(ConditionReady, false, ReasonPending, fmt.Sprintf("condition %s is not reported yet", conditionType))
+		}
+		if condition.Status != metav1.ConditionTrue {
+			return NewCondition(ConditionReady, false, condition.Reason, condition.Message)
+		}
+	}
+	if !IsConditionTrue(rt.Status.Conditions, ConditionReady) {
+		return NewCondition(ConditionReady, false, ReasonRuntimeNotReady, fmt.Sprintf("runtime %q is not ready", rt.Name))
+	}
+	return NewCondition(ConditionReady, true, ReasonReconciled, "")
+}
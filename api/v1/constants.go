@@ -1,5 +1,7 @@
 package v1
 
+import "time"
+
 var (
 	// Finalizer bulabula
 	Finalizer = GroupVersion.Group
@@ -10,12 +12,67 @@ var (
 
 // Type Constants bulabula
 var (
-	TypeRuntime  = "runtime"
-	TypeFunction = "function"
-	TypeLibrary  = "library"
+	TypeRuntime   = "runtime"
+	TypeFunction  = "function"
+	TypeLibrary   = "library"
+	TypeActivator = "activator"
+	TypeChunk     = "chunk"
+
+	TypeFunctionAlias   = "functionalias"
+	TypeRollout         = "rollout"
+	TypeCronTrigger     = "crontrigger"
+	TypeMQTTTrigger     = "mqtttrigger"
+	TypeK8sEventTrigger = "k8seventtrigger"
+	TypeWorkflow        = "workflow"
+	TypeWorkflowRun     = "workflowrun"
+)
+
+// Label Constants bulabula
+var (
+	LabelType      = "kess-type"
+	LabelRuntime   = "kess-runtime"
+	LabelFunction  = "kess-function"
+	LabelLibrary   = "kess-library"
+	LabelVersion   = "kess-version"
+	LabelWorkflow  = "kess-workflow"
+	LabelConfigMap = "kess-configmap"
+)
+
+// Annotation Constants bulabula
+var (
+	AnnotationConfigMapsHash = GroupVersion.Group + "/configmaps-hash"
+	AnnotationLastActivity   = GroupVersion.Group + "/last-activity"
 )
 
 // Default Constants bulabula
 var (
-	DefaultReady = "0/0"
+	DefaultReady              = "0/0"
+	DefaultUpdateStrategyPath = "/-/reload"
+	DefaultVolumeName         = "kess"
+	DefaultChunksVolumeName   = "kess-chunks"
+	DefaultVolumeImage        = "yamajik/kess:latest"
+
+	DefaultScaleToZeroIdleWindow        = 15 * time.Minute
+	DefaultScaleToZeroActivationTimeout = 2 * time.Minute
+
+	DefaultRolloutStepPause        = time.Minute
+	DefaultRolloutAnalysisInterval = 30 * time.Second
+	DefaultRolloutMetricsPath      = "/metrics"
+	DefaultRolloutRequestsMetric   = "kess_runtime_requests_total"
+	DefaultRolloutLatencyMetric    = "kess_runtime_request_duration_seconds"
+
+	DefaultTriggerMethod       = "POST"
+	DefaultTriggerTimeout      = 30 * time.Second
+	DefaultTriggerHistoryLimit = int32(10)
+
+	DefaultMQTTUsernameKey = "username"
+	DefaultMQTTPasswordKey = "password"
+
+	DefaultAsyncMaxAttempts = int32(3)
+	DefaultAsyncBackoff     = time.Second
+	DefaultAsyncMaxBackoff  = time.Minute
+
+	DefaultGitRef         = "HEAD"
+	DefaultGitUsernameKey = "username"
+	DefaultGitPasswordKey = "password"
 )
@@ -0,0 +1,111 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/xorcare/pointer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/yamajik/kess/utils/cron"
+)
+
+// maxMissedSchedules bounds the walk over the schedules missed while the controller was down
+const maxMissedSchedules = 100000
+
+// Default bulabula
+func (r *CronTrigger) Default() {
+	if r.ObjectMeta.Labels == nil {
+		r.ObjectMeta.Labels = make(map[string]string)
+	}
+	for k, v := range r.Labels() {
+		r.ObjectMeta.Labels[k] = v
+	}
+
+	r.Spec.TriggerTarget.Default()
+	if r.Spec.HistoryLimit == nil {
+		r.Spec.HistoryLimit = pointer.Int32(DefaultTriggerHistoryLimit)
+	}
+}
+
+// DefaultStatus bulabula
+func (r *CronTrigger) DefaultStatus() {}
+
+// Labels bulabula
+func (r *CronTrigger) Labels() map[string]string {
+	return map[string]string{
+		LabelType:     TypeCronTrigger,
+		LabelFunction: r.Spec.Function,
+	}
+}
+
+// NamespacedName bulabula
+func (r *CronTrigger) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      r.Name,
+		Namespace: r.Namespace,
+	}
+}
+
+// SetCondition bulabula
+func (r *CronTrigger) SetCondition(condition Condition) {
+	condition.ObservedGeneration = r.Generation
+	SetCondition(&r.Status.Conditions, condition)
+}
+
+// Schedule parses the schedule of trigger and loads its time zone
+func (r *CronTrigger) Schedule() (*cron.Schedule, *time.Location, error) {
+	loc := time.UTC
+	if r.Spec.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(r.Spec.TimeZone); err != nil {
+			return nil, nil, err
+		}
+	}
+	schedule, err := cron.Parse(r.Spec.Schedule)
+	if err != nil {
+		return nil, nil, err
+	}
+	return schedule, loc, nil
+}
+
+// ScheduleTimes returns the latest schedule due at now since the last one, zero when none is due, only the latest
+// of the schedules missed in between is due, and the next schedule after now
+func (r *CronTrigger) ScheduleTimes(schedule *cron.Schedule, loc *time.Location, now time.Time) (due, next time.Time) {
+	since := r.CreationTimestamp.Time
+	if r.Status.LastScheduleTime != nil {
+		since = r.Status.LastScheduleTime.Time
+	}
+
+	t := schedule.Next(since.In(loc))
+	for i := 0; i < maxMissedSchedules && !t.IsZero() && !t.After(now); i++ {
+		due = t
+		t = schedule.Next(t)
+	}
+	if !due.IsZero() && !t.After(now) {
+		// Too many schedules were missed, skip to the one after now
+		t = schedule.Next(now.In(loc))
+	}
+	return due, t
+}
+
+// UpdateStatusSchedule records the schedules of trigger, invocation is nil when nothing was invoked
+func (r *CronTrigger) UpdateStatusSchedule(due, next time.Time, invocation *TriggerInvocation) {
+	if invocation != nil {
+		r.Status.LastScheduleTime = &metav1.Time{Time: due}
+		r.Status.History = prependInvocation(r.Status.History, *invocation, *r.Spec.HistoryLimit)
+	}
+	r.Status.NextScheduleTime = nil
+	if !next.IsZero() {
+		r.Status.NextScheduleTime = &metav1.Time{Time: next}
+	}
+	r.Status.ObservedGeneration = r.Generation
+	r.SetCondition(NewCondition(ConditionReady, true, ReasonReconciled, ""))
+}
+
+// UpdateStatusUnscheduled reports why trigger is not scheduled
+func (r *CronTrigger) UpdateStatusUnscheduled(reason, message string) {
+	r.Status.NextScheduleTime = nil
+	r.Status.ObservedGeneration = r.Generation
+	r.SetCondition(NewCondition(ConditionReady, false, reason, message))
+}
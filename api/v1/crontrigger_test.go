@@ -0,0 +1,58 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xorcare/pointer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCronTriggerScheduleTimes(t *testing.T) {
+	created := time.Date(2020, 1, 1, 0, 0, 30, 0, time.UTC)
+	trigger := &CronTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "sample", CreationTimestamp: metav1.Time{Time: created}},
+		Spec:       CronTriggerSpec{TriggerTarget: TriggerTarget{Function: "sample"}, Schedule: "*/5 * * * *"},
+	}
+	trigger.Default()
+	schedule, loc, err := trigger.Schedule()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	due, next := trigger.ScheduleTimes(schedule, loc, created.Add(time.Minute))
+	if !due.IsZero() || !next.Equal(time.Date(2020, 1, 1, 0, 5, 0, 0, time.UTC)) {
+		t.Fatalf("nothing must be due before the first schedule, got %s and %s", due, next)
+	}
+
+	now := time.Date(2020, 1, 1, 0, 17, 0, 0, time.UTC)
+	due, next = trigger.ScheduleTimes(schedule, loc, now)
+	if !due.Equal(time.Date(2020, 1, 1, 0, 15, 0, 0, time.UTC)) {
+		t.Errorf("only the latest missed schedule must be due, got %s", due)
+	}
+	if !next.Equal(time.Date(2020, 1, 1, 0, 20, 0, 0, time.UTC)) {
+		t.Errorf("next schedule must follow now, got %s", next)
+	}
+
+	trigger.UpdateStatusSchedule(due, next, &TriggerInvocation{StatusCode: 200})
+	if due, _ := trigger.ScheduleTimes(schedule, loc, now); !due.IsZero() {
+		t.Errorf("a schedule must be due once, got %s again", due)
+	}
+}
+
+func TestCronTriggerHistoryLimit(t *testing.T) {
+	trigger := &CronTrigger{Spec: CronTriggerSpec{HistoryLimit: pointer.Int32(2)}}
+	trigger.Default()
+
+	now := time.Now()
+	for i := int32(1); i <= 3; i++ {
+		trigger.UpdateStatusSchedule(now, now.Add(time.Minute), &TriggerInvocation{StatusCode: 200 + i})
+	}
+	history := trigger.Status.History
+	if len(history) != 2 || history[0].StatusCode != 203 || history[1].StatusCode != 202 {
+		t.Errorf("history must keep the 2 newest invocations first, got %+v", history)
+	}
+	if !IsConditionTrue(trigger.Status.Conditions, ConditionReady) {
+		t.Error("scheduled trigger must be ready")
+	}
+}
@@ -0,0 +1,104 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CronTriggerSpec defines the desired state of CronTrigger
+type CronTriggerSpec struct {
+	TriggerTarget `json:",inline"`
+
+	// The cron schedule of invocations, five fields or one of @yearly, @monthly, @weekly, @daily and @hourly
+	// +kubebuilder:validation:Required
+	Schedule string `json:"schedule"`
+
+	// Optional IANA time zone of schedule, defaults to UTC
+	// +kubebuilder:validation:Optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Optional body of invocations
+	// +kubebuilder:validation:Optional
+	Payload string `json:"payload,omitempty"`
+
+	// Optional flag suspending invocations
+	// +kubebuilder:validation:Optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// Optional number of invocations kept in status
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=10
+	HistoryLimit *int32 `json:"historyLimit,omitempty"`
+}
+
+// CronTriggerStatus defines the observed state of CronTrigger
+type CronTriggerStatus struct {
+	// Optional last time function was scheduled
+	// +kubebuilder:validation:Optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// Optional next time function is scheduled
+	// +kubebuilder:validation:Optional
+	NextScheduleTime *metav1.Time `json:"nextScheduleTime,omitempty"`
+
+	// Optional last invocations of function, newest first
+	// +kubebuilder:validation:Optional
+	History []TriggerInvocation `json:"history,omitempty"`
+
+	// The generation observed by the cron trigger controller
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Optional conditions of cron trigger
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:resource:categories="kess",shortName="cron"
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Function",type=string,JSONPath=`.spec.function`,priority=0
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.version`,priority=0
+// +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`,priority=0
+// +kubebuilder:printcolumn:name="Suspend",type=boolean,JSONPath=`.spec.suspend`,priority=0
+// +kubebuilder:printcolumn:name="Last Schedule",type=date,JSONPath=`.status.lastScheduleTime`,priority=0
+// +kubebuilder:object:root=true
+
+// CronTrigger is the Schema for the crontriggers API
+type CronTrigger struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CronTriggerSpec   `json:"spec,omitempty"`
+	Status CronTriggerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// CronTriggerList contains a list of CronTrigger
+type CronTriggerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CronTrigger `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CronTrigger{}, &CronTriggerList{})
+}
@@ -0,0 +1,87 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/yamajik/kess/utils/cron"
+)
+
+// log is for logging in this package.
+var crontriggerlog = logf.Log.WithName("crontrigger-resource")
+
+// SetupWebhookWithManager bulabula
+func (r *CronTrigger) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-core-kess-io-v1-crontrigger,mutating=true,failurePolicy=fail,groups=core.kess.io,resources=crontriggers,verbs=create;update,versions=v1,name=mcrontrigger.kb.io
+
+var _ webhook.Defaulter = &CronTrigger{}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-core-kess-io-v1-crontrigger,mutating=false,failurePolicy=fail,groups=core.kess.io,resources=crontriggers,versions=v1,name=vcrontrigger.kb.io
+
+var _ webhook.Validator = &CronTrigger{}
+
+// ValidateCreate bulabula
+func (r *CronTrigger) ValidateCreate() error {
+	crontriggerlog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate bulabula
+func (r *CronTrigger) ValidateUpdate(old runtime.Object) error {
+	crontriggerlog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateDelete bulabula
+func (r *CronTrigger) ValidateDelete() error {
+	return nil
+}
+
+func (r *CronTrigger) validate() error {
+	var (
+		allErrs  field.ErrorList
+		specPath = field.NewPath("spec")
+	)
+
+	allErrs = append(allErrs, validateTriggerTarget(specPath, &r.Spec.TriggerTarget)...)
+	if _, err := cron.Parse(r.Spec.Schedule); err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("schedule"), r.Spec.Schedule, err.Error()))
+	}
+	if r.Spec.TimeZone != "" {
+		if _, err := time.LoadLocation(r.Spec.TimeZone); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("timeZone"), r.Spec.TimeZone, err.Error()))
+		}
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("CronTrigger").GroupKind(), r.Name, allErrs)
+}
@@ -1,26 +1,21 @@
 package v1
 
 import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-
-	"github.com/xorcare/pointer"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 // Default bulabula
 func (r *Function) Default() {
-	var (
-		labels       = r.Labels()
-		namedVersion = r.NamedVersion()
-	)
-
-	if r.ObjectMeta.Labels == nil {
-		r.ObjectMeta.Labels = make(map[string]string)
-	}
-	for k, v := range labels {
-		r.ObjectMeta.Labels[k] = v
-	}
+	namedVersion := r.NamedVersion()
 
 	if r.Spec.Function == "" {
 		r.Spec.Function = namedVersion.Name
@@ -28,6 +23,40 @@ func (r *Function) Default() {
 	if r.Spec.Version == "" {
 		r.Spec.Version = namedVersion.Version
 	}
+
+	if async := r.Spec.Async; async != nil {
+		if async.MaxAttempts == nil {
+			maxAttempts := DefaultAsyncMaxAttempts
+			async.MaxAttempts = &maxAttempts
+		}
+		if async.Backoff.Duration == 0 {
+			async.Backoff.Duration = DefaultAsyncBackoff
+		}
+		if async.MaxBackoff.Duration == 0 {
+			async.MaxBackoff.Duration = DefaultAsyncMaxBackoff
+		}
+	}
+
+	if git := r.GitSource(); git != nil {
+		if git.Ref == "" {
+			git.Ref = DefaultGitRef
+		}
+		if secret := git.CredentialsSecret; secret != nil {
+			if secret.UsernameKey == "" {
+				secret.UsernameKey = DefaultGitUsernameKey
+			}
+			if secret.PasswordKey == "" {
+				secret.PasswordKey = DefaultGitPasswordKey
+			}
+		}
+	}
+
+	if r.ObjectMeta.Labels == nil {
+		r.ObjectMeta.Labels = make(map[string]string)
+	}
+	for k, v := range r.Labels() {
+		r.ObjectMeta.Labels[k] = v
+	}
 }
 
 // DefaultStatus bulabula
@@ -45,19 +74,28 @@ func (r *Function) NamedVersion() NamedVersion {
 // RuntimeConfigMap bulabula
 func (r *Function) RuntimeConfigMap() RuntimeConfigMap {
 	namedVersion := r.NamedVersion()
-	return RuntimeConfigMap{
+	runtimeConfigMap := RuntimeConfigMap{
 		Name:  namedVersion.Format(r.Spec.ConfigMap.Name),
 		Mount: namedVersion.Format(r.Spec.ConfigMap.Mount),
 	}
+	if secret := r.RuntimeSecret(); secret != nil {
+		runtimeConfigMap.Secrets = []RuntimeSecret{*secret}
+	}
+	return runtimeConfigMap
+}
+
+// FileKey bulabula
+func (r *Function) FileKey() string {
+	return r.NamedVersion().Format(r.Spec.File.Name)
 }
 
 // Labels bulabula
 func (r *Function) Labels() map[string]string {
 	return map[string]string{
-		"kess-type":     TypeFunction,
-		"kess-function": r.Spec.Function,
-		"kess-version":  r.Spec.Version,
-		"kess-runtime":  r.Spec.Runtime,
+		LabelType:     TypeFunction,
+		LabelFunction: r.Spec.Function,
+		LabelVersion:  r.Spec.Version,
+		LabelRuntime:  r.Spec.Runtime,
 	}
 }
 
@@ -83,7 +121,6 @@ func (r *Function) ConfigMap() apiv1.ConfigMap {
 			Namespace: r.Namespace,
 			Labels:    labels,
 		},
-		Immutable: pointer.Bool(true),
 	}
 
 	r.SetConfigMap(&configmap)
@@ -101,7 +138,14 @@ func (r *Function) ConfigMapNamespacedName() types.NamespacedName {
 
 // SetConfigMap bulabula
 func (r *Function) SetConfigMap(out *apiv1.ConfigMap) {
-	key := r.NamedVersion().Format(r.Spec.File.Name)
+	key := r.FileKey()
+	UnsetChunk(out, key)
+	r.unsetFiles(out)
+	// The file of a function read from a secret is projected from the secret itself
+	if r.HasFiles() || r.RuntimeSecret() != nil {
+		delete(out.Data, key)
+		delete(out.BinaryData, key)
+	}
 	if r.Spec.Data != "" {
 		if out.Data == nil {
 			out.Data = make(map[string]string)
@@ -114,20 +158,330 @@ func (r *Function) SetConfigMap(out *apiv1.ConfigMap) {
 		}
 		out.BinaryData[key] = r.Spec.BinaryData
 	}
+	for file, data := range r.Spec.Files {
+		if out.Data == nil {
+			out.Data = make(map[string]string)
+		}
+		out.Data[fileKey(key, file)] = data
+	}
+	for file, data := range r.Spec.BinaryFiles {
+		if out.BinaryData == nil {
+			out.BinaryData = make(map[string][]byte)
+		}
+		out.BinaryData[fileKey(key, file)] = data
+	}
+
+	manifest := Manifest(out)
+	manifest[key] = r.Manifest()
+	setManifest(out, manifest)
+	setContentHash(out)
 }
 
 // UnsetConfigMap bulabula
 func (r *Function) UnsetConfigMap(out *apiv1.ConfigMap) {
-	key := r.NamedVersion().Format(r.Spec.File.Name)
+	key := r.FileKey()
+	UnsetChunk(out, key)
+	r.unsetFiles(out)
 	if out.Data != nil {
 		delete(out.Data, key)
 	}
 	if out.BinaryData != nil {
 		delete(out.BinaryData, key)
 	}
+
+	manifest := Manifest(out)
+	delete(manifest, key)
+	setManifest(out, manifest)
+	setContentHash(out)
+}
+
+// unsetFiles drops every file under the directory of function, so the files removed from it are not mounted
+func (r *Function) unsetFiles(out *apiv1.ConfigMap) {
+	prefix := r.FileKey() + keyPathSeparator
+	for key := range out.Data {
+		if strings.HasPrefix(key, prefix) {
+			delete(out.Data, key)
+		}
+	}
+	for key := range out.BinaryData {
+		if strings.HasPrefix(key, prefix) {
+			delete(out.BinaryData, key)
+		}
+	}
+	for key := range ChunkIndex(out) {
+		if strings.HasPrefix(key, prefix) {
+			UnsetChunk(out, key)
+		}
+	}
+}
+
+// HasFiles tells whether function is made of many files under its own directory rather than one file
+func (r *Function) HasFiles() bool {
+	return len(r.Spec.Files) > 0 || len(r.Spec.BinaryFiles) > 0
+}
+
+// Manifest returns the description of function in the manifest of its config map
+func (r *Function) Manifest() FunctionManifest {
+	manifest := FunctionManifest{
+		Function: r.Spec.Function,
+		Version:  r.Spec.Version,
+		Path:     r.FileKey(),
+		Handler:  r.Spec.Handler,
+	}
+	for file := range r.Spec.Files {
+		manifest.Files = append(manifest.Files, file)
+	}
+	for file := range r.Spec.BinaryFiles {
+		manifest.Files = append(manifest.Files, file)
+	}
+	sort.Strings(manifest.Files)
+	for _, file := range manifest.Files {
+		if manifest.Keys == nil {
+			manifest.Keys = make(map[string]string, len(manifest.Files))
+		}
+		manifest.Keys[file] = fileKey(manifest.Path, file)
+	}
+	return manifest
+}
+
+// GitSource returns the git repository of function, nil when it has none
+func (r *Function) GitSource() *FunctionGitSource {
+	if r.Spec.Source == nil {
+		return nil
+	}
+	return r.Spec.Source.Git
+}
+
+// ArchiveSource returns the archive of function, nil when it has none
+func (r *Function) ArchiveSource() *ArchiveSource {
+	if r.Spec.Source == nil {
+		return nil
+	}
+	return r.Spec.Source.Archive
+}
+
+// ArchiveURL returns the url of archive with the name and version of function
+func (r *Function) ArchiveURL() string {
+	archive := r.ArchiveSource()
+	if archive == nil {
+		return ""
+	}
+	return r.NamedVersion().Format(archive.URL)
+}
+
+// ArchiveFile returns the file of function among the files of its archive, which is the file at path,
+// or the only file of archive when path is empty
+func (r *Function) ArchiveFile(files map[string][]byte) ([]byte, error) {
+	archive := r.ArchiveSource()
+	if archive == nil {
+		return nil, fmt.Errorf("function has no archive")
+	}
+	if archive.Path != "" {
+		data, ok := files[path.Clean(strings.TrimPrefix(archive.Path, "/"))]
+		if !ok {
+			return nil, fmt.Errorf("file %q is not found in archive", archive.Path)
+		}
+		return data, nil
+	}
+	if len(files) != 1 {
+		return nil, fmt.Errorf("archive has %d files, path must select the file of function", len(files))
+	}
+	for _, data := range files {
+		return data, nil
+	}
+	return nil, nil
+}
+
+// Reference returns the config map or secret function reads its file from, nil when it has none
+func (r *Function) Reference() *ReferenceStatus {
+	switch {
+	case r.Spec.Source == nil:
+		return nil
+	case r.Spec.Source.ConfigMapKeyRef != nil:
+		return &ReferenceStatus{Kind: ReferenceKindConfigMap, Name: r.Spec.Source.ConfigMapKeyRef.Name}
+	case r.Spec.Source.SecretKeyRef != nil:
+		return &ReferenceStatus{Kind: ReferenceKindSecret, Name: r.Spec.Source.SecretKeyRef.Name}
+	}
+	return nil
+}
+
+// ReferenceNamespacedName bulabula
+func (r *Function) ReferenceNamespacedName() types.NamespacedName {
+	var name string
+	if ref := r.Reference(); ref != nil {
+		name = ref.Name
+	}
+	return types.NamespacedName{
+		Name:      name,
+		Namespace: r.Namespace,
+	}
+}
+
+// ReferenceOptional tells whether function may miss the referenced config map or secret, or its key
+func (r *Function) ReferenceOptional() bool {
+	var optional *bool
+	switch {
+	case r.Spec.Source == nil:
+	case r.Spec.Source.ConfigMapKeyRef != nil:
+		optional = r.Spec.Source.ConfigMapKeyRef.Optional
+	case r.Spec.Source.SecretKeyRef != nil:
+		optional = r.Spec.Source.SecretKeyRef.Optional
+	}
+	return optional != nil && *optional
+}
+
+// referenceDataKey returns the key of the file of function in the referenced config map or secret
+func (r *Function) referenceDataKey() string {
+	switch {
+	case r.Spec.Source == nil:
+	case r.Spec.Source.ConfigMapKeyRef != nil:
+		return r.Spec.Source.ConfigMapKeyRef.Key
+	case r.Spec.Source.SecretKeyRef != nil:
+		return r.Spec.Source.SecretKeyRef.Key
+	}
+	return ""
+}
+
+// ReferenceFile returns the file of function among the content of the referenced config map,
+// nil when the key is missing and optional
+func (r *Function) ReferenceFile(cm *apiv1.ConfigMap) ([]byte, error) {
+	key := r.referenceDataKey()
+	data, ok := configMapData(cm)[key]
+	if !ok && !r.ReferenceOptional() {
+		return nil, fmt.Errorf("key %q is not found in %s %q", key, ReferenceKindConfigMap, cm.Name)
+	}
+	return data, nil
+}
+
+// CheckReferenceSecret fails when the referenced secret misses the file of function and it is not optional,
+// the content of secret is not read, runtime pods mount it from the secret
+func (r *Function) CheckReferenceSecret(secret *apiv1.Secret) error {
+	key := r.referenceDataKey()
+	if _, ok := secret.Data[key]; !ok && !r.ReferenceOptional() {
+		return fmt.Errorf("key %q is not found in %s %q", key, ReferenceKindSecret, secret.Name)
+	}
+	return nil
+}
+
+// RuntimeSecret returns the secret projected at the file of function, nil unless function reads its file from a secret
+func (r *Function) RuntimeSecret() *RuntimeSecret {
+	if r.Spec.Source == nil || r.Spec.Source.SecretKeyRef == nil {
+		return nil
+	}
+	ref := r.Spec.Source.SecretKeyRef
+	secret := &RuntimeSecret{
+		Name:     ref.Name,
+		Items:    []apiv1.KeyToPath{{Key: ref.Key, Path: r.FileKey()}},
+		Optional: ref.Optional,
+	}
+	if read := r.Status.Reference; read != nil && read.Kind == ReferenceKindSecret && read.Name == ref.Name {
+		secret.ResourceVersion = read.ResourceVersion
+	}
+	return secret
+}
+
+// ReferenceChanged tells whether a config map or secret of kind is referenced by function and changed since function was read
+func (r *Function) ReferenceChanged(kind, name, resourceVersion string) bool {
+	return referenceChanged(r.Reference(), r.Status.Reference, kind, name, resourceVersion)
+}
+
+// UpdateStatusReference records the resource version of the config map or secret function was read from
+func (r *Function) UpdateStatusReference(resourceVersion string) {
+	r.Status.Reference = r.Reference()
+	if r.Status.Reference != nil {
+		r.Status.Reference.ResourceVersion = resourceVersion
+	}
+}
+
+// GitSecretNamespacedName returns the secret holding the credentials of repository, nil when there is none
+func (r *Function) GitSecretNamespacedName() *types.NamespacedName {
+	git := r.GitSource()
+	if git == nil || git.CredentialsSecret == nil {
+		return nil
+	}
+	return &types.NamespacedName{
+		Name:      git.CredentialsSecret.Name,
+		Namespace: r.Namespace,
+	}
+}
+
+// PinnedCommit returns the commit function is pinned to, empty when the repository, ref or path changed since
+func (r *Function) PinnedCommit() string {
+	git, pinned := r.GitSource(), r.Status.Source
+	if git == nil || pinned == nil || pinned.URL != git.URL || pinned.Ref != git.Ref || pinned.Path != git.Path {
+		return ""
+	}
+	return pinned.Commit
+}
+
+// WithSourceData returns a copy of function with the data fetched from its source, data which is not utf-8
+// is kept as binary data
+func (r *Function) WithSourceData(data []byte) *Function {
+	fn := r.DeepCopy()
+	if utf8.Valid(data) {
+		fn.Spec.Data, fn.Spec.BinaryData = string(data), nil
+	} else {
+		fn.Spec.Data, fn.Spec.BinaryData = "", data
+	}
+	return fn
+}
+
+// WithSourceFiles returns a copy of function made of files under its own directory, the utf-8 ones as files
+// and the others as binary files
+func (r *Function) WithSourceFiles(files map[string][]byte) *Function {
+	fn := r.DeepCopy()
+	fn.Spec.Data, fn.Spec.BinaryData = "", nil
+	fn.Spec.Files, fn.Spec.BinaryFiles = splitData(files)
+	return fn
+}
+
+// ValidateSourceFiles checks the files fetched from a directory of the source of function may make up function
+func (r *Function) ValidateSourceFiles(files map[string][]byte) error {
+	if r.Spec.Handler == "" {
+		return fmt.Errorf("function sourced from a directory must specify the handler the runtime calls")
+	}
+	var allErrs field.ErrorList
+	key := r.FileKey()
+	for file := range files {
+		allErrs = append(allErrs, validateFilePath(field.NewPath("files").Key(file), key, file)...)
+	}
+	return allErrs.ToAggregate()
+}
+
+// UpdateStatusSource records that the source of function was fetched, function is pinned to commit when
+// its source is a git repository
+func (r *Function) UpdateStatusSource(commit string) {
+	r.Status.Source = nil
+	if git := r.GitSource(); git != nil {
+		r.Status.Source = &FunctionSourceStatus{
+			URL:    git.URL,
+			Ref:    git.Ref,
+			Path:   git.Path,
+			Commit: commit,
+		}
+	}
+	if r.Spec.Source != nil {
+		r.SetCondition(NewCondition(ConditionSourceFetched, true, ReasonReconciled, ""))
+	}
+}
+
+// SetCondition bulabula
+func (r *Function) SetCondition(condition Condition) {
+	condition.ObservedGeneration = r.Generation
+	SetCondition(&r.Status.Conditions, condition)
 }
 
 // UpdateStatusReady bulabula
 func (r *Function) UpdateStatusReady(rt *Runtime) {
+	runtimeConfigMap := r.RuntimeConfigMap()
+	if _, ok := rt.Status.Functions[runtimeConfigMap.Name]; ok {
+		r.SetCondition(NewCondition(ConditionMounted, true, ReasonReconciled, ""))
+	} else {
+		r.SetCondition(NewCondition(ConditionMounted, false, ReasonNotMounted,
+			fmt.Sprintf("config map %q is not mounted by runtime %q", runtimeConfigMap.Name, r.Spec.Runtime)))
+	}
+	r.SetCondition(dependentReadyCondition(r.Status.Conditions, rt))
+
 	r.Status.Ready = rt.Status.Ready
+	r.Status.ObservedGeneration = r.Generation
 }
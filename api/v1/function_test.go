@@ -0,0 +1,241 @@
+package v1
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestFunctionGitSource(t *testing.T) {
+	fn := &Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "hello-v1", Namespace: "kess-samples"},
+		Spec: FunctionSpec{
+			Runtime: "python",
+			File:    FunctionFile{Name: "{Version}.py"},
+			Source: &FunctionSource{Git: &FunctionGitSource{
+				URL:               "https://github.com/yamajik/kess-samples.git",
+				Path:              "functions/hello.py",
+				CredentialsSecret: &FunctionGitCredentials{Name: "github"},
+			}},
+		},
+	}
+	fn.Default()
+	if git := fn.GitSource(); git.Ref != DefaultGitRef || git.CredentialsSecret.PasswordKey != DefaultGitPasswordKey {
+		t.Fatalf("unexpected defaults %+v", git)
+	}
+	if name := fn.GitSecretNamespacedName(); name == nil || name.Name != "github" || name.Namespace != "kess-samples" {
+		t.Fatalf("unexpected secret %v", name)
+	}
+	if errs := fn.validateSource(field.NewPath("spec", "source")); len(errs) != 0 {
+		t.Fatalf("source must be valid, got %v", errs)
+	}
+
+	const commit = "0123456789abcdef0123456789abcdef01234567"
+	if fn.PinnedCommit() != "" {
+		t.Fatal("function must not be pinned before it is fetched")
+	}
+	fn.UpdateStatusSource(commit)
+	if fn.PinnedCommit() != commit || !IsConditionTrue(fn.Status.Conditions, ConditionSourceFetched) {
+		t.Fatalf("function must be pinned to %s, got %+v", commit, fn.Status)
+	}
+	fn.Spec.Source.Git.Ref = "v2"
+	if fn.PinnedCommit() != "" {
+		t.Error("function must be unpinned once ref changed")
+	}
+
+	var cm apiv1.ConfigMap
+	fn.WithSourceData([]byte("print('hello')\n")).SetConfigMap(&cm)
+	fn.WithSourceData([]byte{0xff, 0xfe}).SetConfigMap(&cm)
+	if cm.Data["v1.py"] != "print('hello')\n" || len(cm.BinaryData["v1.py"]) != 2 {
+		t.Errorf("unexpected config map %+v", cm)
+	}
+	if fn.Spec.Data != "" {
+		t.Error("source data must not be written into function")
+	}
+
+	fn.Spec.Data = "print('inline')"
+	fn.Spec.Source.Git.URL, fn.Spec.Source.Git.Ref, fn.Spec.Source.Git.Path = "file:///etc", "--upload-pack=sh", "../hello.py"
+	if errs := fn.validateSource(field.NewPath("spec", "source")); len(errs) != 4 {
+		t.Errorf("data, url, ref and path must be invalid, got %v", errs)
+	}
+}
+
+func TestFunctionArchiveSource(t *testing.T) {
+	fn := &Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "hello-v1", Namespace: "kess-samples"},
+		Spec: FunctionSpec{
+			Runtime: "python",
+			Source: &FunctionSource{Archive: &ArchiveSource{
+				URL:    "https://artifacts.kess.io/functions/{Name}/{Version}.tar.gz",
+				SHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			}},
+		},
+	}
+	fn.Default()
+	if url := fn.ArchiveURL(); url != "https://artifacts.kess.io/functions/hello/v1.tar.gz" {
+		t.Errorf("unexpected archive url %s", url)
+	}
+	if errs := fn.validateSource(field.NewPath("spec", "source")); len(errs) != 0 {
+		t.Fatalf("source must be valid, got %v", errs)
+	}
+
+	files := map[string][]byte{"hello/handler.py": []byte("def handler(): pass\n"), "README.md": []byte("# hello\n")}
+	if _, err := fn.ArchiveFile(files); err == nil {
+		t.Error("archive with many files must require a path")
+	}
+	fn.Spec.Source.Archive.Path = "/hello/handler.py"
+	if data, err := fn.ArchiveFile(files); err != nil || string(data) != "def handler(): pass\n" {
+		t.Errorf("unexpected file %q, %v", data, err)
+	}
+
+	files = map[string][]byte{"main.py": []byte("from lib import greet\n"), "lib/__init__.py": {0xff}}
+	if err := fn.ValidateSourceFiles(files); err == nil {
+		t.Error("function sourced from a directory must require a handler")
+	}
+	fn.Spec.File.Name, fn.Spec.Handler = "{Version}", "main.handler"
+	if err := fn.ValidateSourceFiles(files); err != nil {
+		t.Errorf("files must be valid, got %v", err)
+	}
+	if err := fn.ValidateSourceFiles(map[string][]byte{"lib/a..b.py": nil}); err == nil {
+		t.Error("file with the key path separator must be invalid")
+	}
+	var cm apiv1.ConfigMap
+	fn.WithSourceData([]byte("print('hello')\n")).SetConfigMap(&cm)
+	fn.WithSourceFiles(files).SetConfigMap(&cm)
+	if _, ok := cm.Data["v1"]; ok || cm.Data["v1..main.py"] != "from lib import greet\n" || len(cm.BinaryData["v1..lib..__init__.py"]) != 1 {
+		t.Errorf("unexpected config map %+v", cm)
+	}
+	fn.Spec.File.Name, fn.Spec.Handler = "", ""
+
+	fn.UpdateStatusSource("")
+	if fn.Status.Source != nil || !IsConditionTrue(fn.Status.Conditions, ConditionSourceFetched) {
+		t.Errorf("unexpected status %+v", fn.Status)
+	}
+
+	fn.Spec.Source.Git = &FunctionGitSource{URL: "https://github.com/yamajik/kess-samples.git", Ref: "HEAD", Path: "hello.py"}
+	fn.Spec.Source.Archive.URL, fn.Spec.Source.Archive.SHA256 = "ftp://{Name}", "9f86d081"
+	if errs := fn.validateSource(field.NewPath("spec", "source")); len(errs) != 3 {
+		t.Errorf("git, url and sha256 must be invalid, got %v", errs)
+	}
+}
+
+func TestFunctionReference(t *testing.T) {
+	fn := &Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "hello-v1", Namespace: "kess-samples"},
+		Spec: FunctionSpec{
+			Runtime: "python",
+			Source: &FunctionSource{ConfigMapKeyRef: &apiv1.ConfigMapKeySelector{
+				LocalObjectReference: apiv1.LocalObjectReference{Name: "hello-code"},
+				Key:                  "handler.py",
+			}},
+		},
+	}
+	if errs := fn.validateSource(field.NewPath("spec", "source")); len(errs) != 0 {
+		t.Fatalf("source must be valid, got %v", errs)
+	}
+	if name := fn.ReferenceNamespacedName(); name.Name != "hello-code" || name.Namespace != "kess-samples" {
+		t.Fatalf("unexpected reference %v", name)
+	}
+
+	cm := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "hello-code", Namespace: "kess-samples", ResourceVersion: "7"},
+		Data:       map[string]string{"handler.py": "def handler(): pass\n"},
+	}
+	if data, err := fn.ReferenceFile(cm); err != nil || string(data) != cm.Data["handler.py"] {
+		t.Errorf("unexpected file %q, %v", data, err)
+	}
+	if !fn.ReferenceChanged(ReferenceKindConfigMap, "hello-code", "7") {
+		t.Error("function must be synced before it has read its reference")
+	}
+	fn.UpdateStatusReference(cm.ResourceVersion)
+	if fn.ReferenceChanged(ReferenceKindConfigMap, "hello-code", "7") || fn.ReferenceChanged(ReferenceKindSecret, "hello-code", "8") {
+		t.Errorf("only changes of the referenced config map must sync function, got %+v", fn.Status.Reference)
+	}
+	if !fn.ReferenceChanged(ReferenceKindConfigMap, "hello-code", "8") {
+		t.Error("function must be synced once its reference changed")
+	}
+
+	delete(cm.Data, "handler.py")
+	if _, err := fn.ReferenceFile(cm); err == nil {
+		t.Error("missing key must fail unless optional")
+	}
+	optional := true
+	fn.Spec.Source.ConfigMapKeyRef.Optional = &optional
+	if data, err := fn.ReferenceFile(cm); err != nil || data != nil {
+		t.Errorf("missing optional key must be empty, got %q, %v", data, err)
+	}
+
+	secretFn := goldenFunction("hello-v1")
+	out := secretFn.ConfigMap()
+	secretFn.Spec.Source = &FunctionSource{SecretKeyRef: &apiv1.SecretKeySelector{
+		LocalObjectReference: apiv1.LocalObjectReference{Name: "hello-token"},
+		Key:                  "handler.py",
+	}}
+	secret := &apiv1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "hello-token", ResourceVersion: "3"}}
+	if err := secretFn.CheckReferenceSecret(secret); err == nil {
+		t.Error("missing secret key must fail unless optional")
+	}
+	secret.Data = map[string][]byte{"handler.py": []byte("TOKEN = 'x'\n")}
+	if err := secretFn.CheckReferenceSecret(secret); err != nil {
+		t.Errorf("secret key must be found, got %v", err)
+	}
+	secretFn.UpdateStatusReference(secret.ResourceVersion)
+	secretFn.WithSourceData(nil).SetConfigMap(&out)
+	if _, ok := out.Data[secretFn.FileKey()]; ok {
+		t.Error("secret content must not be copied into the config map of function")
+	}
+	secrets := secretFn.RuntimeConfigMap().Secrets
+	if len(secrets) != 1 || secrets[0].ResourceVersion != "3" || secrets[0].Items[0].Key != "handler.py" || secrets[0].Items[0].Path != secretFn.FileKey() {
+		t.Errorf("secret key must be projected at the file of function, got %+v", secrets)
+	}
+
+	fn.Spec.Source.SecretKeyRef = &apiv1.SecretKeySelector{Key: "handler/py"}
+	if errs := fn.validateSource(field.NewPath("spec", "source")); len(errs) != 3 {
+		t.Errorf("sources, secret name and key must be invalid, got %v", errs)
+	}
+}
+
+func TestFunctionFiles(t *testing.T) {
+	var (
+		single = goldenFunction("tasks-v2")
+		fn     = goldenFilesFunction("tasks-v1")
+		cm     = fn.ConfigMap()
+	)
+	if errs := fn.validateFiles(field.NewPath("spec")); len(errs) != 0 {
+		t.Fatalf("files must be valid, got %v", errs)
+	}
+	single.SetConfigMap(&cm)
+
+	manifest := Manifest(&cm)
+	if m := manifest["v1"]; m.Path != "v1" || m.Handler != "handler.main" || len(m.Files) != 4 || m.Files[0] != "fixtures/event.bin" {
+		t.Errorf("unexpected manifest of files function %+v", m)
+	}
+	if m := manifest["v2.py"]; m.Path != "v2.py" || m.Version != "v2" || len(m.Files) != 0 {
+		t.Errorf("unexpected manifest of single file function %+v", m)
+	}
+	if KeyPath("v1..utils..greet.py") != "v1/utils/greet.py" {
+		t.Error("directories of key must be laid out as path")
+	}
+
+	delete(fn.Spec.Files, "utils/greet.py")
+	fn.SetConfigMap(&cm)
+	if _, ok := cm.Data["v1..utils..greet.py"]; ok {
+		t.Error("file removed from function must be removed from config map")
+	}
+
+	fn.UnsetConfigMap(&cm)
+	single.UnsetConfigMap(&cm)
+	if len(cm.Data) != 0 || len(cm.BinaryData) != 0 {
+		t.Errorf("config map must be empty once its functions are unset, got %v %v", cm.Data, cm.BinaryData)
+	}
+
+	fn.Spec.Data, fn.Spec.Handler = "print('inline')", ""
+	fn.Spec.Files["../escape.py"] = ""
+	fn.Spec.Files["a..b.py"] = ""
+	fn.Spec.BinaryFiles["handler.py"] = nil
+	if errs := fn.validateFiles(field.NewPath("spec")); len(errs) != 5 {
+		t.Errorf("data, handler, paths and duplicate must be invalid, got %v", errs)
+	}
+}
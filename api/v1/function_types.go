@@ -17,6 +17,7 @@ limitations under the License.
 package v1
 
 import (
+	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -44,6 +45,103 @@ type FunctionConfigMap struct {
 	Mount string `json:"mount,omitempty"`
 }
 
+// FunctionDeadLetter is where asynchronous invocations are delivered once every attempt failed,
+// either a function or an http url
+type FunctionDeadLetter struct {
+	// The name of dead-letter function in the same namespace
+	// +kubebuilder:validation:Optional
+	Function string `json:"function,omitempty"`
+
+	// Optional version of dead-letter function, the latest one if empty
+	// +kubebuilder:validation:Optional
+	Version string `json:"version,omitempty"`
+
+	// The http url of dead-letter sink
+	// +kubebuilder:validation:Optional
+	URL string `json:"url,omitempty"`
+}
+
+// FunctionAsync bulabula
+type FunctionAsync struct {
+	// How many times an asynchronous invocation is attempted, retried on 5xx codes and connection errors
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=3
+	MaxAttempts *int32 `json:"maxAttempts,omitempty"`
+
+	// How long to wait before the first retry, doubled on every retry
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="1s"
+	Backoff metav1.Duration `json:"backoff,omitempty"`
+
+	// The longest wait between retries
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="1m"
+	MaxBackoff metav1.Duration `json:"maxBackoff,omitempty"`
+
+	// Optional sink of invocations failing every attempt
+	// +kubebuilder:validation:Optional
+	DeadLetter *FunctionDeadLetter `json:"deadLetter,omitempty"`
+}
+
+// FunctionGitCredentials references the secret holding the credentials of a git repository
+type FunctionGitCredentials struct {
+	// The name of the secret in the namespace of function
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Optional key of the user name in secret
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="username"
+	UsernameKey string `json:"usernameKey,omitempty"`
+
+	// Optional key of the password or access token in secret
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="password"
+	PasswordKey string `json:"passwordKey,omitempty"`
+}
+
+// FunctionGitSource is a file of a git repository
+type FunctionGitSource struct {
+	// The http or https url of repository
+	// +kubebuilder:validation:Required
+	URL string `json:"url"`
+
+	// Optional branch, tag or commit fetched, the default branch if empty
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="HEAD"
+	Ref string `json:"ref,omitempty"`
+
+	// The path of function in repository, a file or a directory of files
+	// +kubebuilder:validation:Required
+	Path string `json:"path"`
+
+	// Optional secret holding the credentials of repository
+	// +kubebuilder:validation:Optional
+	CredentialsSecret *FunctionGitCredentials `json:"credentialsSecret,omitempty"`
+}
+
+// FunctionSource is where the code of function is fetched from instead of data, either a git repository,
+// an archive, or the key of a config map or secret
+type FunctionSource struct {
+	// Optional git repository of function
+	// +kubebuilder:validation:Optional
+	Git *FunctionGitSource `json:"git,omitempty"`
+
+	// Optional tar.gz archive of function, which holds only function unless path is set
+	// +kubebuilder:validation:Optional
+	Archive *ArchiveSource `json:"archive,omitempty"`
+
+	// Optional key of an existing config map in the namespace of function
+	// +kubebuilder:validation:Optional
+	ConfigMapKeyRef *apiv1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// Optional key of an existing secret in the namespace of function, which runtime pods mount from the secret,
+	// the creator of function must be allowed to get the secret
+	// +kubebuilder:validation:Optional
+	SecretKeyRef *apiv1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
 // FunctionSpec defines the desired state of Function
 type FunctionSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
@@ -76,6 +174,45 @@ type FunctionSpec struct {
 	// The binary of function
 	// +kubebuilder:validation:Optional
 	BinaryData []byte `json:"binaryData,omitempty"`
+
+	// Optional files of function by their path under the directory of function, exclusive with data and binaryData
+	// +kubebuilder:validation:Optional
+	Files map[string]string `json:"files,omitempty"`
+
+	// Optional binary files of function by their path under the directory of function
+	// +kubebuilder:validation:Optional
+	BinaryFiles map[string][]byte `json:"binaryFiles,omitempty"`
+
+	// Optional entrypoint of function written to the manifest, required when function has files or is sourced from a directory
+	// +kubebuilder:validation:Optional
+	Handler string `json:"handler,omitempty"`
+
+	// Optional source of function, exclusive with data and binaryData
+	// +kubebuilder:validation:Optional
+	Source *FunctionSource `json:"source,omitempty"`
+
+	// Optional retry policy of asynchronous invocations
+	// +kubebuilder:validation:Optional
+	Async *FunctionAsync `json:"async,omitempty"`
+}
+
+// FunctionSourceStatus is the commit function is pinned to, until the repository, ref or path changes
+type FunctionSourceStatus struct {
+	// The url of repository
+	// +kubebuilder:validation:Optional
+	URL string `json:"url,omitempty"`
+
+	// The ref resolved
+	// +kubebuilder:validation:Optional
+	Ref string `json:"ref,omitempty"`
+
+	// The path of function in repository
+	// +kubebuilder:validation:Optional
+	Path string `json:"path,omitempty"`
+
+	// The commit ref resolved to
+	// +kubebuilder:validation:Optional
+	Commit string `json:"commit,omitempty"`
 }
 
 // FunctionStatus defines the observed state of Function
@@ -86,6 +223,24 @@ type FunctionStatus struct {
 	// Optional ready string of runtime for show
 	// +kubebuilder:validation:Optional
 	Ready string `json:"ready,omitempty"`
+
+	// The generation observed by the function controller
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Optional source function is pinned to
+	// +kubebuilder:validation:Optional
+	Source *FunctionSourceStatus `json:"source,omitempty"`
+
+	// Optional config map or secret function was last read from
+	// +kubebuilder:validation:Optional
+	Reference *ReferenceStatus `json:"reference,omitempty"`
+
+	// Optional conditions of function
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:resource:categories="kess",shortName="fn"
@@ -95,6 +250,7 @@ type FunctionStatus struct {
 // +kubebuilder:printcolumn:name="Function",type=string,JSONPath=`.spec.function`,priority=0
 // +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.version`,priority=0
 // +kubebuilder:printcolumn:name="Runtime",type=string,JSONPath=`.spec.runtime`,priority=0
+// +kubebuilder:printcolumn:name="Commit",type=string,JSONPath=`.status.source.commit`,priority=1
 // +kubebuilder:object:root=true
 
 // Function is the Schema for the functions API
@@ -0,0 +1,281 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var functionlog = logf.Log.WithName("function-resource")
+
+// SetupWebhookWithManager bulabula
+func (r *Function) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-core-kess-io-v1-function,mutating=true,failurePolicy=fail,groups=core.kess.io,resources=functions,verbs=create;update,versions=v1,name=mfunction.kb.io
+
+var _ webhook.Defaulter = &Function{}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-core-kess-io-v1-function,mutating=false,failurePolicy=fail,groups=core.kess.io,resources=functions,versions=v1,name=vfunction.kb.io
+
+var _ webhook.Validator = &Function{}
+
+// ValidateCreate bulabula
+func (r *Function) ValidateCreate() error {
+	functionlog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate bulabula
+func (r *Function) ValidateUpdate(old runtime.Object) error {
+	functionlog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateDelete bulabula
+func (r *Function) ValidateDelete() error {
+	return nil
+}
+
+func (r *Function) validate() error {
+	var (
+		allErrs          field.ErrorList
+		specPath         = field.NewPath("spec")
+		runtimeConfigMap = r.RuntimeConfigMap()
+	)
+
+	if r.Spec.Runtime == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("runtime"), "function must reference a runtime"))
+	}
+	allErrs = append(allErrs, validateConfigMapName(specPath.Child("configMap", "name"), r.Spec.ConfigMap.Name, runtimeConfigMap.Name)...)
+	allErrs = append(allErrs, validateConfigMapMount(specPath.Child("configMap", "mount"), r.Spec.ConfigMap.Mount, runtimeConfigMap.Mount)...)
+	allErrs = append(allErrs, validateConfigMapKey(specPath.Child("file", "name"), r.Spec.File.Name, r.FileKey())...)
+	if key := r.FileKey(); strings.Contains(key, keyPathSeparator) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("file", "name"), r.Spec.File.Name,
+			fmt.Sprintf("renders to %q: must not contain %q, which separates directories in config map keys", key, keyPathSeparator)))
+	}
+	allErrs = append(allErrs, r.validateAsync(specPath.Child("async"))...)
+	allErrs = append(allErrs, r.validateSource(specPath.Child("source"))...)
+	allErrs = append(allErrs, r.validateFiles(specPath)...)
+
+	if len(allErrs) == 0 {
+		errs, err := r.validateFileKeyCollision(specPath.Child("file", "name"))
+		if err != nil {
+			return err
+		}
+		allErrs = append(allErrs, errs...)
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Function").GroupKind(), r.Name, allErrs)
+}
+
+// validateAsync bulabula
+func (r *Function) validateAsync(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	async := r.Spec.Async
+	if async == nil {
+		return allErrs
+	}
+	if async.MaxAttempts != nil && *async.MaxAttempts < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxAttempts"), *async.MaxAttempts, "must be at least 1"))
+	}
+	if async.Backoff.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("backoff"), async.Backoff.Duration.String(), "must not be negative"))
+	}
+	if async.MaxBackoff.Duration < async.Backoff.Duration {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxBackoff"), async.MaxBackoff.Duration.String(), "must not be less than backoff"))
+	}
+
+	if deadLetter := async.DeadLetter; deadLetter != nil {
+		deadLetterPath := fldPath.Child("deadLetter")
+		switch {
+		case deadLetter.Function == "" && deadLetter.URL == "":
+			allErrs = append(allErrs, field.Required(deadLetterPath, "dead letter must set either function or url"))
+		case deadLetter.Function != "" && deadLetter.URL != "":
+			allErrs = append(allErrs, field.Invalid(deadLetterPath.Child("url"), deadLetter.URL, "dead letter must not set both function and url"))
+		case deadLetter.Function == r.Spec.Function:
+			allErrs = append(allErrs, field.Invalid(deadLetterPath.Child("function"), deadLetter.Function, "dead letter must not be the function itself"))
+		case deadLetter.URL != "":
+			if u, err := url.Parse(deadLetter.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				allErrs = append(allErrs, field.Invalid(deadLetterPath.Child("url"), deadLetter.URL, "must be an http or https url"))
+			}
+		}
+	}
+	return allErrs
+}
+
+// validateSource bulabula
+func (r *Function) validateSource(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	source := r.Spec.Source
+	if source == nil {
+		return allErrs
+	}
+	sources := 0
+	for _, set := range []bool{source.Git != nil, source.Archive != nil, source.ConfigMapKeyRef != nil, source.SecretKeyRef != nil} {
+		if set {
+			sources++
+		}
+	}
+	switch {
+	case sources == 0:
+		allErrs = append(allErrs, field.Required(fldPath, "source must set one of git, archive, configMapKeyRef or secretKeyRef"))
+	case sources > 1:
+		allErrs = append(allErrs, field.Forbidden(fldPath, "source must set only one of git, archive, configMapKeyRef or secretKeyRef"))
+	}
+	if r.Spec.Data != "" || len(r.Spec.BinaryData) > 0 {
+		allErrs = append(allErrs, field.Forbidden(fldPath, "function must not set data or binaryData along with a source"))
+	}
+
+	if git := source.Git; git != nil {
+		gitPath := fldPath.Child("git")
+		if u, err := url.Parse(git.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+			allErrs = append(allErrs, field.Invalid(gitPath.Child("url"), git.URL, "must be an http or https url without credentials"))
+		}
+		if strings.HasPrefix(git.Ref, "-") || strings.ContainsAny(git.Ref, " ~^:?*[\\") {
+			allErrs = append(allErrs, field.Invalid(gitPath.Child("ref"), git.Ref, "must be a branch, a tag or a commit"))
+		}
+		if file := strings.TrimPrefix(git.Path, "/"); path.Clean(file) != file || file == "." || file == ".." || strings.HasPrefix(file, "../") {
+			allErrs = append(allErrs, field.Invalid(gitPath.Child("path"), git.Path, "must be the path of a file in repository"))
+		}
+		if secret := git.CredentialsSecret; secret != nil && secret.Name == "" {
+			allErrs = append(allErrs, field.Required(gitPath.Child("credentialsSecret", "name"), "credentials secret must have a name"))
+		}
+	}
+	if archive := source.Archive; archive != nil {
+		allErrs = append(allErrs, validateArchiveSource(fldPath.Child("archive"), archive, r.ArchiveURL())...)
+	}
+	if ref := source.ConfigMapKeyRef; ref != nil {
+		allErrs = append(allErrs, validateKeyReference(fldPath.Child("configMapKeyRef"), ref.Name, ref.Key)...)
+	}
+	if ref := source.SecretKeyRef; ref != nil {
+		allErrs = append(allErrs, validateKeyReference(fldPath.Child("secretKeyRef"), ref.Name, ref.Key)...)
+	}
+	return allErrs
+}
+
+// validateKeyReference checks the name and key of a referenced config map or secret
+func validateKeyReference(fldPath *field.Path, name, key string) field.ErrorList {
+	var allErrs field.ErrorList
+	if name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), "reference must have a name"))
+	}
+	for _, msg := range validation.IsConfigMapKey(key) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("key"), key, msg))
+	}
+	return allErrs
+}
+
+// validateFiles checks the files of function are relative paths, which are mounted under the directory of function
+func (r *Function) validateFiles(specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if !r.HasFiles() {
+		return allErrs
+	}
+	if r.Spec.Data != "" || len(r.Spec.BinaryData) > 0 || r.Spec.Source != nil {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("files"), "function must not set data, binaryData or a source along with files"))
+	}
+	if r.Spec.Handler == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("handler"), "function with files must specify the handler the runtime calls"))
+	}
+
+	key := r.FileKey()
+	for file := range r.Spec.Files {
+		allErrs = append(allErrs, validateFilePath(specPath.Child("files").Key(file), key, file)...)
+	}
+	for file := range r.Spec.BinaryFiles {
+		filePath := specPath.Child("binaryFiles").Key(file)
+		if _, ok := r.Spec.Files[file]; ok {
+			allErrs = append(allErrs, field.Duplicate(filePath, file))
+		}
+		allErrs = append(allErrs, validateFilePath(filePath, key, file)...)
+	}
+	return allErrs
+}
+
+// validateFilePath checks file is a relative path whose elements are valid config map keys
+func validateFilePath(fldPath *field.Path, dir, file string) field.ErrorList {
+	var allErrs field.ErrorList
+	if path.Clean(file) != file || path.IsAbs(file) || file == "." {
+		return append(allErrs, field.Invalid(fldPath, file, "must be a relative path without empty, . or .. elements"))
+	}
+	for _, element := range strings.Split(file, "/") {
+		if strings.Contains(element, keyPathSeparator) {
+			allErrs = append(allErrs, field.Invalid(fldPath, file, fmt.Sprintf("must not contain %q", keyPathSeparator)))
+			continue
+		}
+		for _, msg := range validation.IsConfigMapKey(element) {
+			allErrs = append(allErrs, field.Invalid(fldPath, file, msg))
+		}
+	}
+	for _, msg := range validation.IsConfigMapKey(fileKey(dir, file)) {
+		allErrs = append(allErrs, field.Invalid(fldPath, file, fmt.Sprintf("renders to key %q: %s", fileKey(dir, file), msg)))
+	}
+	return allErrs
+}
+
+// validateFileKeyCollision rejects functions sharing a config map and file key with another function,
+// which would otherwise silently overwrite each other's content.
+func (r *Function) validateFileKeyCollision(fldPath *field.Path) (field.ErrorList, error) {
+	var (
+		allErrs field.ErrorList
+		fns     FunctionList
+		cmName  = r.RuntimeConfigMap().Name
+		key     = r.FileKey()
+	)
+
+	if webhookClient == nil {
+		return allErrs, nil
+	}
+	if err := webhookClient.List(context.Background(), &fns, client.InNamespace(r.Namespace)); err != nil {
+		return allErrs, err
+	}
+
+	for _, fn := range fns.Items {
+		if fn.Name == r.Name {
+			continue
+		}
+		if fn.RuntimeConfigMap().Name == cmName && fn.FileKey() == key {
+			allErrs = append(allErrs, field.Invalid(fldPath, r.Spec.File.Name,
+				fmt.Sprintf("file key %q in config map %q collides with function %q", key, cmName, fn.Name)))
+		}
+	}
+	return allErrs, nil
+}
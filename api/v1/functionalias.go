@@ -0,0 +1,162 @@
+package v1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Default bulabula
+func (r *FunctionAlias) Default() {
+	namedVersion := r.NamedVersion()
+
+	if r.Spec.Function == "" {
+		r.Spec.Function = namedVersion.Name
+	}
+	if r.Spec.Alias == "" {
+		r.Spec.Alias = namedVersion.Version
+	}
+
+	if r.ObjectMeta.Labels == nil {
+		r.ObjectMeta.Labels = make(map[string]string)
+	}
+	for k, v := range r.Labels() {
+		r.ObjectMeta.Labels[k] = v
+	}
+}
+
+// DefaultStatus bulabula
+func (r *FunctionAlias) DefaultStatus() {
+	if r.Status.Ready == "" {
+		r.Status.Ready = DefaultReady
+	}
+}
+
+// NamedVersion bulabula
+func (r *FunctionAlias) NamedVersion() NamedVersion {
+	return NamedVersionFromString(r.Name)
+}
+
+// Labels bulabula
+func (r *FunctionAlias) Labels() map[string]string {
+	return map[string]string{
+		LabelType:     TypeFunctionAlias,
+		LabelFunction: r.Spec.Function,
+	}
+}
+
+// NamespacedName bulabula
+func (r *FunctionAlias) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      r.Name,
+		Namespace: r.Namespace,
+	}
+}
+
+// SetCondition bulabula
+func (r *FunctionAlias) SetCondition(condition Condition) {
+	condition.ObservedGeneration = r.Generation
+	SetCondition(&r.Status.Conditions, condition)
+}
+
+// Weights returns the effective weights of the versions of alias, versions without weight share
+// the rest of 100 evenly, the first of them taking the remainder
+func (r *FunctionAlias) Weights() []int32 {
+	var (
+		weights = make([]int32, len(r.Spec.Versions))
+		rest    = int32(100)
+		unset   int32
+	)
+
+	for i, version := range r.Spec.Versions {
+		if version.Weight == nil {
+			unset++
+			continue
+		}
+		weights[i] = *version.Weight
+		rest -= *version.Weight
+	}
+	if unset == 0 || rest <= 0 {
+		return weights
+	}
+
+	share, remainder := rest/unset, rest%unset
+	for i, version := range r.Spec.Versions {
+		if version.Weight != nil {
+			continue
+		}
+		weights[i] = share
+		if remainder > 0 {
+			weights[i]++
+			remainder--
+		}
+	}
+	return weights
+}
+
+// UpdateStatusVersions resolves the versions and rules of alias to the concrete versions of fns, latest resolves to
+// the function of version latest if any, or else the greatest version
+func (r *FunctionAlias) UpdateStatusVersions(fns []Function) {
+	var (
+		versions = make(map[string]*Function)
+		latest   *Function
+		missing  []string
+		weights  = r.Weights()
+	)
+
+	for i := range fns {
+		fn := &fns[i]
+		if fn.Spec.Function != r.Spec.Function || !fn.DeletionTimestamp.IsZero() {
+			continue
+		}
+		versions[fn.Spec.Version] = fn
+		if latest == nil || latest.Spec.Version != LatestVersion &&
+			(fn.Spec.Version == LatestVersion || CompareVersions(fn.Spec.Version, latest.Spec.Version) > 0) {
+			latest = fn
+		}
+	}
+	resolve := func(version string) (*Function, bool) {
+		if version == LatestVersion {
+			return latest, latest != nil
+		}
+		fn, ok := versions[version]
+		return fn, ok
+	}
+
+	r.Status.Versions = nil
+	for i, version := range r.Spec.Versions {
+		fn, ok := resolve(version.Version)
+		if !ok {
+			missing = append(missing, version.Version)
+			continue
+		}
+		r.Status.Versions = append(r.Status.Versions, FunctionAliasTarget{Version: fn.Spec.Version, Name: fn.Name, Weight: weights[i]})
+	}
+
+	r.Status.Rules = nil
+	for _, rule := range r.Spec.Rules {
+		fn, ok := resolve(rule.Version)
+		if !ok {
+			missing = append(missing, rule.Version)
+			continue
+		}
+		resolved := *rule.DeepCopy()
+		resolved.Version = fn.Spec.Version
+		r.Status.Rules = append(r.Status.Rules, resolved)
+	}
+
+	if len(missing) == 0 {
+		r.SetCondition(NewCondition(ConditionResolved, true, ReasonReconciled, ""))
+	} else {
+		r.SetCondition(NewCondition(ConditionResolved, false, ReasonVersionNotFound,
+			fmt.Sprintf("versions %s of function %q are not found", strings.Join(missing, ", "), r.Spec.Function)))
+	}
+	resolved := FindCondition(r.Status.Conditions, ConditionResolved)
+	r.SetCondition(NewCondition(ConditionReady, resolved.Status == metav1.ConditionTrue, resolved.Reason, resolved.Message))
+
+	r.Status.Ready = strconv.Itoa(len(r.Status.Versions)) + "/" + strconv.Itoa(len(r.Spec.Versions))
+	r.Status.ObservedGeneration = r.Generation
+}
@@ -0,0 +1,109 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/xorcare/pointer"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFunctionAliasUpdateStatusVersions(t *testing.T) {
+	fns := []Function{*goldenFunction("sample-v2"), *goldenFunction("sample-v10"), *goldenFunction("other-v1")}
+
+	alias := &FunctionAlias{ObjectMeta: metav1.ObjectMeta{Name: "sample-prod", Namespace: "kess-samples"}}
+	alias.Default()
+	if alias.Spec.Function != "sample" || alias.Spec.Alias != "prod" {
+		t.Fatalf("function and alias must default from the name, got %+v", alias.Spec)
+	}
+
+	alias.Spec.Versions = []FunctionAliasVersion{{Version: "v2"}, {Version: LatestVersion}}
+	alias.UpdateStatusVersions(fns)
+	want := []FunctionAliasTarget{{Version: "v2", Name: "sample-v2", Weight: 50}, {Version: "v10", Name: "sample-v10", Weight: 50}}
+	if len(alias.Status.Versions) != len(want) || alias.Status.Versions[0] != want[0] || alias.Status.Versions[1] != want[1] {
+		t.Errorf("unexpected versions %+v, want %+v", alias.Status.Versions, want)
+	}
+	if !IsConditionTrue(alias.Status.Conditions, ConditionReady) || alias.Status.Ready != "2/2" {
+		t.Errorf("alias must be ready once all versions are resolved, got %+v", alias.Status)
+	}
+
+	alias.Spec.Versions = []FunctionAliasVersion{{Version: "v3"}, {Version: "v2"}}
+	alias.UpdateStatusVersions(fns)
+	if len(alias.Status.Versions) != 1 || alias.Status.Versions[0].Version != "v2" {
+		t.Errorf("unexpected versions %+v", alias.Status.Versions)
+	}
+	if condition := FindCondition(alias.Status.Conditions, ConditionResolved); condition.Status != metav1.ConditionFalse || condition.Reason != ReasonVersionNotFound {
+		t.Errorf("missing version must be reported, got %+v", condition)
+	}
+	if alias.Status.Ready != "1/2" {
+		t.Errorf("unexpected ready %q", alias.Status.Ready)
+	}
+}
+
+func TestFunctionAliasWeights(t *testing.T) {
+	cases := []struct {
+		weights []*int32
+		want    []int32
+	}{
+		{[]*int32{nil, nil, nil}, []int32{34, 33, 33}},
+		{[]*int32{pointer.Int32(95), nil}, []int32{95, 5}},
+		{[]*int32{pointer.Int32(90), pointer.Int32(5)}, []int32{90, 5}},
+		{[]*int32{pointer.Int32(100), nil}, []int32{100, 0}},
+	}
+	for _, c := range cases {
+		alias := &FunctionAlias{}
+		for _, weight := range c.weights {
+			alias.Spec.Versions = append(alias.Spec.Versions, FunctionAliasVersion{Weight: weight})
+		}
+		if weights := alias.Weights(); !reflect.DeepEqual(weights, c.want) {
+			t.Errorf("unexpected weights %v, want %v", weights, c.want)
+		}
+	}
+}
+
+func TestFunctionAliasUpdateStatusRules(t *testing.T) {
+	fns := []Function{*goldenFunction("sample-v1"), *goldenFunction("sample-v2")}
+
+	alias := &FunctionAlias{ObjectMeta: metav1.ObjectMeta{Name: "sample-prod", Namespace: "kess-samples"}}
+	alias.Default()
+	alias.Spec.Versions = []FunctionAliasVersion{{Version: "v1"}}
+	alias.Spec.Rules = []FunctionAliasRule{
+		{Headers: map[string]string{"X-Canary": "true"}, Version: LatestVersion},
+		{Headers: map[string]string{"X-Canary": "v3"}, Version: "v3"},
+	}
+	alias.UpdateStatusVersions(fns)
+
+	if len(alias.Status.Rules) != 1 || alias.Status.Rules[0].Version != "v2" {
+		t.Errorf("rules must be resolved to concrete versions, got %+v", alias.Status.Rules)
+	}
+	if alias.Spec.Rules[0].Version != LatestVersion {
+		t.Error("resolving rules must not change the spec")
+	}
+	if IsConditionTrue(alias.Status.Conditions, ConditionResolved) {
+		t.Error("rules with missing versions must be reported")
+	}
+}
+
+func TestFunctionAliasValidateWeights(t *testing.T) {
+	alias := &FunctionAlias{ObjectMeta: metav1.ObjectMeta{Name: "sample-prod"}}
+	alias.Default()
+
+	alias.Spec.Versions = []FunctionAliasVersion{{Version: "v1", Weight: pointer.Int32(95)}, {Version: "v2", Weight: pointer.Int32(10)}}
+	if err := alias.ValidateCreate(); err == nil {
+		t.Error("weights over 100 must be rejected")
+	}
+	alias.Spec.Versions = []FunctionAliasVersion{{Version: "v1", Weight: pointer.Int32(0)}}
+	if err := alias.ValidateCreate(); err == nil {
+		t.Error("weights summing to zero must be rejected")
+	}
+	alias.Spec.Versions = []FunctionAliasVersion{{Version: "v1", Weight: pointer.Int32(95)}, {Version: "v2"}}
+	alias.Spec.Rules = []FunctionAliasRule{{Version: "v2"}}
+	if err := alias.ValidateCreate(); err == nil {
+		t.Error("rules without headers must be rejected")
+	}
+	alias.Spec.Rules[0].Headers = map[string]string{"X-Canary": "true"}
+	if err := alias.ValidateCreate(); err != nil {
+		t.Errorf("valid alias must be accepted, got %v", err)
+	}
+}
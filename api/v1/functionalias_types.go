@@ -0,0 +1,134 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FunctionAliasVersion bulabula
+type FunctionAliasVersion struct {
+	// The version of function, latest resolves to the latest version
+	// +kubebuilder:validation:Required
+	Version string `json:"version"`
+
+	// Optional percentage of requests sent to the version, versions without weight share the rest of 100 evenly
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight *int32 `json:"weight,omitempty"`
+}
+
+// FunctionAliasRule bulabula
+type FunctionAliasRule struct {
+	// Request headers which must all be present with exactly these values
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinProperties=1
+	Headers map[string]string `json:"headers"`
+
+	// The version of function matching requests are sent to, latest resolves to the latest version
+	// +kubebuilder:validation:Required
+	Version string `json:"version"`
+}
+
+// FunctionAliasSpec defines the desired state of FunctionAlias
+type FunctionAliasSpec struct {
+	// Optional function name of alias
+	// +kubebuilder:validation:Optional
+	Function string `json:"function,omitempty"`
+
+	// Optional alias name, used in place of a version by callers
+	// +kubebuilder:validation:Optional
+	Alias string `json:"alias,omitempty"`
+
+	// The function versions of alias, requests are split between them by weight
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	Versions []FunctionAliasVersion `json:"versions"`
+
+	// Optional rules sending matching requests to a version regardless of weights, the first matching rule wins
+	// +kubebuilder:validation:Optional
+	Rules []FunctionAliasRule `json:"rules,omitempty"`
+}
+
+// FunctionAliasTarget bulabula
+type FunctionAliasTarget struct {
+	// The concrete version of function
+	Version string `json:"version"`
+
+	// The name of the function object
+	Name string `json:"name"`
+
+	// The percentage of requests sent to the version
+	// +kubebuilder:validation:Optional
+	Weight int32 `json:"weight,omitempty"`
+}
+
+// FunctionAliasStatus defines the observed state of FunctionAlias
+type FunctionAliasStatus struct {
+	// Optional concrete function versions of alias
+	// +kubebuilder:validation:Optional
+	Versions []FunctionAliasTarget `json:"versions,omitempty"`
+
+	// Optional rules of alias with concrete versions
+	// +kubebuilder:validation:Optional
+	Rules []FunctionAliasRule `json:"rules,omitempty"`
+
+	// Optional ready string of alias for show
+	// +kubebuilder:validation:Optional
+	Ready string `json:"ready,omitempty"`
+
+	// The generation observed by the function alias controller
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Optional conditions of alias
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:resource:categories="kess",shortName="fnalias"
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.ready`,priority=0
+// +kubebuilder:printcolumn:name="Function",type=string,JSONPath=`.spec.function`,priority=0
+// +kubebuilder:printcolumn:name="Alias",type=string,JSONPath=`.spec.alias`,priority=0
+// +kubebuilder:printcolumn:name="Versions",type=string,JSONPath=`.status.versions[*].version`,priority=0
+// +kubebuilder:object:root=true
+
+// FunctionAlias is the Schema for the functionaliases API
+type FunctionAlias struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FunctionAliasSpec   `json:"spec,omitempty"`
+	Status FunctionAliasStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// FunctionAliasList contains a list of FunctionAlias
+type FunctionAliasList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FunctionAlias `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FunctionAlias{}, &FunctionAliasList{})
+}
@@ -0,0 +1,171 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var functionaliaslog = logf.Log.WithName("functionalias-resource")
+
+// SetupWebhookWithManager bulabula
+func (r *FunctionAlias) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-core-kess-io-v1-functionalias,mutating=true,failurePolicy=fail,groups=core.kess.io,resources=functionaliases,verbs=create;update,versions=v1,name=mfunctionalias.kb.io
+
+var _ webhook.Defaulter = &FunctionAlias{}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-core-kess-io-v1-functionalias,mutating=false,failurePolicy=fail,groups=core.kess.io,resources=functionaliases,versions=v1,name=vfunctionalias.kb.io
+
+var _ webhook.Validator = &FunctionAlias{}
+
+// ValidateCreate bulabula
+func (r *FunctionAlias) ValidateCreate() error {
+	functionaliaslog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate bulabula
+func (r *FunctionAlias) ValidateUpdate(old runtime.Object) error {
+	functionaliaslog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateDelete bulabula
+func (r *FunctionAlias) ValidateDelete() error {
+	return nil
+}
+
+func (r *FunctionAlias) validate() error {
+	var (
+		allErrs  field.ErrorList
+		specPath = field.NewPath("spec")
+		versions = make(map[string]bool)
+	)
+
+	if r.Spec.Function == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("function"), "alias must reference a function"))
+	}
+	for _, msg := range validation.IsDNS1123Label(r.Spec.Alias) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("alias"), r.Spec.Alias, msg))
+	}
+	if r.Spec.Alias == LatestVersion {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("alias"), r.Spec.Alias, "latest is reserved for the latest version"))
+	}
+	if len(r.Spec.Versions) == 0 {
+		allErrs = append(allErrs, field.Required(specPath.Child("versions"), "alias must point to at least one version"))
+	}
+	for i, version := range r.Spec.Versions {
+		versionPath := specPath.Child("versions").Index(i).Child("version")
+		if version.Version == "" {
+			allErrs = append(allErrs, field.Required(versionPath, ""))
+		} else if versions[version.Version] {
+			allErrs = append(allErrs, field.Duplicate(versionPath, version.Version))
+		}
+		versions[version.Version] = true
+	}
+	allErrs = append(allErrs, r.validateWeights(specPath.Child("versions"))...)
+	for i, rule := range r.Spec.Rules {
+		rulePath := specPath.Child("rules").Index(i)
+		if len(rule.Headers) == 0 {
+			allErrs = append(allErrs, field.Required(rulePath.Child("headers"), "rule must match at least one header"))
+		}
+		if rule.Version == "" {
+			allErrs = append(allErrs, field.Required(rulePath.Child("version"), ""))
+		}
+	}
+
+	if len(allErrs) == 0 {
+		errs, err := r.validateAliasCollision(specPath.Child("alias"))
+		if err != nil {
+			return err
+		}
+		allErrs = append(allErrs, errs...)
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("FunctionAlias").GroupKind(), r.Name, allErrs)
+}
+
+// validateWeights rejects weights summing to more than 100, or to zero when every version has a weight,
+// requests could not be split between the versions then.
+func (r *FunctionAlias) validateWeights(fldPath *field.Path) field.ErrorList {
+	var (
+		allErrs field.ErrorList
+		total   int32
+		unset   bool
+	)
+
+	for _, version := range r.Spec.Versions {
+		if version.Weight == nil {
+			unset = true
+			continue
+		}
+		total += *version.Weight
+	}
+	if total > 100 {
+		allErrs = append(allErrs, field.Invalid(fldPath, total, "weights of versions must not sum to more than 100"))
+	}
+	if !unset && len(r.Spec.Versions) > 0 && total == 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath, total, "at least one version must have a positive weight"))
+	}
+	return allErrs
+}
+
+// validateAliasCollision rejects aliases with the same function and alias name as another alias,
+// routing could not tell them apart.
+func (r *FunctionAlias) validateAliasCollision(fldPath *field.Path) (field.ErrorList, error) {
+	var (
+		allErrs field.ErrorList
+		aliases FunctionAliasList
+	)
+
+	if webhookClient == nil {
+		return allErrs, nil
+	}
+	if err := webhookClient.List(context.Background(), &aliases, client.InNamespace(r.Namespace)); err != nil {
+		return allErrs, err
+	}
+
+	for _, alias := range aliases.Items {
+		if alias.Name == r.Name {
+			continue
+		}
+		if alias.Spec.Function == r.Spec.Function && alias.Spec.Alias == r.Spec.Alias {
+			allErrs = append(allErrs, field.Invalid(fldPath, r.Spec.Alias,
+				fmt.Sprintf("alias %q of function %q is already defined by %q", r.Spec.Alias, r.Spec.Function, alias.Name)))
+		}
+	}
+	return allErrs, nil
+}
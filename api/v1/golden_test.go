@@ -0,0 +1,298 @@
+package v1
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/xorcare/pointer"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func goldenRuntime() *Runtime {
+	rt := &Runtime{
+		ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "kess-samples"},
+		Spec: RuntimeSpec{
+			Image:       "python:3",
+			Command:     []string{"python", "-m", "http.server"},
+			Port:        8000,
+			PortName:    "http",
+			Replicas:    pointer.Int32(2),
+			ReadyFormat: "{AvailableReplicas}/{AvailableReplicas}",
+		},
+	}
+	rt.Default()
+	rt.DefaultStatus()
+	return rt
+}
+
+func goldenMountedRuntime(volumeType RuntimeVolumeType) *Runtime {
+	var (
+		rt   = goldenRuntime()
+		fns  = []Function{*goldenFunction("sample-v2"), *goldenFunction("sample-v1"), *goldenFunction("other-v1"), *goldenFilesFunction("tasks-v1")}
+		libs = []Library{*goldenLibrary("util-v1"), *goldenLibrary("base-v1")}
+		cms  = make(map[string]*apiv1.ConfigMap)
+	)
+
+	// A model over the config map limit is only mountable through the chunked volume
+	if volumeType == ChunkedRuntimeVolumeType {
+		libs[0].Spec.BinaryData["model.bin"] = bytes.Repeat([]byte{0x6b}, ChunkConfigMapLimit+ChunkSize)
+	}
+
+	rt.Spec.Volume.Type = volumeType
+	rt.UpdateStatusConfigMaps(fns, libs)
+
+	for _, fn := range fns {
+		cm, ok := cms[fn.RuntimeConfigMap().Name]
+		if !ok {
+			configMap := fn.ConfigMap()
+			cm = &configMap
+			cms[cm.Name] = cm
+		}
+		fn.SetConfigMap(cm)
+	}
+	for _, lib := range libs {
+		cm := lib.ConfigMap()
+		ChunkConfigMap(&cm)
+		cms[cm.Name] = &cm
+	}
+
+	var mounted []apiv1.ConfigMap
+	for _, namespacedName := range rt.ConfigMapNamespacedNames() {
+		mounted = append(mounted, *cms[namespacedName.Name])
+	}
+	rt.UpdateStatusConfigMapChunks(mounted)
+	rt.UpdateStatusConfigMapsHash(mounted)
+
+	return rt
+}
+
+func goldenAutoscalingRuntime() *Runtime {
+	rt := goldenRuntime()
+	rt.Spec.Autoscaling = &RuntimeAutoscaling{
+		MinReplicas:                    pointer.Int32(1),
+		MaxReplicas:                    5,
+		TargetCPUUtilizationPercentage: pointer.Int32(80),
+		Metrics: []autoscalingv2beta2.MetricSpec{
+			{
+				Type: autoscalingv2beta2.PodsMetricSourceType,
+				Pods: &autoscalingv2beta2.PodsMetricSource{
+					Metric: autoscalingv2beta2.MetricIdentifier{Name: "requests_per_second"},
+					Target: autoscalingv2beta2.MetricTarget{
+						Type:         autoscalingv2beta2.AverageValueMetricType,
+						AverageValue: resource.NewQuantity(100, resource.DecimalSI),
+					},
+				},
+			},
+		},
+	}
+	return rt
+}
+
+func goldenScaleToZeroRuntime() *Runtime {
+	rt := goldenRuntime()
+	rt.CreationTimestamp = metav1.NewTime(time.Date(2020, 9, 9, 0, 0, 0, 0, time.UTC))
+	rt.Spec.ScaleToZero = &RuntimeScaleToZero{}
+	rt.Default()
+	return rt
+}
+
+func goldenFunction(name string) *Function {
+	fn := &Function{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kess-samples"},
+		Spec: FunctionSpec{
+			Runtime:   "sample",
+			File:      FunctionFile{Name: "{Version}.py"},
+			ConfigMap: FunctionConfigMap{Name: "fn-{Name}", Mount: "/kess/fn/{Name}"},
+			Data:      "print(\"" + name + "\")\n",
+		},
+	}
+	fn.Default()
+	return fn
+}
+
+func goldenFilesFunction(name string) *Function {
+	fn := &Function{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kess-samples"},
+		Spec: FunctionSpec{
+			Runtime:   "sample",
+			File:      FunctionFile{Name: "{Version}"},
+			ConfigMap: FunctionConfigMap{Name: "fn-{Name}", Mount: "/kess/fn/{Name}"},
+			Files: map[string]string{
+				"handler.py":        "from .utils.greet import greet\n\ndef main(event):\n    return greet(event)\n",
+				"utils/__init__.py": "",
+				"utils/greet.py":    "def greet(event):\n    return \"hello \" + event\n",
+			},
+			BinaryFiles: map[string][]byte{
+				"fixtures/event.bin": {0x6b, 0x65, 0x73, 0x73},
+			},
+			Handler: "handler.main",
+		},
+	}
+	fn.Default()
+	return fn
+}
+
+func goldenLibrary(name string) *Library {
+	lib := &Library{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kess-samples"},
+		Spec: LibrarySpec{
+			Runtime:   "sample",
+			ConfigMap: LibraryConfigMap{Name: "lib-{Name}-{Version}", Mount: "/kess/lib/{Name}-{Version}"},
+			Data: map[string]string{
+				"__init__.py": "from .util import *\n",
+				"util.py":     "def test():\n    print(\"" + name + "\")\n",
+			},
+			BinaryData: map[string][]byte{
+				"data.bin": {0x6b, 0x65, 0x73, 0x73},
+			},
+		},
+	}
+	lib.Default()
+	return lib
+}
+
+func TestGolden(t *testing.T) {
+	cases := []struct {
+		name   string
+		render func() (interface{}, error)
+	}{
+		{"runtime_deployment", func() (interface{}, error) {
+			return goldenRuntime().Deployment()
+		}},
+		{"runtime_deployment_mounted", func() (interface{}, error) {
+			return goldenMountedRuntime(ConfigMapRuntimeVolumeType).Deployment()
+		}},
+		{"runtime_deployment_projected", func() (interface{}, error) {
+			return goldenMountedRuntime(ProjectedRuntimeVolumeType).Deployment()
+		}},
+		{"runtime_deployment_chunked", func() (interface{}, error) {
+			return goldenMountedRuntime(ChunkedRuntimeVolumeType).Deployment()
+		}},
+		{"runtime_deployment_template", func() (interface{}, error) {
+			rt := goldenMountedRuntime(ConfigMapRuntimeVolumeType)
+			rt.Spec.UpdateStrategy.Type = SignalRuntimeUpdateStrategyType
+			rt.Spec.Template = &apiv1.PodTemplateSpec{
+				Spec: apiv1.PodSpec{
+					ServiceAccountName: "sample",
+					Containers: []apiv1.Container{
+						{
+							Env: []apiv1.EnvVar{{Name: "PYTHONUNBUFFERED", Value: "1"}},
+							Resources: apiv1.ResourceRequirements{
+								Limits: apiv1.ResourceList{apiv1.ResourceCPU: resource.MustParse("200m")},
+							},
+						},
+						{Name: "sidecar", Image: "busybox"},
+					},
+				},
+			}
+			return rt.Deployment()
+		}},
+		{"runtime_deployment_autoscaling", func() (interface{}, error) {
+			return goldenAutoscalingRuntime().Deployment()
+		}},
+		{"runtime_hpa", func() (interface{}, error) {
+			return goldenAutoscalingRuntime().HorizontalPodAutoscaler(), nil
+		}},
+		{"runtime_deployment_scaled_to_zero", func() (interface{}, error) {
+			rt := goldenScaleToZeroRuntime()
+			rt.UpdateStatusActivity(rt.CreationTimestamp.Add(time.Hour))
+			return rt.Deployment()
+		}},
+		{"runtime_service_scale_to_zero", func() (interface{}, error) {
+			return goldenScaleToZeroRuntime().Service(), nil
+		}},
+		{"runtime_private_service", func() (interface{}, error) {
+			return goldenScaleToZeroRuntime().PrivateService(), nil
+		}},
+		{"runtime_activator_deployment", func() (interface{}, error) {
+			return goldenScaleToZeroRuntime().ActivatorDeployment("yamajik/kess:latest", "http://activity.kess-system.svc:8082/activity"), nil
+		}},
+		{"runtime_service", func() (interface{}, error) {
+			return goldenRuntime().Service(), nil
+		}},
+		{"function_configmap", func() (interface{}, error) {
+			return goldenFunction("sample-v1").ConfigMap(), nil
+		}},
+		{"function_configmap_files", func() (interface{}, error) {
+			return goldenFilesFunction("tasks-v1").ConfigMap(), nil
+		}},
+		{"library_configmap", func() (interface{}, error) {
+			return goldenLibrary("util-v1").ConfigMap(), nil
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := renderGolden(t, c.render)
+
+			// Rendering must not depend on map iteration order
+			for i := 0; i < 10; i++ {
+				if again := renderGolden(t, c.render); !bytes.Equal(got, again) {
+					t.Fatalf("rendering is not deterministic:\n%s\n---\n%s", got, again)
+				}
+			}
+
+			path := filepath.Join("testdata", c.name+".golden.yaml")
+			if *update {
+				if err := ioutil.WriteFile(path, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatalf("unable to read golden file, run go test with -update to create it: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s does not match, run go test with -update if the change is intended:\n%s", path, got)
+			}
+		})
+	}
+}
+
+func TestUpdateStatusActivity(t *testing.T) {
+	rt := goldenScaleToZeroRuntime()
+	created := rt.CreationTimestamp.Time
+
+	if idleAfter := rt.UpdateStatusActivity(created.Add(5 * time.Minute)); idleAfter != 10*time.Minute || rt.Status.ScaledToZero {
+		t.Errorf("runtime without activity must stay up for one idle window after creation, got %s, scaled to zero %t", idleAfter, rt.Status.ScaledToZero)
+	}
+	if idleAfter := rt.UpdateStatusActivity(created.Add(15 * time.Minute)); idleAfter != 0 || !rt.Status.ScaledToZero {
+		t.Errorf("runtime must be scaled to zero once idle, got %s, scaled to zero %t", idleAfter, rt.Status.ScaledToZero)
+	}
+
+	rt.Annotations = map[string]string{AnnotationLastActivity: created.Add(20 * time.Minute).Format(time.RFC3339Nano)}
+	if idleAfter := rt.UpdateStatusActivity(created.Add(21 * time.Minute)); idleAfter != 14*time.Minute || rt.Status.ScaledToZero {
+		t.Errorf("reported activity must scale runtime up again, got %s, scaled to zero %t", idleAfter, rt.Status.ScaledToZero)
+	}
+	if !rt.Status.LastActivityTime.Time.Equal(created.Add(20 * time.Minute)) {
+		t.Errorf("unexpected last activity time %s", rt.Status.LastActivityTime)
+	}
+
+	rt.Spec.ScaleToZero = nil
+	if idleAfter := rt.UpdateStatusActivity(created.Add(time.Hour)); idleAfter != 0 || rt.Status.ScaledToZero || rt.Status.LastActivityTime != nil {
+		t.Errorf("runtime without scale-to-zero must not be scaled to zero")
+	}
+}
+
+func renderGolden(t *testing.T, render func() (interface{}, error)) []byte {
+	obj, err := render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := yaml.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
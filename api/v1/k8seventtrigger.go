@@ -0,0 +1,134 @@
+package v1
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/xorcare/pointer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// K8sEventKinds are the kinds of resources k8s event triggers may watch, the manager is only granted to read these
+var K8sEventKinds = map[schema.GroupKind]bool{
+	{Kind: "ConfigMap"}:                                true,
+	{Kind: "Endpoints"}:                                true,
+	{Kind: "Event"}:                                    true,
+	{Kind: "PersistentVolumeClaim"}:                    true,
+	{Kind: "Pod"}:                                      true,
+	{Kind: "Service"}:                                  true,
+	{Group: "apps", Kind: "DaemonSet"}:                 true,
+	{Group: "apps", Kind: "Deployment"}:                true,
+	{Group: "apps", Kind: "ReplicaSet"}:                true,
+	{Group: "apps", Kind: "StatefulSet"}:               true,
+	{Group: "batch", Kind: "CronJob"}:                  true,
+	{Group: "batch", Kind: "Job"}:                      true,
+	{Group: GroupVersion.Group, Kind: "Function"}:      true,
+	{Group: GroupVersion.Group, Kind: "FunctionAlias"}: true,
+	{Group: GroupVersion.Group, Kind: "Library"}:       true,
+	{Group: GroupVersion.Group, Kind: "Runtime"}:       true,
+	{Group: GroupVersion.Group, Kind: "Workflow"}:      true,
+	{Group: GroupVersion.Group, Kind: "WorkflowRun"}:   true,
+}
+
+// Default bulabula
+func (r *K8sEventTrigger) Default() {
+	if r.ObjectMeta.Labels == nil {
+		r.ObjectMeta.Labels = make(map[string]string)
+	}
+	for k, v := range r.Labels() {
+		r.ObjectMeta.Labels[k] = v
+	}
+
+	r.Spec.TriggerTarget.Default()
+	if r.Spec.HistoryLimit == nil {
+		r.Spec.HistoryLimit = pointer.Int32(DefaultTriggerHistoryLimit)
+	}
+}
+
+// DefaultStatus bulabula
+func (r *K8sEventTrigger) DefaultStatus() {}
+
+// Labels bulabula
+func (r *K8sEventTrigger) Labels() map[string]string {
+	return map[string]string{
+		LabelType:     TypeK8sEventTrigger,
+		LabelFunction: r.Spec.Function,
+	}
+}
+
+// NamespacedName bulabula
+func (r *K8sEventTrigger) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      r.Name,
+		Namespace: r.Namespace,
+	}
+}
+
+// GroupVersionKind returns the kind of resources watched
+func (r *K8sEventTrigger) GroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{
+		Group:   r.Spec.Resource.Group,
+		Version: r.Spec.Resource.Version,
+		Kind:    r.Spec.Resource.Kind,
+	}
+}
+
+// Watchable reports whether the kind of resources is one of K8sEventKinds
+func (r *K8sEventTrigger) Watchable() bool {
+	return K8sEventKinds[r.GroupVersionKind().GroupKind()]
+}
+
+// watchableKinds returns K8sEventKinds as Kind.group strings
+func watchableKinds() []string {
+	kinds := make([]string, 0, len(K8sEventKinds))
+	for gk := range K8sEventKinds {
+		kinds = append(kinds, gk.String())
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Delivers reports whether events of type eventType are delivered to function
+func (r *K8sEventTrigger) Delivers(eventType K8sEventType) bool {
+	if len(r.Spec.Events) == 0 {
+		return true
+	}
+	for _, t := range r.Spec.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCondition bulabula
+func (r *K8sEventTrigger) SetCondition(condition Condition) {
+	condition.ObservedGeneration = r.Generation
+	SetCondition(&r.Status.Conditions, condition)
+}
+
+// UpdateStatusWatch records the watch of trigger and the invocations since the last update, oldest first,
+// message is why resources are not watched
+func (r *K8sEventTrigger) UpdateStatusWatch(watching bool, message string, lastEvent time.Time, invocations []TriggerInvocation) {
+	for _, invocation := range invocations {
+		r.Status.History = prependInvocation(r.Status.History, invocation, *r.Spec.HistoryLimit)
+	}
+	if !lastEvent.IsZero() {
+		r.Status.LastEventTime = &metav1.Time{Time: lastEvent}
+	}
+	r.Status.ObservedGeneration = r.Generation
+
+	if watching {
+		message = fmt.Sprintf("watching %s in namespace %s", r.Spec.Resource.Kind, r.Namespace)
+		r.SetCondition(NewCondition(ConditionReady, true, ReasonWatching, message))
+		return
+	}
+	reason := ReasonWatchFailed
+	if message == "" {
+		reason, message = ReasonPending, fmt.Sprintf("starting to watch %s", r.Spec.Resource.Kind)
+	}
+	r.SetCondition(NewCondition(ConditionReady, false, reason, message))
+}
@@ -0,0 +1,47 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestK8sEventTriggerUpdateStatusWatch(t *testing.T) {
+	trigger := &K8sEventTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "deployments", Namespace: "kess-samples"},
+		Spec: K8sEventTriggerSpec{
+			TriggerTarget: TriggerTarget{Function: "sample"},
+			Resource:      K8sEventResource{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Events:        []K8sEventType{DeletedK8sEventType},
+		},
+	}
+	trigger.Default()
+	if err := trigger.validate(); err != nil {
+		t.Fatalf("trigger must be valid, got %v", err)
+	}
+	if trigger.Delivers(AddedK8sEventType) || !trigger.Delivers(DeletedK8sEventType) {
+		t.Errorf("only deleted events must be delivered")
+	}
+
+	trigger.UpdateStatusWatch(false, "no matches for kind", time.Time{}, nil)
+	if c := FindCondition(trigger.Status.Conditions, ConditionReady); c == nil || c.Reason != ReasonWatchFailed || c.Message != "no matches for kind" {
+		t.Fatalf("failed watch must be reported, got %+v", c)
+	}
+	now := time.Now()
+	trigger.UpdateStatusWatch(true, "", now, []TriggerInvocation{{StatusCode: 200}})
+	if !IsConditionTrue(trigger.Status.Conditions, ConditionReady) || len(trigger.Status.History) != 1 || !trigger.Status.LastEventTime.Time.Equal(now) {
+		t.Errorf("watch must be ready with its invocation recorded, got %+v", trigger.Status)
+	}
+
+	trigger.Spec.Resource = K8sEventResource{Version: "v1", Kind: "Secret"}
+	if err := trigger.validate(); err == nil {
+		t.Error("secrets must not be watched")
+	}
+
+	trigger.Spec.Resource.Kind = ""
+	trigger.Spec.Selector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Near"}}}
+	if err := trigger.validate(); err == nil {
+		t.Error("kind and selector must be invalid")
+	}
+}
@@ -0,0 +1,122 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// K8sEventType bulabula
+// +kubebuilder:validation:Enum=Added;Updated;Deleted
+type K8sEventType string
+
+// K8sEventType Constants bulabula
+const (
+	AddedK8sEventType   K8sEventType = "Added"
+	UpdatedK8sEventType K8sEventType = "Updated"
+	DeletedK8sEventType K8sEventType = "Deleted"
+)
+
+// K8sEventResource is the kind of resources watched
+type K8sEventResource struct {
+	// Optional API group of resources, the core group when empty
+	// +kubebuilder:validation:Optional
+	Group string `json:"group,omitempty"`
+
+	// The API version of resources
+	// +kubebuilder:validation:Required
+	Version string `json:"version"`
+
+	// The kind of resources, one of K8sEventKinds, secrets in particular are never watched
+	// +kubebuilder:validation:Required
+	Kind string `json:"kind"`
+}
+
+// K8sEventTriggerSpec defines the desired state of K8sEventTrigger
+type K8sEventTriggerSpec struct {
+	TriggerTarget `json:",inline"`
+
+	// The kind of resources watched in the namespace of trigger
+	// +kubebuilder:validation:Required
+	Resource K8sEventResource `json:"resource"`
+
+	// Optional label selector of resources, every resource of kind when empty
+	// +kubebuilder:validation:Optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Optional types of events delivered, every type when empty
+	// +kubebuilder:validation:Optional
+	Events []K8sEventType `json:"events,omitempty"`
+
+	// Optional number of invocations kept in status
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=10
+	HistoryLimit *int32 `json:"historyLimit,omitempty"`
+}
+
+// K8sEventTriggerStatus defines the observed state of K8sEventTrigger
+type K8sEventTriggerStatus struct {
+	// Optional last time an event was delivered
+	// +kubebuilder:validation:Optional
+	LastEventTime *metav1.Time `json:"lastEventTime,omitempty"`
+
+	// Optional last invocations of function, newest first
+	// +kubebuilder:validation:Optional
+	History []TriggerInvocation `json:"history,omitempty"`
+
+	// The generation observed by the k8s event trigger controller
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Optional conditions of k8s event trigger
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:resource:categories="kess",shortName="k8sevent"
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Function",type=string,JSONPath=`.spec.function`,priority=0
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.version`,priority=0
+// +kubebuilder:printcolumn:name="Kind",type=string,JSONPath=`.spec.resource.kind`,priority=0
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`,priority=0
+// +kubebuilder:printcolumn:name="Last Event",type=date,JSONPath=`.status.lastEventTime`,priority=0
+// +kubebuilder:object:root=true
+
+// K8sEventTrigger is the Schema for the k8seventtriggers API
+type K8sEventTrigger struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   K8sEventTriggerSpec   `json:"spec,omitempty"`
+	Status K8sEventTriggerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// K8sEventTriggerList contains a list of K8sEventTrigger
+type K8sEventTriggerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []K8sEventTrigger `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&K8sEventTrigger{}, &K8sEventTriggerList{})
+}
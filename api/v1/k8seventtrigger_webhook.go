@@ -0,0 +1,90 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var k8seventtriggerlog = logf.Log.WithName("k8seventtrigger-resource")
+
+// SetupWebhookWithManager bulabula
+func (r *K8sEventTrigger) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-core-kess-io-v1-k8seventtrigger,mutating=true,failurePolicy=fail,groups=core.kess.io,resources=k8seventtriggers,verbs=create;update,versions=v1,name=mk8seventtrigger.kb.io
+
+var _ webhook.Defaulter = &K8sEventTrigger{}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-core-kess-io-v1-k8seventtrigger,mutating=false,failurePolicy=fail,groups=core.kess.io,resources=k8seventtriggers,versions=v1,name=vk8seventtrigger.kb.io
+
+var _ webhook.Validator = &K8sEventTrigger{}
+
+// ValidateCreate bulabula
+func (r *K8sEventTrigger) ValidateCreate() error {
+	k8seventtriggerlog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate bulabula
+func (r *K8sEventTrigger) ValidateUpdate(old runtime.Object) error {
+	k8seventtriggerlog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateDelete bulabula
+func (r *K8sEventTrigger) ValidateDelete() error {
+	return nil
+}
+
+func (r *K8sEventTrigger) validate() error {
+	var (
+		allErrs  field.ErrorList
+		specPath = field.NewPath("spec")
+	)
+
+	allErrs = append(allErrs, validateTriggerTarget(specPath, &r.Spec.TriggerTarget)...)
+	resourcePath := specPath.Child("resource")
+	if r.Spec.Resource.Version == "" {
+		allErrs = append(allErrs, field.Required(resourcePath.Child("version"), "version of resources must be set"))
+	}
+	if r.Spec.Resource.Kind == "" {
+		allErrs = append(allErrs, field.Required(resourcePath.Child("kind"), "kind of resources must be set"))
+	} else if !r.Watchable() {
+		allErrs = append(allErrs, field.NotSupported(resourcePath.Child("kind"), r.GroupVersionKind().GroupKind().String(), watchableKinds()))
+	}
+	if r.Spec.Selector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(r.Spec.Selector); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("selector"), r.Spec.Selector, err.Error()))
+		}
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("K8sEventTrigger").GroupKind(), r.Name, allErrs)
+}
@@ -1,25 +1,19 @@
 package v1
 
 import (
+	"fmt"
+	"strings"
+
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 // Default bulabula
 func (r *Library) Default() {
-	var (
-		labels       = r.Labels()
-		namedVersion = r.NamedVersion()
-	)
-
-	if r.ObjectMeta.Labels == nil {
-		r.ObjectMeta.Labels = make(map[string]string)
-	}
-	for k, v := range labels {
-		r.ObjectMeta.Labels[k] = v
-	}
+	namedVersion := r.NamedVersion()
 
 	if r.Spec.Library == "" {
 		r.Spec.Library = namedVersion.Name
@@ -27,6 +21,13 @@ func (r *Library) Default() {
 	if r.Spec.Version == "" {
 		r.Spec.Version = namedVersion.Version
 	}
+
+	if r.ObjectMeta.Labels == nil {
+		r.ObjectMeta.Labels = make(map[string]string)
+	}
+	for k, v := range r.Labels() {
+		r.ObjectMeta.Labels[k] = v
+	}
 }
 
 // DefaultStatus bulabula
@@ -44,10 +45,10 @@ func (r *Library) NamedVersion() NamedVersion {
 // Labels bulabula
 func (r *Library) Labels() map[string]string {
 	return map[string]string{
-		"kess-type":    TypeLibrary,
-		"kess-library": r.Spec.Library,
-		"kess-version": r.Spec.Version,
-		"kess-runtime": r.Spec.Runtime,
+		LabelType:    TypeLibrary,
+		LabelLibrary: r.Spec.Library,
+		LabelVersion: r.Spec.Version,
+		LabelRuntime: r.Spec.Runtime,
 	}
 }
 
@@ -62,10 +63,14 @@ func (r *Library) RuntimeNamespacedName() types.NamespacedName {
 // RuntimeConfigMap bulabula
 func (r *Library) RuntimeConfigMap() RuntimeConfigMap {
 	namedVersion := r.NamedVersion()
-	return RuntimeConfigMap{
+	runtimeConfigMap := RuntimeConfigMap{
 		Name:  namedVersion.Format(r.Spec.ConfigMap.Name),
 		Mount: namedVersion.Format(r.Spec.ConfigMap.Mount),
 	}
+	if secret := r.RuntimeSecret(); secret != nil {
+		runtimeConfigMap.Secrets = []RuntimeSecret{*secret}
+	}
+	return runtimeConfigMap
 }
 
 // ConfigMap bulabula
@@ -86,6 +91,7 @@ func (r *Library) ConfigMap() apiv1.ConfigMap {
 		BinaryData: r.Spec.BinaryData,
 		// Immutable:  pointer.Bool(true),
 	}
+	setContentHash(&configmap)
 
 	return configmap
 }
@@ -102,6 +108,7 @@ func (r *Library) ConfigMapNamespacedName() types.NamespacedName {
 func (r *Library) SetConfigMap(out *apiv1.ConfigMap) {
 	out.Data = r.Spec.Data
 	out.BinaryData = r.Spec.BinaryData
+	setContentHash(out)
 }
 
 // UnsetConfigMap bulabula
@@ -110,9 +117,164 @@ func (r *Library) UnsetConfigMap(out *apiv1.ConfigMap) {
 	out.BinaryData = nil
 }
 
+// ArchiveSource returns the archive of library, nil when it has none
+func (r *Library) ArchiveSource() *ArchiveSource {
+	if r.Spec.Source == nil {
+		return nil
+	}
+	return r.Spec.Source.Archive
+}
+
+// ArchiveURL returns the url of archive with the name and version of library
+func (r *Library) ArchiveURL() string {
+	archive := r.ArchiveSource()
+	if archive == nil {
+		return ""
+	}
+	return r.NamedVersion().Format(archive.URL)
+}
+
+// contentReference returns the config map or secret reference of library, nil when it has none
+func (r *Library) contentReference() (string, *ContentReference) {
+	switch {
+	case r.Spec.Source == nil:
+		return "", nil
+	case r.Spec.Source.ConfigMapRef != nil:
+		return ReferenceKindConfigMap, r.Spec.Source.ConfigMapRef
+	case r.Spec.Source.SecretRef != nil:
+		return ReferenceKindSecret, r.Spec.Source.SecretRef
+	}
+	return "", nil
+}
+
+// Reference returns the config map or secret library reads its files from, nil when it has none
+func (r *Library) Reference() *ReferenceStatus {
+	kind, ref := r.contentReference()
+	if ref == nil {
+		return nil
+	}
+	return &ReferenceStatus{Kind: kind, Name: ref.Name}
+}
+
+// ReferenceNamespacedName bulabula
+func (r *Library) ReferenceNamespacedName() types.NamespacedName {
+	var name string
+	if _, ref := r.contentReference(); ref != nil {
+		name = ref.Name
+	}
+	return types.NamespacedName{
+		Name:      name,
+		Namespace: r.Namespace,
+	}
+}
+
+// ReferenceFiles returns the files of library among the content of the referenced config map,
+// every key when no item is selected
+func (r *Library) ReferenceFiles(cm *apiv1.ConfigMap) (map[string][]byte, error) {
+	kind, ref := r.contentReference()
+	if kind != ReferenceKindConfigMap {
+		return nil, fmt.Errorf("library has no config map reference")
+	}
+	data := configMapData(cm)
+	if len(ref.Items) == 0 {
+		return data, nil
+	}
+
+	files := make(map[string][]byte, len(ref.Items))
+	for _, item := range ref.Items {
+		content, ok := data[item.Key]
+		if !ok {
+			return nil, fmt.Errorf("key %q is not found in %s %q", item.Key, kind, ref.Name)
+		}
+		name := item.Path
+		if name == "" {
+			name = item.Key
+		}
+		files[name] = content
+	}
+	return files, nil
+}
+
+// CheckReferenceSecret fails when the referenced secret misses a selected key, the content of secret is not read,
+// runtime pods mount it from the secret
+func (r *Library) CheckReferenceSecret(secret *apiv1.Secret) error {
+	_, ref := r.contentReference()
+	if ref == nil {
+		return fmt.Errorf("library has no reference")
+	}
+	for _, item := range ref.Items {
+		if _, ok := secret.Data[item.Key]; !ok {
+			return fmt.Errorf("key %q is not found in %s %q", item.Key, ReferenceKindSecret, secret.Name)
+		}
+	}
+	return nil
+}
+
+// RuntimeSecret returns the secret projected into the mount of library, nil unless library reads its files from a secret
+func (r *Library) RuntimeSecret() *RuntimeSecret {
+	kind, ref := r.contentReference()
+	if kind != ReferenceKindSecret {
+		return nil
+	}
+	secret := &RuntimeSecret{Name: ref.Name}
+	for _, item := range ref.Items {
+		name := item.Path
+		if name == "" {
+			name = item.Key
+		}
+		secret.Items = append(secret.Items, apiv1.KeyToPath{Key: item.Key, Path: name})
+	}
+	if read := r.Status.Reference; read != nil && read.Kind == ReferenceKindSecret && read.Name == ref.Name {
+		secret.ResourceVersion = read.ResourceVersion
+	}
+	return secret
+}
+
+// ReferenceChanged tells whether a config map or secret of kind is referenced by library and changed since library was read
+func (r *Library) ReferenceChanged(kind, name, resourceVersion string) bool {
+	return referenceChanged(r.Reference(), r.Status.Reference, kind, name, resourceVersion)
+}
+
+// UpdateStatusReference records the resource version of the config map or secret library was read from
+func (r *Library) UpdateStatusReference(resourceVersion string) {
+	r.Status.Reference = r.Reference()
+	if r.Status.Reference != nil {
+		r.Status.Reference.ResourceVersion = resourceVersion
+	}
+}
+
+// WithSourceFiles returns a copy of library with files as its data, the utf-8 ones as data and the others
+// as binary data, every file must be a valid config map key
+func (r *Library) WithSourceFiles(files map[string][]byte) (*Library, error) {
+	for name := range files {
+		if msgs := validation.IsConfigMapKey(name); len(msgs) > 0 {
+			return nil, fmt.Errorf("file %q is not a valid config map key: %s", name, strings.Join(msgs, ", "))
+		}
+	}
+	lib := r.DeepCopy()
+	lib.Spec.Data, lib.Spec.BinaryData = splitData(files)
+	return lib, nil
+}
+
+// SetCondition bulabula
+func (r *Library) SetCondition(condition Condition) {
+	condition.ObservedGeneration = r.Generation
+	SetCondition(&r.Status.Conditions, condition)
+}
+
 // UpdateStatusReady bulabula
 func (r *Library) UpdateStatusReady(rt *Runtime) {
+	runtimeConfigMap := r.RuntimeConfigMap()
+	if _, ok := rt.Status.Libraries[runtimeConfigMap.Name]; ok {
+		r.SetCondition(NewCondition(ConditionMounted, true, ReasonReconciled, ""))
+	} else {
+		r.SetCondition(NewCondition(ConditionMounted, false, ReasonNotMounted,
+			fmt.Sprintf("config map %q is not mounted by runtime %q", runtimeConfigMap.Name, r.Spec.Runtime)))
+	}
+	r.SetCondition(dependentReadyCondition(r.Status.Conditions, rt))
+
 	r.Status.Ready = rt.Status.Ready
+	r.Status.ObservedGeneration = r.Generation
 }
 
 // AddFinalizer bulabula
@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestLibraryArchiveSource(t *testing.T) {
+	lib := &Library{
+		ObjectMeta: metav1.ObjectMeta{Name: "utils-v2", Namespace: "kess-samples"},
+		Spec: LibrarySpec{
+			Runtime:   "python",
+			ConfigMap: LibraryConfigMap{Name: "lib-{Name}-{Version}"},
+			Source: &LibrarySource{Archive: &ArchiveSource{
+				URL:    "https://artifacts.kess.io/libraries/{Name}-{Version}.tar.gz",
+				SHA256: "3A6EB0790F39AC87C94F3856B2DD2C5D110E6811602261A9A923D3BB23ADC8B7",
+				Path:   "utils",
+			}},
+		},
+	}
+	lib.Default()
+	if url := lib.ArchiveURL(); url != "https://artifacts.kess.io/libraries/utils-v2.tar.gz" {
+		t.Errorf("unexpected archive url %s", url)
+	}
+	if errs := lib.validateSource(field.NewPath("spec", "source")); len(errs) != 0 {
+		t.Fatalf("source must be valid, got %v", errs)
+	}
+
+	src, err := lib.WithSourceFiles(map[string][]byte{"__init__.py": []byte("VERSION = 2\n"), "data.bin": {0xff, 0x00}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := src.ConfigMap()
+	if cm.Name != "lib-utils-v2" || cm.Data["__init__.py"] != "VERSION = 2\n" || len(cm.BinaryData["data.bin"]) != 2 {
+		t.Errorf("unexpected config map %+v", cm)
+	}
+	if lib.Spec.Data != nil {
+		t.Error("source files must not be written into library")
+	}
+	if _, err := lib.WithSourceFiles(map[string][]byte{"helpers/strings.py": nil}); err == nil {
+		t.Error("nested files must not be config map keys")
+	}
+
+	lib.Spec.Data = map[string]string{"__init__.py": ""}
+	lib.Spec.Source.Archive.Path = "../utils"
+	if errs := lib.validateSource(field.NewPath("spec", "source")); len(errs) != 2 {
+		t.Errorf("data and path must be invalid, got %v", errs)
+	}
+}
+
+func TestLibraryReference(t *testing.T) {
+	lib := &Library{
+		ObjectMeta: metav1.ObjectMeta{Name: "utils-v2", Namespace: "kess-samples"},
+		Spec: LibrarySpec{
+			Runtime: "python",
+			Source:  &LibrarySource{SecretRef: &ContentReference{Name: "utils-code"}},
+		},
+	}
+	if errs := lib.validateSource(field.NewPath("spec", "source")); len(errs) != 0 {
+		t.Fatalf("source must be valid, got %v", errs)
+	}
+
+	secret := &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "utils-code", Namespace: "kess-samples", ResourceVersion: "3"},
+		Data:       map[string][]byte{"init.py": []byte("VERSION = 2\n"), "token": []byte("secret")},
+	}
+	if err := lib.CheckReferenceSecret(secret); err != nil {
+		t.Errorf("every key of secret must be mounted without items, got %v", err)
+	}
+	lib.Spec.Source.SecretRef.Items = []ContentKey{{Key: "init.py", Path: "__init__.py"}}
+	if err := lib.CheckReferenceSecret(secret); err != nil {
+		t.Errorf("selected key must be found, got %v", err)
+	}
+	lib.Spec.Source.SecretRef.Items = append(lib.Spec.Source.SecretRef.Items, ContentKey{Key: "missing.py"})
+	if err := lib.CheckReferenceSecret(secret); err == nil {
+		t.Error("missing selected key must fail")
+	}
+	lib.Spec.Source.SecretRef.Items = lib.Spec.Source.SecretRef.Items[:1]
+
+	lib.UpdateStatusReference(secret.ResourceVersion)
+	if lib.ReferenceChanged(ReferenceKindSecret, "utils-code", "3") || !lib.ReferenceChanged(ReferenceKindSecret, "utils-code", "4") {
+		t.Errorf("library must be synced only once its reference changed, got %+v", lib.Status.Reference)
+	}
+	secrets := lib.RuntimeConfigMap().Secrets
+	if len(secrets) != 1 || secrets[0].ResourceVersion != "3" || len(secrets[0].Items) != 1 || secrets[0].Items[0].Path != "__init__.py" {
+		t.Errorf("selected keys of secret must be projected at their path, got %+v", secrets)
+	}
+	cm := lib.ConfigMap()
+	if len(cm.Data) != 0 || len(cm.BinaryData) != 0 {
+		t.Errorf("secret content must not be copied into the config map of library, got %v %v", cm.Data, cm.BinaryData)
+	}
+
+	lib.Spec.Source.ConfigMapRef = &ContentReference{Items: []ContentKey{{Key: "a/b"}}}
+	if errs := lib.validateSource(field.NewPath("spec", "source")); len(errs) != 3 {
+		t.Errorf("sources, config map name and key must be invalid, got %v", errs)
+	}
+}
@@ -36,6 +36,23 @@ type LibraryConfigMap struct {
 	Mount string `json:"mount,omitempty"`
 }
 
+// LibrarySource is where the files of library are fetched from instead of data, either an archive,
+// or a config map or secret
+type LibrarySource struct {
+	// Optional tar.gz archive of library, every file of path is a key of its config map
+	// +kubebuilder:validation:Optional
+	Archive *ArchiveSource `json:"archive,omitempty"`
+
+	// Optional existing config map in the namespace of library
+	// +kubebuilder:validation:Optional
+	ConfigMapRef *ContentReference `json:"configMapRef,omitempty"`
+
+	// Optional existing secret in the namespace of library, which runtime pods mount from the secret,
+	// the creator of library must be allowed to get the secret
+	// +kubebuilder:validation:Optional
+	SecretRef *ContentReference `json:"secretRef,omitempty"`
+}
+
 // LibrarySpec defines the desired state of Library
 type LibrarySpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
@@ -64,6 +81,10 @@ type LibrarySpec struct {
 	// The binary of lib
 	// +kubebuilder:validation:Optional
 	BinaryData map[string][]byte `json:"binaryData,omitempty"`
+
+	// Optional source of lib, exclusive with data and binaryData
+	// +kubebuilder:validation:Optional
+	Source *LibrarySource `json:"source,omitempty"`
 }
 
 // LibraryStatus defines the observed state of Library
@@ -74,6 +95,20 @@ type LibraryStatus struct {
 	// Optional ready string of runtime for show
 	// +kubebuilder:validation:Optional
 	Ready string `json:"ready,omitempty"`
+
+	// The generation observed by the library controller
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Optional config map or secret library was last read from
+	// +kubebuilder:validation:Optional
+	Reference *ReferenceStatus `json:"reference,omitempty"`
+
+	// Optional conditions of library
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:resource:categories="kess",shortName="lib",singular="library"
@@ -0,0 +1,166 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var librarylog = logf.Log.WithName("library-resource")
+
+// SetupWebhookWithManager bulabula
+func (r *Library) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-core-kess-io-v1-library,mutating=true,failurePolicy=fail,groups=core.kess.io,resources=libraries,verbs=create;update,versions=v1,name=mlibrary.kb.io
+
+var _ webhook.Defaulter = &Library{}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-core-kess-io-v1-library,mutating=false,failurePolicy=fail,groups=core.kess.io,resources=libraries,versions=v1,name=vlibrary.kb.io
+
+var _ webhook.Validator = &Library{}
+
+// ValidateCreate bulabula
+func (r *Library) ValidateCreate() error {
+	librarylog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate bulabula
+func (r *Library) ValidateUpdate(old runtime.Object) error {
+	librarylog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateDelete bulabula
+func (r *Library) ValidateDelete() error {
+	return nil
+}
+
+func (r *Library) validate() error {
+	var (
+		allErrs          field.ErrorList
+		specPath         = field.NewPath("spec")
+		runtimeConfigMap = r.RuntimeConfigMap()
+	)
+
+	if r.Spec.Runtime == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("runtime"), "library must reference a runtime"))
+	}
+	allErrs = append(allErrs, validateConfigMapName(specPath.Child("configMap", "name"), r.Spec.ConfigMap.Name, runtimeConfigMap.Name)...)
+	allErrs = append(allErrs, validateConfigMapMount(specPath.Child("configMap", "mount"), r.Spec.ConfigMap.Mount, runtimeConfigMap.Mount)...)
+	for key := range r.Spec.Data {
+		allErrs = append(allErrs, validateConfigMapKey(specPath.Child("data").Key(key), key, key)...)
+		if _, ok := r.Spec.BinaryData[key]; ok {
+			allErrs = append(allErrs, field.Duplicate(specPath.Child("binaryData").Key(key), key))
+		}
+	}
+	for key := range r.Spec.BinaryData {
+		allErrs = append(allErrs, validateConfigMapKey(specPath.Child("binaryData").Key(key), key, key)...)
+	}
+	allErrs = append(allErrs, r.validateSource(specPath.Child("source"))...)
+
+	if len(allErrs) == 0 {
+		errs, err := r.validateConfigMapCollision(specPath.Child("configMap", "name"))
+		if err != nil {
+			return err
+		}
+		allErrs = append(allErrs, errs...)
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Library").GroupKind(), r.Name, allErrs)
+}
+
+// validateSource bulabula
+func (r *Library) validateSource(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	source := r.Spec.Source
+	if source == nil {
+		return allErrs
+	}
+	sources := 0
+	for _, set := range []bool{source.Archive != nil, source.ConfigMapRef != nil, source.SecretRef != nil} {
+		if set {
+			sources++
+		}
+	}
+	switch {
+	case sources == 0:
+		return append(allErrs, field.Required(fldPath, "source must set one of archive, configMapRef or secretRef"))
+	case sources > 1:
+		allErrs = append(allErrs, field.Forbidden(fldPath, "source must set only one of archive, configMapRef or secretRef"))
+	}
+	if len(r.Spec.Data) > 0 || len(r.Spec.BinaryData) > 0 {
+		allErrs = append(allErrs, field.Forbidden(fldPath, "library must not set data or binaryData along with a source"))
+	}
+
+	if archive := source.Archive; archive != nil {
+		allErrs = append(allErrs, validateArchiveSource(fldPath.Child("archive"), archive, r.ArchiveURL())...)
+	}
+	if ref := source.ConfigMapRef; ref != nil {
+		allErrs = append(allErrs, validateContentReference(fldPath.Child("configMapRef"), ref)...)
+	}
+	if ref := source.SecretRef; ref != nil {
+		allErrs = append(allErrs, validateContentReference(fldPath.Child("secretRef"), ref)...)
+	}
+	return allErrs
+}
+
+// validateConfigMapCollision rejects libraries rendering the same config map as another library,
+// a library owns its whole config map so two of them would overwrite each other.
+func (r *Library) validateConfigMapCollision(fldPath *field.Path) (field.ErrorList, error) {
+	var (
+		allErrs field.ErrorList
+		libs    LibraryList
+		cmName  = r.RuntimeConfigMap().Name
+	)
+
+	if webhookClient == nil {
+		return allErrs, nil
+	}
+	if err := webhookClient.List(context.Background(), &libs, client.InNamespace(r.Namespace)); err != nil {
+		return allErrs, err
+	}
+
+	for _, lib := range libs.Items {
+		if lib.Name == r.Name {
+			continue
+		}
+		if lib.RuntimeConfigMap().Name == cmName {
+			allErrs = append(allErrs, field.Invalid(fldPath, r.Spec.ConfigMap.Name,
+				fmt.Sprintf("config map %q collides with library %q", cmName, lib.Name)))
+		}
+	}
+	return allErrs, nil
+}
@@ -0,0 +1,113 @@
+package v1
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// ManifestKey is the key of the manifest in a function config map, which describes every function version in it
+const ManifestKey = "kess.json"
+
+// contentHashKeyPrefix prefixes the name of a config map in the key of its content hash, so the keys of config maps
+// projected into the same directory never collide
+const contentHashKeyPrefix = "kess-hash."
+
+// keyPathSeparator stands for a slash in a config map key, which can not hold slashes
+const keyPathSeparator = ".."
+
+// FunctionManifest describes a function version in the manifest, so the runtime knows what to call
+type FunctionManifest struct {
+	Function string `json:"function"`
+	Version  string `json:"version"`
+	// The file of function, or its directory when it has many files, relative to the mount
+	Path string `json:"path"`
+	// The entrypoint of function, if any
+	Handler string `json:"handler,omitempty"`
+	// The files of function relative to its directory when it has many files
+	Files []string `json:"files,omitempty"`
+	// The config map key of every file, which config map volumes mount as it is instead of at the file path
+	Keys map[string]string `json:"keys,omitempty"`
+}
+
+// Manifest returns the function versions described by the manifest of config map by their file key
+func Manifest(cm *apiv1.ConfigMap) map[string]FunctionManifest {
+	manifest := make(map[string]FunctionManifest)
+	if data, ok := cm.Data[ManifestKey]; ok {
+		json.Unmarshal([]byte(data), &manifest)
+	}
+	return manifest
+}
+
+func setManifest(cm *apiv1.ConfigMap, manifest map[string]FunctionManifest) {
+	if len(manifest) == 0 {
+		delete(cm.Data, ManifestKey)
+		return
+	}
+	data, _ := json.Marshal(manifest)
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[ManifestKey] = string(data)
+}
+
+// KeyPath returns the path a config map key is laid out at relative to the mount, every ".." of key
+// separates a directory from what is under it, config map volumes mount such keys as they are so that
+// new keys never change the pod template, the runtime finds the files of a function through its manifest
+func KeyPath(key string) string {
+	return strings.Replace(key, keyPathSeparator, "/", -1)
+}
+
+// fileKey returns the config map key of a file under the directory dir
+func fileKey(dir, file string) string {
+	return dir + keyPathSeparator + strings.Replace(file, "/", keyPathSeparator, -1)
+}
+
+// ContentHashKey returns the key of the content hash in the function or library config map name, runtime pods
+// signaled by the Signal update strategy read it from their mount to confirm they see the content of config map
+func ContentHashKey(name string) string {
+	return contentHashKeyPrefix + name
+}
+
+// ConfigMapContentHash returns the hash of every key of cm but its content hash
+func ConfigMapContentHash(cm *apiv1.ConfigMap) string {
+	skip := ContentHashKey(cm.Name)
+	hash := sha256.New()
+	for _, key := range sortedKeys(cm.Data) {
+		if key != skip {
+			fmt.Fprintf(hash, "%s=%x\n", key, sha256.Sum256([]byte(cm.Data[key])))
+		}
+	}
+	binaryKeys := make([]string, 0, len(cm.BinaryData))
+	for key := range cm.BinaryData {
+		binaryKeys = append(binaryKeys, key)
+	}
+	sort.Strings(binaryKeys)
+	for _, key := range binaryKeys {
+		fmt.Fprintf(hash, "%s=%x\n", key, sha256.Sum256(cm.BinaryData[key]))
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// setContentHash stamps the content hash into cm, or drops it along with the last other key, the data of cm is
+// copied first as it may be shared with the spec of a library
+func setContentHash(cm *apiv1.ConfigMap) {
+	key := ContentHashKey(cm.Name)
+	data := make(map[string]string, len(cm.Data)+1)
+	for k, v := range cm.Data {
+		if k != key {
+			data[k] = v
+		}
+	}
+	if len(data) == 0 && len(cm.BinaryData) == 0 {
+		cm.Data = nil
+		return
+	}
+	data[key] = ConfigMapContentHash(cm)
+	cm.Data = data
+}
@@ -0,0 +1,109 @@
+package v1
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xorcare/pointer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Default bulabula
+func (r *MQTTTrigger) Default() {
+	if r.ObjectMeta.Labels == nil {
+		r.ObjectMeta.Labels = make(map[string]string)
+	}
+	for k, v := range r.Labels() {
+		r.ObjectMeta.Labels[k] = v
+	}
+
+	r.Spec.TriggerTarget.Default()
+	if r.Spec.HistoryLimit == nil {
+		r.Spec.HistoryLimit = pointer.Int32(DefaultTriggerHistoryLimit)
+	}
+	if secret := r.Spec.CredentialsSecret; secret != nil {
+		if secret.UsernameKey == "" {
+			secret.UsernameKey = DefaultMQTTUsernameKey
+		}
+		if secret.PasswordKey == "" {
+			secret.PasswordKey = DefaultMQTTPasswordKey
+		}
+	}
+}
+
+// DefaultStatus bulabula
+func (r *MQTTTrigger) DefaultStatus() {}
+
+// Labels bulabula
+func (r *MQTTTrigger) Labels() map[string]string {
+	return map[string]string{
+		LabelType:     TypeMQTTTrigger,
+		LabelFunction: r.Spec.Function,
+	}
+}
+
+// NamespacedName bulabula
+func (r *MQTTTrigger) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      r.Name,
+		Namespace: r.Namespace,
+	}
+}
+
+// SecretNamespacedName returns the secret holding the credentials of broker, nil when there is none
+func (r *MQTTTrigger) SecretNamespacedName() *types.NamespacedName {
+	if r.Spec.CredentialsSecret == nil {
+		return nil
+	}
+	return &types.NamespacedName{
+		Name:      r.Spec.CredentialsSecret.Name,
+		Namespace: r.Namespace,
+	}
+}
+
+// ClientID returns the client identifier of trigger
+func (r *MQTTTrigger) ClientID() string {
+	if r.Spec.ClientID != "" {
+		return r.Spec.ClientID
+	}
+	return fmt.Sprintf("kess-%s-%s", r.Namespace, r.Name)
+}
+
+// SetCondition bulabula
+func (r *MQTTTrigger) SetCondition(condition Condition) {
+	condition.ObservedGeneration = r.Generation
+	SetCondition(&r.Status.Conditions, condition)
+}
+
+// UpdateStatusSubscription records the connection of trigger and the invocations since the last update, oldest
+// first, message is why trigger is not connected
+func (r *MQTTTrigger) UpdateStatusSubscription(connected bool, message string, lastMessage time.Time, invocations []TriggerInvocation) {
+	for _, invocation := range invocations {
+		r.Status.History = prependInvocation(r.Status.History, invocation, *r.Spec.HistoryLimit)
+	}
+	if !lastMessage.IsZero() {
+		r.Status.LastMessageTime = &metav1.Time{Time: lastMessage}
+	}
+	r.Status.ObservedGeneration = r.Generation
+
+	if connected {
+		message = fmt.Sprintf("subscribed to %s", r.Spec.Topic)
+		r.SetCondition(NewCondition(ConditionConnected, true, ReasonSubscribed, message))
+		r.SetCondition(NewCondition(ConditionReady, true, ReasonSubscribed, message))
+		return
+	}
+	reason := ReasonConnectionFailed
+	if message == "" {
+		reason, message = ReasonPending, fmt.Sprintf("connecting to %s", r.Spec.Broker)
+	}
+	r.SetCondition(NewCondition(ConditionConnected, false, reason, message))
+	r.SetCondition(NewCondition(ConditionReady, false, reason, message))
+}
+
+// UpdateStatusUnsubscribed reports why trigger is not subscribed
+func (r *MQTTTrigger) UpdateStatusUnsubscribed(reason, message string) {
+	r.Status.ObservedGeneration = r.Generation
+	r.SetCondition(NewCondition(ConditionConnected, false, reason, message))
+	r.SetCondition(NewCondition(ConditionReady, false, reason, message))
+}
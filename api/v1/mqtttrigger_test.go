@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xorcare/pointer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMQTTTriggerUpdateStatusSubscription(t *testing.T) {
+	trigger := &MQTTTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "sensors", Namespace: "kess-samples"},
+		Spec: MQTTTriggerSpec{
+			TriggerTarget:     TriggerTarget{Function: "sample"},
+			Broker:            "tcp://mosquitto:1883",
+			Topic:             "sensors/#",
+			CredentialsSecret: &MQTTCredentials{Name: "mosquitto"},
+			HistoryLimit:      pointer.Int32(2),
+		},
+	}
+	trigger.Default()
+	if trigger.ClientID() != "kess-kess-samples-sensors" || trigger.Spec.CredentialsSecret.PasswordKey != DefaultMQTTPasswordKey {
+		t.Fatalf("unexpected defaults %+v", trigger.Spec)
+	}
+	if err := trigger.validate(); err != nil {
+		t.Fatalf("trigger must be valid, got %v", err)
+	}
+
+	trigger.UpdateStatusSubscription(false, "", time.Time{}, nil)
+	if c := FindCondition(trigger.Status.Conditions, ConditionConnected); c == nil || c.Reason != ReasonPending {
+		t.Fatalf("trigger must be connecting, got %+v", c)
+	}
+
+	now := time.Now()
+	trigger.UpdateStatusSubscription(true, "", now, []TriggerInvocation{{StatusCode: 200}, {StatusCode: 201}, {StatusCode: 202}})
+	if !IsConditionTrue(trigger.Status.Conditions, ConditionConnected) || !trigger.Status.LastMessageTime.Time.Equal(now) {
+		t.Fatalf("trigger must be connected, got %+v", trigger.Status)
+	}
+	if history := trigger.Status.History; len(history) != 2 || history[0].StatusCode != 202 {
+		t.Errorf("history must keep the newest invocations first, got %+v", history)
+	}
+
+	trigger.Spec.Broker, trigger.Spec.Topic = "http://mosquitto", "sensors/#/temperature"
+	if err := trigger.validate(); err == nil {
+		t.Error("broker and topic must be invalid")
+	}
+}
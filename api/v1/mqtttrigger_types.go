@@ -0,0 +1,124 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MQTTCredentials references the secret holding the credentials of a broker
+type MQTTCredentials struct {
+	// The name of the secret in the namespace of trigger
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Optional key of the user name in secret
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="username"
+	UsernameKey string `json:"usernameKey,omitempty"`
+
+	// Optional key of the password in secret
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="password"
+	PasswordKey string `json:"passwordKey,omitempty"`
+}
+
+// MQTTTriggerSpec defines the desired state of MQTTTrigger
+type MQTTTriggerSpec struct {
+	TriggerTarget `json:",inline"`
+
+	// The URL of broker, one of tcp://, mqtt://, ssl://, tls:// and mqtts://
+	// +kubebuilder:validation:Required
+	Broker string `json:"broker"`
+
+	// The topic filter subscribed, + matches a single level and # the remaining levels
+	// +kubebuilder:validation:Required
+	Topic string `json:"topic"`
+
+	// Optional QoS of subscription
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=2
+	QoS int32 `json:"qos,omitempty"`
+
+	// Optional client identifier, defaults to kess-{Namespace}-{Name}
+	// +kubebuilder:validation:Optional
+	ClientID string `json:"clientID,omitempty"`
+
+	// Optional secret holding the credentials of broker
+	// +kubebuilder:validation:Optional
+	CredentialsSecret *MQTTCredentials `json:"credentialsSecret,omitempty"`
+
+	// Optional number of invocations kept in status
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=10
+	HistoryLimit *int32 `json:"historyLimit,omitempty"`
+}
+
+// MQTTTriggerStatus defines the observed state of MQTTTrigger
+type MQTTTriggerStatus struct {
+	// Optional last time a message was received
+	// +kubebuilder:validation:Optional
+	LastMessageTime *metav1.Time `json:"lastMessageTime,omitempty"`
+
+	// Optional last invocations of function, newest first
+	// +kubebuilder:validation:Optional
+	History []TriggerInvocation `json:"history,omitempty"`
+
+	// The generation observed by the mqtt trigger controller
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Optional conditions of mqtt trigger
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:resource:categories="kess",shortName="mqtt"
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Function",type=string,JSONPath=`.spec.function`,priority=0
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.version`,priority=0
+// +kubebuilder:printcolumn:name="Broker",type=string,JSONPath=`.spec.broker`,priority=0
+// +kubebuilder:printcolumn:name="Topic",type=string,JSONPath=`.spec.topic`,priority=0
+// +kubebuilder:printcolumn:name="Connected",type=string,JSONPath=`.status.conditions[?(@.type=="Connected")].status`,priority=0
+// +kubebuilder:printcolumn:name="Last Message",type=date,JSONPath=`.status.lastMessageTime`,priority=0
+// +kubebuilder:object:root=true
+
+// MQTTTrigger is the Schema for the mqtttriggers API
+type MQTTTrigger struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MQTTTriggerSpec   `json:"spec,omitempty"`
+	Status MQTTTriggerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MQTTTriggerList contains a list of MQTTTrigger
+type MQTTTriggerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MQTTTrigger `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MQTTTrigger{}, &MQTTTriggerList{})
+}
@@ -0,0 +1,95 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"net/url"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/yamajik/kess/mqtt"
+)
+
+// log is for logging in this package.
+var mqtttriggerlog = logf.Log.WithName("mqtttrigger-resource")
+
+// validMQTTSchemes are the URL schemes of brokers supported
+var validMQTTSchemes = map[string]bool{"tcp": true, "mqtt": true, "ssl": true, "tls": true, "mqtts": true}
+
+// SetupWebhookWithManager bulabula
+func (r *MQTTTrigger) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-core-kess-io-v1-mqtttrigger,mutating=true,failurePolicy=fail,groups=core.kess.io,resources=mqtttriggers,verbs=create;update,versions=v1,name=mmqtttrigger.kb.io
+
+var _ webhook.Defaulter = &MQTTTrigger{}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-core-kess-io-v1-mqtttrigger,mutating=false,failurePolicy=fail,groups=core.kess.io,resources=mqtttriggers,versions=v1,name=vmqtttrigger.kb.io
+
+var _ webhook.Validator = &MQTTTrigger{}
+
+// ValidateCreate bulabula
+func (r *MQTTTrigger) ValidateCreate() error {
+	mqtttriggerlog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate bulabula
+func (r *MQTTTrigger) ValidateUpdate(old runtime.Object) error {
+	mqtttriggerlog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateDelete bulabula
+func (r *MQTTTrigger) ValidateDelete() error {
+	return nil
+}
+
+func (r *MQTTTrigger) validate() error {
+	var (
+		allErrs  field.ErrorList
+		specPath = field.NewPath("spec")
+	)
+
+	allErrs = append(allErrs, validateTriggerTarget(specPath, &r.Spec.TriggerTarget)...)
+	if u, err := url.Parse(r.Spec.Broker); err != nil || u.Host == "" || !validMQTTSchemes[u.Scheme] {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("broker"), r.Spec.Broker,
+			"must be a tcp://, mqtt://, ssl://, tls:// or mqtts:// URL"))
+	}
+	if err := mqtt.ValidateFilter(r.Spec.Topic); err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("topic"), r.Spec.Topic, err.Error()))
+	}
+	if r.Spec.QoS < 0 || r.Spec.QoS > 2 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("qos"), r.Spec.QoS, "must be 0, 1 or 2"))
+	}
+	if secret := r.Spec.CredentialsSecret; secret != nil && secret.Name == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("credentialsSecret", "name"), "secret must be named"))
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("MQTTTrigger").GroupKind(), r.Name, allErrs)
+}
@@ -2,6 +2,7 @@ package v1
 
 import (
 	"path"
+	"strconv"
 	"strings"
 
 	utilsstrings "github.com/yamajik/kess/utils/strings"
@@ -73,3 +74,24 @@ func (v NamedVersion) Map() map[string]interface{} {
 func (v NamedVersion) Format(s string) string {
 	return utilsstrings.Format(s, v.Map())
 }
+
+// CompareVersions compares dot separated versions segment by segment, numerically where both segments are numbers,
+// a leading v is ignored so that v10 is later than v9
+func CompareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), VersionSeparator)
+	bs := strings.Split(strings.TrimPrefix(b, "v"), VersionSeparator)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aerr := strconv.Atoi(as[i])
+		bn, berr := strconv.Atoi(bs[i])
+		switch {
+		case aerr == nil && berr == nil && an != bn:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case (aerr != nil || berr != nil) && as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	return len(as) - len(bs)
+}
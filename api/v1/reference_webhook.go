@@ -0,0 +1,130 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var referencelog = logf.Log.WithName("reference-resource")
+
+// secretReferencesPath is the path the secret references of functions and libraries are authorized at
+const secretReferencesPath = "/validate-core-kess-io-v1-secret-references"
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-core-kess-io-v1-secret-references,mutating=false,failurePolicy=fail,groups=core.kess.io,resources=functions;libraries,versions=v1,name=vsecretreferences.kb.io
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// setupSecretReferencesWebhookWithManager registers the webhook authorizing the secret references
+func setupSecretReferencesWebhookWithManager(mgr ctrl.Manager) error {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+	mgr.GetWebhookServer().Register(secretReferencesPath, &webhook.Admission{
+		Handler: &secretReferenceAuthorizer{client: mgr.GetClient(), decoder: decoder},
+	})
+	return nil
+}
+
+// referencer is a function or library which may read its content from a config map or secret
+type referencer interface {
+	runtime.Object
+	Reference() *ReferenceStatus
+}
+
+// secretReferenceAuthorizer denies functions and libraries referencing a secret their author is not allowed to get,
+// runtime pods mount the secret with the permissions of kess, which would otherwise expose any secret of namespace
+type secretReferenceAuthorizer struct {
+	client  client.Client
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &secretReferenceAuthorizer{}
+
+// Handle bulabula
+func (a *secretReferenceAuthorizer) Handle(ctx context.Context, req admission.Request) admission.Response {
+	obj, old := newReferencer(req.Kind.Kind), newReferencer(req.Kind.Kind)
+	if obj == nil {
+		return admission.Errored(http.StatusBadRequest, fmt.Errorf("unknown kind %q", req.Kind.Kind))
+	}
+	if err := a.decoder.DecodeRaw(req.Object, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	ref := obj.Reference()
+	if ref == nil || ref.Kind != ReferenceKindSecret {
+		return admission.Allowed("")
+	}
+	// The author of the reference was authorized when it was set, others may still update the object
+	if len(req.OldObject.Raw) > 0 {
+		if err := a.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if oldRef := old.Reference(); oldRef != nil && *oldRef == *ref {
+			return admission.Allowed("")
+		}
+	}
+
+	referencelog.Info("authorize secret reference", "kind", req.Kind.Kind, "name", req.Name, "secret", ref.Name)
+	extra := make(map[string]authorizationv1.ExtraValue, len(req.UserInfo.Extra))
+	for key, value := range req.UserInfo.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: req.Namespace,
+				Verb:      "get",
+				Resource:  "secrets",
+				Name:      ref.Name,
+			},
+			User:   req.UserInfo.Username,
+			UID:    req.UserInfo.UID,
+			Groups: req.UserInfo.Groups,
+			Extra:  extra,
+		},
+	}
+	if err := a.client.Create(ctx, review); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if !review.Status.Allowed {
+		return admission.Denied(fmt.Sprintf("user %q is not allowed to get secret %q", req.UserInfo.Username, ref.Name))
+	}
+	return admission.Allowed("")
+}
+
+// newReferencer returns an empty object of kind which may reference a secret
+func newReferencer(kind string) referencer {
+	switch kind {
+	case "Function":
+		return &Function{}
+	case "Library":
+		return &Library{}
+	}
+	return nil
+}
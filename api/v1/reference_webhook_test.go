@@ -0,0 +1,113 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// reviewClient answers subject access reviews with allowed and records the last one
+type reviewClient struct {
+	client.Client
+	allowed bool
+	review  *authorizationv1.SubjectAccessReview
+}
+
+func (c *reviewClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	c.review = obj.(*authorizationv1.SubjectAccessReview)
+	c.review.Status.Allowed = c.allowed
+	return nil
+}
+
+func TestSecretReferenceAuthorizer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	function := func(source *FunctionSource) runtime.RawExtension {
+		fn := &Function{
+			TypeMeta:   metav1.TypeMeta{APIVersion: GroupVersion.String(), Kind: "Function"},
+			ObjectMeta: metav1.ObjectMeta{Name: "hello-v1", Namespace: "kess-samples"},
+			Spec:       FunctionSpec{Runtime: "python", Source: source},
+		}
+		raw, err := json.Marshal(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return runtime.RawExtension{Raw: raw}
+	}
+	secretSource := &FunctionSource{SecretKeyRef: &apiv1.SecretKeySelector{
+		LocalObjectReference: apiv1.LocalObjectReference{Name: "hello"},
+		Key:                  "hello.py",
+	}}
+	request := func(obj, old runtime.RawExtension) admission.Request {
+		op := admissionv1beta1.Create
+		if old.Raw != nil {
+			op = admissionv1beta1.Update
+		}
+		return admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: GroupVersion.Group, Version: GroupVersion.Version, Kind: "Function"},
+			Name:      "hello-v1",
+			Namespace: "kess-samples",
+			Operation: op,
+			UserInfo: authenticationv1.UserInfo{
+				Username: "alice",
+				Groups:   []string{"developers"},
+				Extra:    map[string]authenticationv1.ExtraValue{"scopes": {"kess"}},
+			},
+			Object:    obj,
+			OldObject: old,
+		}}
+	}
+
+	c := &reviewClient{}
+	authorizer := &secretReferenceAuthorizer{client: c, decoder: decoder}
+
+	resp := authorizer.Handle(context.Background(), request(function(secretSource), runtime.RawExtension{}))
+	if resp.Allowed {
+		t.Fatal("a secret the author may not get must be denied")
+	}
+	attrs := c.review.Spec.ResourceAttributes
+	if c.review.Spec.User != "alice" || c.review.Spec.Groups[0] != "developers" || c.review.Spec.Extra["scopes"][0] != "kess" {
+		t.Errorf("unexpected review subject %+v", c.review.Spec)
+	}
+	if attrs.Verb != "get" || attrs.Resource != "secrets" || attrs.Namespace != "kess-samples" || attrs.Name != "hello" {
+		t.Errorf("unexpected review attributes %+v", attrs)
+	}
+
+	c.allowed = true
+	if resp := authorizer.Handle(context.Background(), request(function(secretSource), runtime.RawExtension{})); !resp.Allowed {
+		t.Errorf("a secret the author may get must be allowed, got %v", resp.Result)
+	}
+
+	c.allowed, c.review = false, nil
+	if resp := authorizer.Handle(context.Background(), request(function(secretSource), function(secretSource))); !resp.Allowed || c.review != nil {
+		t.Error("an unchanged secret reference must be allowed without review")
+	}
+	if resp := authorizer.Handle(context.Background(), request(function(secretSource), function(nil))); resp.Allowed || c.review == nil {
+		t.Error("a new secret reference must be reviewed")
+	}
+
+	c.review = nil
+	cmSource := &FunctionSource{ConfigMapKeyRef: &apiv1.ConfigMapKeySelector{
+		LocalObjectReference: apiv1.LocalObjectReference{Name: "hello"},
+		Key:                  "hello.py",
+	}}
+	if resp := authorizer.Handle(context.Background(), request(function(cmSource), runtime.RawExtension{})); !resp.Allowed || c.review != nil {
+		t.Error("a config map reference must be allowed without review")
+	}
+}
@@ -0,0 +1,314 @@
+package v1
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xorcare/pointer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// MaxRolloutAnalysisErrors is the number of analyses in a row unable to collect metrics after which
+// a rollout reverts to its stable version
+const MaxRolloutAnalysisErrors = 3
+
+// Default bulabula
+func (r *Rollout) Default() {
+	if r.ObjectMeta.Labels == nil {
+		r.ObjectMeta.Labels = make(map[string]string)
+	}
+	for k, v := range r.Labels() {
+		r.ObjectMeta.Labels[k] = v
+	}
+
+	for i := range r.Spec.Steps {
+		if r.Spec.Steps[i].Pause.Duration == 0 {
+			r.Spec.Steps[i].Pause.Duration = DefaultRolloutStepPause
+		}
+	}
+
+	if analysis := r.Spec.Analysis; analysis != nil {
+		if analysis.Interval.Duration == 0 {
+			analysis.Interval.Duration = DefaultRolloutAnalysisInterval
+		}
+		if analysis.Path == "" {
+			analysis.Path = DefaultRolloutMetricsPath
+		}
+		if analysis.RequestsMetric == "" {
+			analysis.RequestsMetric = DefaultRolloutRequestsMetric
+		}
+		if analysis.LatencyMetric == "" {
+			analysis.LatencyMetric = DefaultRolloutLatencyMetric
+		}
+	}
+}
+
+// DefaultStatus bulabula
+func (r *Rollout) DefaultStatus() {}
+
+// Labels bulabula
+func (r *Rollout) Labels() map[string]string {
+	return map[string]string{
+		LabelType: TypeRollout,
+	}
+}
+
+// NamespacedName bulabula
+func (r *Rollout) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      r.Name,
+		Namespace: r.Namespace,
+	}
+}
+
+// AliasNamespacedName bulabula
+func (r *Rollout) AliasNamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      r.Spec.Alias,
+		Namespace: r.Namespace,
+	}
+}
+
+// SetCondition bulabula
+func (r *Rollout) SetCondition(condition Condition) {
+	condition.ObservedGeneration = r.Generation
+	SetCondition(&r.Status.Conditions, condition)
+}
+
+// IsStarted reports whether rollout has started for its current spec, a changed spec starts over
+func (r *Rollout) IsStarted() bool {
+	return r.Status.Phase != "" && r.Status.ObservedGeneration == r.Generation
+}
+
+// IsComplete reports whether rollout has succeeded or failed for its current spec
+func (r *Rollout) IsComplete() bool {
+	return r.IsStarted() && r.Status.Phase != ProgressingRolloutPhase
+}
+
+// Start begins rollout, the version of alias receiving most requests other than the new version becomes
+// the stable version
+func (r *Rollout) Start(alias *FunctionAlias) {
+	var stable *FunctionAliasTarget
+	for i, target := range alias.Status.Versions {
+		if target.Version == r.Spec.Version {
+			continue
+		}
+		if stable == nil || target.Weight > stable.Weight {
+			stable = &alias.Status.Versions[i]
+		}
+	}
+
+	r.Status = RolloutStatus{
+		Phase:              ProgressingRolloutPhase,
+		ObservedGeneration: r.Generation,
+		Conditions:         r.Status.Conditions,
+	}
+	if stable != nil {
+		r.Status.StableVersion = stable.Version
+	}
+}
+
+// Progress moves rollout forward at now, current is the metrics of the new version when analysis is enabled,
+// and returns how long until rollout should progress again, zero when it is complete
+func (r *Rollout) Progress(now time.Time, current *RolloutMetrics) time.Duration {
+	if r.Status.Phase != ProgressingRolloutPhase {
+		return 0
+	}
+	if r.Status.StableVersion == "" {
+		r.promote("no previous version of alias to roll back to")
+		return 0
+	}
+	if r.Status.StepStartTime == nil {
+		r.startStep(0, now, current)
+		return r.wait(r.Spec.Steps[0].Pause.Duration)
+	}
+
+	if analysis := r.Spec.Analysis; analysis != nil && current != nil {
+		result := analysis.Evaluate(r.Status.Baseline, *current, now)
+		r.Status.LastAnalysis = &result
+		r.Status.AnalysisErrors = 0
+		if !result.Passed {
+			r.rollback(result.Message)
+			return 0
+		}
+		reason := ReasonReconciled
+		if result.Message != "" {
+			reason = ReasonInconclusive
+		}
+		r.SetCondition(NewCondition(ConditionAnalysisPassed, true, reason, result.Message))
+	}
+
+	step := r.Spec.Steps[r.Status.CurrentStep]
+	if remaining := step.Pause.Duration - now.Sub(r.Status.StepStartTime.Time); remaining > 0 {
+		return r.wait(remaining)
+	}
+	if next := r.Status.CurrentStep + 1; int(next) < len(r.Spec.Steps) {
+		r.startStep(next, now, current)
+		return r.wait(r.Spec.Steps[next].Pause.Duration)
+	}
+	r.promote("")
+	return 0
+}
+
+// FailAnalysis records that the metrics of the new version could not be collected at now, rollout reverts
+// once MaxRolloutAnalysisErrors analyses in a row failed, and returns how long until rollout should
+// progress again, zero when it is complete
+func (r *Rollout) FailAnalysis(now time.Time, err error) time.Duration {
+	if r.Status.Phase != ProgressingRolloutPhase {
+		return 0
+	}
+	if r.Status.StableVersion == "" {
+		return r.Progress(now, nil)
+	}
+
+	r.Status.AnalysisErrors++
+	message := fmt.Sprintf("unable to collect metrics: %s", err)
+	r.Status.LastAnalysis = &RolloutAnalysisResult{Time: metav1.Time{Time: now}, Message: message}
+	if r.Status.AnalysisErrors >= MaxRolloutAnalysisErrors {
+		r.rollback(fmt.Sprintf("%d analyses in a row were %s", r.Status.AnalysisErrors, message))
+		return 0
+	}
+	r.SetCondition(NewCondition(ConditionAnalysisPassed, false, ReasonMetricsUnavailable, message))
+	return r.Spec.Analysis.Interval.Duration
+}
+
+// AliasVersions returns the versions of alias for the current state of rollout, rollout only weighs its stable
+// and new versions within the share of requests they receive together, the other versions of alias are kept
+// and latest, which would resolve to either of them, is pinned to them
+func (r *Rollout) AliasVersions(alias *FunctionAlias) []FunctionAliasVersion {
+	var (
+		share    = int32(100)
+		weights  = alias.Weights()
+		owned    = make(map[string]int32, 2)
+		versions []FunctionAliasVersion
+	)
+	owns := func(version string) bool {
+		return version == r.Spec.Version || version == r.Status.StableVersion || version == LatestVersion
+	}
+
+	for i, version := range alias.Spec.Versions {
+		if !owns(version.Version) {
+			share -= weights[i]
+		}
+	}
+	switch {
+	case r.Status.Phase == SucceededRolloutPhase || r.Status.StableVersion == "":
+		owned[r.Spec.Version] = share
+	case r.Status.Phase == FailedRolloutPhase:
+		owned[r.Status.StableVersion] = share
+	default:
+		owned[r.Spec.Version] = share * r.Status.Weight / 100
+		owned[r.Status.StableVersion] = share - owned[r.Spec.Version]
+	}
+
+	for _, version := range alias.Spec.Versions {
+		if !owns(version.Version) {
+			versions = append(versions, version)
+			continue
+		}
+		if weight, ok := owned[version.Version]; ok {
+			versions = append(versions, FunctionAliasVersion{Version: version.Version, Weight: pointer.Int32(weight)})
+			delete(owned, version.Version)
+		}
+	}
+	for _, version := range []string{r.Status.StableVersion, r.Spec.Version} {
+		if weight, ok := owned[version]; ok {
+			versions = append(versions, FunctionAliasVersion{Version: version, Weight: pointer.Int32(weight)})
+			delete(owned, version)
+		}
+	}
+	return versions
+}
+
+func (r *Rollout) startStep(step int32, now time.Time, current *RolloutMetrics) {
+	r.Status.CurrentStep = step
+	r.Status.Weight = r.Spec.Steps[step].Weight
+	r.Status.StepStartTime = &metav1.Time{Time: now}
+	r.Status.Baseline = current
+
+	message := fmt.Sprintf("step %d/%d sends %d%% of requests to version %s",
+		step+1, len(r.Spec.Steps), r.Status.Weight, r.Spec.Version)
+	r.SetCondition(NewCondition(ConditionProgressing, true, ReasonStepProgressing, message))
+	r.SetCondition(NewCondition(ConditionReady, false, ReasonStepProgressing, message))
+}
+
+func (r *Rollout) promote(message string) {
+	if message == "" {
+		message = fmt.Sprintf("version %s receives all requests", r.Spec.Version)
+	}
+	r.Status.Phase = SucceededRolloutPhase
+	r.Status.Weight = 100
+	r.SetCondition(NewCondition(ConditionProgressing, false, ReasonPromoted, message))
+	r.SetCondition(NewCondition(ConditionReady, true, ReasonPromoted, message))
+}
+
+func (r *Rollout) rollback(message string) {
+	message = fmt.Sprintf("reverted to version %s: %s", r.Status.StableVersion, message)
+	r.Status.Phase = FailedRolloutPhase
+	r.Status.Weight = 0
+	r.SetCondition(NewCondition(ConditionAnalysisPassed, false, ReasonAnalysisFailed, r.Status.LastAnalysis.Message))
+	r.SetCondition(NewCondition(ConditionProgressing, false, ReasonRolledBack, message))
+	r.SetCondition(NewCondition(ConditionReady, false, ReasonRolledBack, message))
+}
+
+// NextAnalysis returns how long until the metrics of the new version are due at now, zero when they are due or
+// rollout is not analyzed, metrics are collected when a step starts and at every analysis since
+func (r *Rollout) NextAnalysis(now time.Time) time.Duration {
+	if r.Spec.Analysis == nil || !r.IsStarted() || r.Status.Phase != ProgressingRolloutPhase {
+		return 0
+	}
+
+	var last time.Time
+	if r.Status.StepStartTime != nil {
+		last = r.Status.StepStartTime.Time
+	}
+	if r.Status.LastAnalysis != nil && r.Status.LastAnalysis.Time.After(last) {
+		last = r.Status.LastAnalysis.Time.Time
+	}
+	if last.IsZero() {
+		return 0
+	}
+	if remaining := last.Add(r.Spec.Analysis.Interval.Duration).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// wait caps remaining by the analysis interval, so that every step is analyzed while it lasts
+func (r *Rollout) wait(remaining time.Duration) time.Duration {
+	if r.Spec.Analysis != nil && r.Spec.Analysis.Interval.Duration < remaining {
+		return r.Spec.Analysis.Interval.Duration
+	}
+	return remaining
+}
+
+// Evaluate analyzes the metrics of the new version since baseline, counters lower than baseline are
+// taken as reset by restarted pods
+func (r *RolloutAnalysis) Evaluate(baseline *RolloutMetrics, current RolloutMetrics, now time.Time) RolloutAnalysisResult {
+	delta := current
+	if baseline != nil && current.Requests >= baseline.Requests && current.Errors >= baseline.Errors {
+		delta = RolloutMetrics{
+			Requests:            current.Requests - baseline.Requests,
+			Errors:              current.Errors - baseline.Errors,
+			LatencyMilliseconds: current.LatencyMilliseconds - baseline.LatencyMilliseconds,
+		}
+	}
+
+	result := RolloutAnalysisResult{Time: metav1.Time{Time: now}, Requests: delta.Requests, Passed: true}
+	if delta.Requests == 0 || delta.Requests < r.MinRequests {
+		result.Message = fmt.Sprintf("%d requests are too few to analyze", delta.Requests)
+		return result
+	}
+	result.ErrorPercentage = int32(delta.Errors * 100 / delta.Requests)
+	result.AverageLatency.Duration = time.Duration(delta.LatencyMilliseconds/delta.Requests) * time.Millisecond
+
+	if max := r.MaxErrorPercentage; max != nil && delta.Errors*100 > int64(*max)*delta.Requests {
+		result.Passed = false
+		result.Message = fmt.Sprintf("%d of %d requests failed, more than %d%%", delta.Errors, delta.Requests, *max)
+	} else if max := r.MaxLatency; max != nil && delta.LatencyMilliseconds > max.Milliseconds()*delta.Requests {
+		result.Passed = false
+		result.Message = fmt.Sprintf("average latency %s is more than %s", result.AverageLatency.Duration, max.Duration)
+	}
+	return result
+}
@@ -0,0 +1,199 @@
+package v1
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xorcare/pointer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testRollout(analysis *RolloutAnalysis) (*Rollout, *FunctionAlias) {
+	rollout := &Rollout{
+		ObjectMeta: metav1.ObjectMeta{Name: "sample-v2", Namespace: "kess-samples", Generation: 1},
+		Spec: RolloutSpec{
+			Alias:    "sample-prod",
+			Version:  "v2",
+			Steps:    []RolloutStep{{Weight: 5}, {Weight: 50}},
+			Analysis: analysis,
+		},
+	}
+	rollout.Default()
+
+	alias := &FunctionAlias{Status: FunctionAliasStatus{Versions: []FunctionAliasTarget{
+		{Version: "v2", Weight: 0},
+		{Version: "v1", Weight: 100},
+	}}}
+	return rollout, alias
+}
+
+func TestRolloutProgress(t *testing.T) {
+	rollout, alias := testRollout(nil)
+	now := time.Now()
+
+	rollout.Start(alias)
+	if !rollout.IsStarted() || rollout.Status.StableVersion != "v1" {
+		t.Fatalf("rollout must start from the stable version v1, got %+v", rollout.Status)
+	}
+
+	if wait := rollout.Progress(now, nil); wait != DefaultRolloutStepPause || rollout.Status.Weight != 5 {
+		t.Fatalf("first step must send 5%% for one pause, got %d for %s", rollout.Status.Weight, wait)
+	}
+	versions := rollout.AliasVersions(alias)
+	if *versions[0].Weight != 95 || versions[1].Version != "v2" || *versions[1].Weight != 5 {
+		t.Errorf("unexpected alias versions %+v", versions)
+	}
+
+	if wait := rollout.Progress(now.Add(time.Second), nil); wait != DefaultRolloutStepPause-time.Second || rollout.Status.CurrentStep != 0 {
+		t.Errorf("step must last its pause, got step %d waiting %s", rollout.Status.CurrentStep, wait)
+	}
+	rollout.Progress(now.Add(DefaultRolloutStepPause), nil)
+	if rollout.Status.CurrentStep != 1 || rollout.Status.Weight != 50 {
+		t.Errorf("rollout must move to the second step, got %+v", rollout.Status)
+	}
+
+	rollout.Progress(now.Add(2*DefaultRolloutStepPause), nil)
+	if !rollout.IsComplete() || rollout.Status.Phase != SucceededRolloutPhase || !IsConditionTrue(rollout.Status.Conditions, ConditionReady) {
+		t.Errorf("rollout must succeed after the last step, got %+v", rollout.Status)
+	}
+	if versions := rollout.AliasVersions(alias); len(versions) != 1 || versions[0].Version != "v2" || *versions[0].Weight != 100 {
+		t.Errorf("new version must receive all requests, got %+v", versions)
+	}
+
+	rollout.Generation++
+	if rollout.IsStarted() {
+		t.Error("a changed spec must start rollout over")
+	}
+}
+
+func TestRolloutRollback(t *testing.T) {
+	rollout, alias := testRollout(&RolloutAnalysis{MinRequests: 10, MaxErrorPercentage: pointer.Int32(5)})
+	now := time.Now()
+
+	rollout.Start(alias)
+	if wait := rollout.Progress(now, &RolloutMetrics{Requests: 100, Errors: 50}); wait != DefaultRolloutAnalysisInterval {
+		t.Errorf("steps must be analyzed every interval, got %s", wait)
+	}
+
+	rollout.Progress(now.Add(DefaultRolloutAnalysisInterval), &RolloutMetrics{Requests: 105, Errors: 55})
+	if rollout.Status.Phase != ProgressingRolloutPhase {
+		t.Fatalf("too few requests must not fail rollout, got %+v", rollout.Status)
+	}
+	if condition := FindCondition(rollout.Status.Conditions, ConditionAnalysisPassed); condition.Reason != ReasonInconclusive {
+		t.Errorf("analysis must be inconclusive, got %+v", condition)
+	}
+
+	rollout.Progress(now.Add(2*DefaultRolloutAnalysisInterval), &RolloutMetrics{Requests: 200, Errors: 54})
+	if rollout.Status.Phase != ProgressingRolloutPhase || rollout.Status.LastAnalysis.ErrorPercentage != 4 {
+		t.Fatalf("unexpected status %+v", rollout.Status)
+	}
+	if condition := FindCondition(rollout.Status.Conditions, ConditionAnalysisPassed); condition.Reason != ReasonReconciled {
+		t.Errorf("errors of the baseline must not count, got %+v", condition)
+	}
+
+	rollout.Progress(now.Add(3*DefaultRolloutAnalysisInterval), &RolloutMetrics{Requests: 300, Errors: 70})
+	if rollout.Status.Phase != FailedRolloutPhase || IsConditionTrue(rollout.Status.Conditions, ConditionAnalysisPassed) {
+		t.Fatalf("rollout must fail when too many requests fail, got %+v", rollout.Status)
+	}
+	if condition := FindCondition(rollout.Status.Conditions, ConditionProgressing); condition.Reason != ReasonRolledBack {
+		t.Errorf("rollback must be reported, got %+v", condition)
+	}
+	if versions := rollout.AliasVersions(alias); len(versions) != 1 || versions[0].Version != "v1" || *versions[0].Weight != 100 {
+		t.Errorf("stable version must receive all requests, got %+v", versions)
+	}
+}
+
+func TestRolloutFailAnalysis(t *testing.T) {
+	rollout, alias := testRollout(&RolloutAnalysis{MinRequests: 10, MaxErrorPercentage: pointer.Int32(5)})
+	now := time.Now()
+
+	rollout.Start(alias)
+	rollout.Progress(now, &RolloutMetrics{})
+	for i := 1; i < MaxRolloutAnalysisErrors; i++ {
+		if wait := rollout.FailAnalysis(now.Add(time.Duration(i)*time.Second), errors.New("timeout")); wait != DefaultRolloutAnalysisInterval {
+			t.Errorf("failed analysis must be retried every interval, got %s", wait)
+		}
+	}
+	if condition := FindCondition(rollout.Status.Conditions, ConditionAnalysisPassed); rollout.Status.Phase != ProgressingRolloutPhase ||
+		condition == nil || condition.Reason != ReasonMetricsUnavailable {
+		t.Fatalf("rollout must go on while analyses fail, got %+v", rollout.Status)
+	}
+
+	rollout.Progress(now.Add(time.Minute), &RolloutMetrics{Requests: 1})
+	if rollout.Status.AnalysisErrors != 0 {
+		t.Errorf("a passed analysis must reset the errors, got %d", rollout.Status.AnalysisErrors)
+	}
+	for i := 0; i < MaxRolloutAnalysisErrors; i++ {
+		rollout.FailAnalysis(now.Add(time.Minute), errors.New("timeout"))
+	}
+	if rollout.Status.Phase != FailedRolloutPhase {
+		t.Errorf("rollout must revert once analyses failed in a row, got %+v", rollout.Status)
+	}
+}
+
+func TestRolloutNextAnalysis(t *testing.T) {
+	rollout, alias := testRollout(&RolloutAnalysis{})
+	now := time.Now()
+
+	rollout.Start(alias)
+	if wait := rollout.NextAnalysis(now); wait != 0 {
+		t.Errorf("metrics must be due before the first step, got %s", wait)
+	}
+	rollout.Progress(now, &RolloutMetrics{})
+	if wait := rollout.NextAnalysis(now.Add(time.Second)); wait != DefaultRolloutAnalysisInterval-time.Second {
+		t.Errorf("metrics must not be collected again within the interval, got %s", wait)
+	}
+
+	rollout.FailAnalysis(now.Add(DefaultRolloutAnalysisInterval), errors.New("timeout"))
+	if wait := rollout.NextAnalysis(now.Add(DefaultRolloutAnalysisInterval)); wait != DefaultRolloutAnalysisInterval {
+		t.Errorf("a failed analysis must wait for the interval too, got %s", wait)
+	}
+	if wait := rollout.NextAnalysis(now.Add(2 * DefaultRolloutAnalysisInterval)); wait != 0 {
+		t.Errorf("metrics must be due once the interval passed, got %s", wait)
+	}
+}
+
+func TestRolloutAliasVersions(t *testing.T) {
+	rollout, alias := testRollout(nil)
+	alias.Spec.Versions = []FunctionAliasVersion{
+		{Version: "v0", Weight: pointer.Int32(20)},
+		{Version: LatestVersion},
+	}
+	alias.Status.Versions = []FunctionAliasTarget{{Version: "v0", Weight: 20}, {Version: "v1", Weight: 80}}
+
+	rollout.Start(alias)
+	rollout.Progress(time.Now(), nil)
+	versions := rollout.AliasVersions(alias)
+	if len(versions) != 3 || versions[0].Version != "v0" || *versions[0].Weight != 20 ||
+		versions[1].Version != "v1" || *versions[1].Weight != 76 || versions[2].Version != "v2" || *versions[2].Weight != 4 {
+		t.Errorf("rollout must only weigh its versions within their share, got %+v", versions)
+	}
+
+	alias.Spec.Versions = versions
+	if again := rollout.AliasVersions(alias); len(again) != 3 || *again[1].Weight != 76 || *again[2].Weight != 4 {
+		t.Errorf("alias versions must be stable, got %+v", again)
+	}
+}
+
+func TestRolloutAnalysisEvaluate(t *testing.T) {
+	analysis := &RolloutAnalysis{MaxLatency: &metav1.Duration{Duration: 100 * time.Millisecond}}
+	baseline := &RolloutMetrics{Requests: 1000, LatencyMilliseconds: 1000000}
+
+	if result := analysis.Evaluate(baseline, RolloutMetrics{Requests: 1010, LatencyMilliseconds: 1000500}, time.Now()); !result.Passed ||
+		result.AverageLatency.Duration != 50*time.Millisecond {
+		t.Errorf("latency must be averaged since baseline, got %+v", result)
+	}
+	if result := analysis.Evaluate(baseline, RolloutMetrics{Requests: 10, LatencyMilliseconds: 5000}, time.Now()); result.Passed {
+		t.Errorf("counters reset by restarted pods must be analyzed from zero, got %+v", result)
+	}
+}
+
+func TestRolloutWithoutStableVersion(t *testing.T) {
+	rollout, _ := testRollout(nil)
+	rollout.Start(&FunctionAlias{})
+	rollout.Progress(time.Now(), nil)
+	if rollout.Status.Phase != SucceededRolloutPhase {
+		t.Errorf("rollout without a previous version must promote at once, got %+v", rollout.Status)
+	}
+}
@@ -0,0 +1,217 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RolloutPhase bulabula
+type RolloutPhase string
+
+// RolloutPhase Constants bulabula
+const (
+	ProgressingRolloutPhase RolloutPhase = "Progressing"
+	SucceededRolloutPhase   RolloutPhase = "Succeeded"
+	FailedRolloutPhase      RolloutPhase = "Failed"
+)
+
+// RolloutStep bulabula
+type RolloutStep struct {
+	// The percentage of requests sent to the new version during the step
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight int32 `json:"weight"`
+
+	// Optional duration the step lasts before moving to the next one, defaults to 1m
+	// +kubebuilder:validation:Optional
+	Pause metav1.Duration `json:"pause,omitempty"`
+}
+
+// RolloutAnalysis bulabula
+type RolloutAnalysis struct {
+	// Optional interval between two analyses of a step, defaults to 30s
+	// +kubebuilder:validation:Optional
+	Interval metav1.Duration `json:"interval,omitempty"`
+
+	// Optional path of the metrics endpoint of runtime pods
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="/metrics"
+	Path string `json:"path,omitempty"`
+
+	// Optional port of the metrics endpoint of runtime pods, defaults to the runtime port
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port,omitempty"`
+
+	// Optional counter of requests labeled with function, version and code
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="kess_runtime_requests_total"
+	RequestsMetric string `json:"requestsMetric,omitempty"`
+
+	// Optional histogram or summary of request durations in seconds labeled with function and version
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="kess_runtime_request_duration_seconds"
+	LatencyMetric string `json:"latencyMetric,omitempty"`
+
+	// Optional number of requests below which an analysis is inconclusive and passes
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	MinRequests int64 `json:"minRequests,omitempty"`
+
+	// Optional maximum percentage of requests answered with a 5xx code
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	MaxErrorPercentage *int32 `json:"maxErrorPercentage,omitempty"`
+
+	// Optional maximum average request duration
+	// +kubebuilder:validation:Optional
+	MaxLatency *metav1.Duration `json:"maxLatency,omitempty"`
+}
+
+// RolloutSpec defines the desired state of Rollout
+type RolloutSpec struct {
+	// The name of the function alias whose weights are driven by rollout
+	// +kubebuilder:validation:Required
+	Alias string `json:"alias"`
+
+	// The new version of function rolled out through alias
+	// +kubebuilder:validation:Required
+	Version string `json:"version"`
+
+	// The steps of rollout, the new version receives all requests after the last one
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	Steps []RolloutStep `json:"steps"`
+
+	// Optional analysis of every step, rollout reverts to the previous version when it fails
+	// +kubebuilder:validation:Optional
+	Analysis *RolloutAnalysis `json:"analysis,omitempty"`
+}
+
+// RolloutMetrics bulabula
+type RolloutMetrics struct {
+	// The number of requests
+	Requests int64 `json:"requests"`
+
+	// The number of requests answered with a 5xx code
+	Errors int64 `json:"errors"`
+
+	// The sum of request durations in milliseconds
+	LatencyMilliseconds int64 `json:"latencyMilliseconds"`
+}
+
+// RolloutAnalysisResult bulabula
+type RolloutAnalysisResult struct {
+	// The time of analysis
+	Time metav1.Time `json:"time"`
+
+	// The number of requests to the new version since the step started
+	Requests int64 `json:"requests"`
+
+	// The percentage of requests answered with a 5xx code
+	ErrorPercentage int32 `json:"errorPercentage"`
+
+	// The average request duration
+	AverageLatency metav1.Duration `json:"averageLatency"`
+
+	// Whether the analysis passed
+	Passed bool `json:"passed"`
+
+	// Optional message of analysis
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+}
+
+// RolloutStatus defines the observed state of Rollout
+type RolloutStatus struct {
+	// Optional phase of rollout
+	// +kubebuilder:validation:Optional
+	Phase RolloutPhase `json:"phase,omitempty"`
+
+	// Optional version receiving the rest of requests, restored when rollout fails
+	// +kubebuilder:validation:Optional
+	StableVersion string `json:"stableVersion,omitempty"`
+
+	// Optional index of the current step
+	// +kubebuilder:validation:Optional
+	CurrentStep int32 `json:"currentStep,omitempty"`
+
+	// Optional percentage of requests currently sent to the new version
+	// +kubebuilder:validation:Optional
+	Weight int32 `json:"weight,omitempty"`
+
+	// Optional time the current step started
+	// +kubebuilder:validation:Optional
+	StepStartTime *metav1.Time `json:"stepStartTime,omitempty"`
+
+	// Optional metrics of the new version when the current step started
+	// +kubebuilder:validation:Optional
+	Baseline *RolloutMetrics `json:"baseline,omitempty"`
+
+	// Optional result of the last analysis
+	// +kubebuilder:validation:Optional
+	LastAnalysis *RolloutAnalysisResult `json:"lastAnalysis,omitempty"`
+
+	// Optional number of analyses in a row unable to collect the metrics of the new version
+	// +kubebuilder:validation:Optional
+	AnalysisErrors int32 `json:"analysisErrors,omitempty"`
+
+	// The generation observed by the rollout controller
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Optional conditions of rollout
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:resource:categories="kess",shortName="ro"
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`,priority=0
+// +kubebuilder:printcolumn:name="Alias",type=string,JSONPath=`.spec.alias`,priority=0
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.version`,priority=0
+// +kubebuilder:printcolumn:name="Stable",type=string,JSONPath=`.status.stableVersion`,priority=0
+// +kubebuilder:printcolumn:name="Weight",type=integer,JSONPath=`.status.weight`,priority=0
+// +kubebuilder:object:root=true
+
+// Rollout is the Schema for the rollouts API
+type Rollout struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RolloutSpec   `json:"spec,omitempty"`
+	Status RolloutStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RolloutList contains a list of Rollout
+type RolloutList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Rollout `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Rollout{}, &RolloutList{})
+}
@@ -0,0 +1,130 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var rolloutlog = logf.Log.WithName("rollout-resource")
+
+// SetupWebhookWithManager bulabula
+func (r *Rollout) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-core-kess-io-v1-rollout,mutating=true,failurePolicy=fail,groups=core.kess.io,resources=rollouts,verbs=create;update,versions=v1,name=mrollout.kb.io
+
+var _ webhook.Defaulter = &Rollout{}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-core-kess-io-v1-rollout,mutating=false,failurePolicy=fail,groups=core.kess.io,resources=rollouts,versions=v1,name=vrollout.kb.io
+
+var _ webhook.Validator = &Rollout{}
+
+// ValidateCreate bulabula
+func (r *Rollout) ValidateCreate() error {
+	rolloutlog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate bulabula
+func (r *Rollout) ValidateUpdate(old runtime.Object) error {
+	rolloutlog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateDelete bulabula
+func (r *Rollout) ValidateDelete() error {
+	return nil
+}
+
+func (r *Rollout) validate() error {
+	var (
+		allErrs  field.ErrorList
+		specPath = field.NewPath("spec")
+	)
+
+	if r.Spec.Alias == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("alias"), "rollout must reference a function alias"))
+	}
+	if r.Spec.Version == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("version"), ""))
+	}
+	if r.Spec.Version == LatestVersion {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("version"), r.Spec.Version, "rollout needs a concrete version"))
+	}
+	if len(r.Spec.Steps) == 0 {
+		allErrs = append(allErrs, field.Required(specPath.Child("steps"), "rollout must have at least one step"))
+	}
+	for i, step := range r.Spec.Steps {
+		if step.Weight < 0 || step.Weight > 100 {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("steps").Index(i).Child("weight"), step.Weight, "must be between 0 and 100"))
+		}
+	}
+
+	if len(allErrs) == 0 {
+		errs, err := r.validateAliasCollision(specPath.Child("alias"))
+		if err != nil {
+			return err
+		}
+		allErrs = append(allErrs, errs...)
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Rollout").GroupKind(), r.Name, allErrs)
+}
+
+// validateAliasCollision rejects rollouts driving the same function alias as another rollout in progress,
+// they would overwrite the weights of each other.
+func (r *Rollout) validateAliasCollision(fldPath *field.Path) (field.ErrorList, error) {
+	var (
+		allErrs  field.ErrorList
+		rollouts RolloutList
+	)
+
+	if webhookClient == nil {
+		return allErrs, nil
+	}
+	if err := webhookClient.List(context.Background(), &rollouts, client.InNamespace(r.Namespace)); err != nil {
+		return allErrs, err
+	}
+
+	for _, rollout := range rollouts.Items {
+		if rollout.Name == r.Name || rollout.Spec.Alias != r.Spec.Alias {
+			continue
+		}
+		if rollout.Status.Phase == ProgressingRolloutPhase {
+			allErrs = append(allErrs, field.Invalid(fldPath, r.Spec.Alias,
+				fmt.Sprintf("alias %q is already being rolled out by %q", r.Spec.Alias, rollout.Name)))
+		}
+	}
+	return allErrs, nil
+}
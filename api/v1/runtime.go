@@ -17,14 +17,25 @@ limitations under the License.
 package v1
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
 	"strconv"
+	"time"
 
+	utilsjson "github.com/yamajik/kess/utils/json"
 	utilsstrings "github.com/yamajik/kess/utils/strings"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 )
 
 // Default bulabula
@@ -39,6 +50,29 @@ func (r *Runtime) Default() {
 	for k, v := range labels {
 		r.ObjectMeta.Labels[k] = v
 	}
+
+	if r.Spec.UpdateStrategy.Type == "" {
+		r.Spec.UpdateStrategy.Type = RestartRuntimeUpdateStrategyType
+	}
+	if r.Spec.UpdateStrategy.Path == "" {
+		r.Spec.UpdateStrategy.Path = DefaultUpdateStrategyPath
+	}
+
+	if r.Spec.Volume.Type == "" {
+		r.Spec.Volume.Type = ConfigMapRuntimeVolumeType
+	}
+	if r.Spec.Volume.Image == "" {
+		r.Spec.Volume.Image = DefaultVolumeImage
+	}
+
+	if scaleToZero := r.Spec.ScaleToZero; scaleToZero != nil {
+		if scaleToZero.IdleWindow.Duration == 0 {
+			scaleToZero.IdleWindow.Duration = DefaultScaleToZeroIdleWindow
+		}
+		if scaleToZero.ActivationTimeout.Duration == 0 {
+			scaleToZero.ActivationTimeout.Duration = DefaultScaleToZeroActivationTimeout
+		}
+	}
 }
 
 // DefaultStatus bulabula
@@ -57,8 +91,16 @@ func (r *Runtime) DefaultStatus() {
 // Labels bulabula
 func (r *Runtime) Labels() map[string]string {
 	return map[string]string{
-		"kess-type":    TypeRuntime,
-		"kess-runtime": r.Name,
+		LabelType:    TypeRuntime,
+		LabelRuntime: r.Name,
+	}
+}
+
+// ActivatorLabels bulabula
+func (r *Runtime) ActivatorLabels() map[string]string {
+	return map[string]string{
+		LabelType:    TypeActivator,
+		LabelRuntime: r.Name,
 	}
 }
 
@@ -70,6 +112,29 @@ func (r *Runtime) NamespacedName() types.NamespacedName {
 	}
 }
 
+// ActivatorNamespacedName bulabula
+func (r *Runtime) ActivatorNamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      r.Name + "-activator",
+		Namespace: r.Namespace,
+	}
+}
+
+// ActivatorUsername is the user the activator authenticates to the activity endpoint as, the service account
+// of the activator, so that it can only report the activity of its own runtime
+func (r *Runtime) ActivatorUsername() string {
+	namespacedName := r.ActivatorNamespacedName()
+	return fmt.Sprintf("system:serviceaccount:%s:%s", namespacedName.Namespace, namespacedName.Name)
+}
+
+// PrivateServiceNamespacedName is the service selecting the runtime pods only, which the activator proxies to
+func (r *Runtime) PrivateServiceNamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      r.Name + "-private",
+		Namespace: r.Namespace,
+	}
+}
+
 // ConfigMapNamespacedName bulabula
 func (r *Runtime) ConfigMapNamespacedName(name string) types.NamespacedName {
 	return types.NamespacedName{
@@ -79,52 +144,12 @@ func (r *Runtime) ConfigMapNamespacedName(name string) types.NamespacedName {
 }
 
 // Deployment bulabula
-func (r *Runtime) Deployment() appsv1.Deployment {
-	var (
-		volumes []apiv1.Volume
-		mounts  []apiv1.VolumeMount
-	)
-
+func (r *Runtime) Deployment() (appsv1.Deployment, error) {
 	labels := r.Labels()
 
-	// Functions ConfigMap Volumes
-	{
-		for _, fn := range r.Status.Functions {
-			volumes = append(volumes, apiv1.Volume{
-				Name: fn.Name,
-				VolumeSource: apiv1.VolumeSource{
-					ConfigMap: &apiv1.ConfigMapVolumeSource{
-						LocalObjectReference: apiv1.LocalObjectReference{
-							Name: fn.Name,
-						},
-					},
-				},
-			})
-			mounts = append(mounts, apiv1.VolumeMount{
-				Name:      fn.Name,
-				MountPath: fn.Mount,
-			})
-		}
-	}
-
-	// Libraries ConfigMap Volumes
-	{
-		for _, lib := range r.Status.Libraries {
-			volumes = append(volumes, apiv1.Volume{
-				Name: lib.Name,
-				VolumeSource: apiv1.VolumeSource{
-					ConfigMap: &apiv1.ConfigMapVolumeSource{
-						LocalObjectReference: apiv1.LocalObjectReference{
-							Name: lib.Name,
-						},
-					},
-				},
-			})
-			mounts = append(mounts, apiv1.VolumeMount{
-				Name:      lib.Name,
-				MountPath: lib.Mount,
-			})
-		}
+	volumes, mounts, err := r.ConfigMapVolumes()
+	if err != nil {
+		return appsv1.Deployment{}, err
 	}
 
 	port := apiv1.ContainerPort{
@@ -141,16 +166,33 @@ func (r *Runtime) Deployment() appsv1.Deployment {
 		VolumeMounts: mounts,
 	}
 
-	template := apiv1.PodTemplateSpec{
+	var initContainers []apiv1.Container
+	if r.Spec.Volume.Type == ChunkedRuntimeVolumeType {
+		initContainers = append(initContainers, r.AssemblerContainer())
+	}
+
+	var annotations map[string]string
+	if r.Spec.UpdateStrategy.Type == RestartRuntimeUpdateStrategyType || r.Spec.UpdateStrategy.Type == "" {
+		annotations = map[string]string{
+			AnnotationConfigMapsHash: r.Status.ConfigMapsHash,
+		}
+	}
+
+	template, err := r.MergePodTemplate(apiv1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      r.Name,
-			Namespace: r.Namespace,
-			Labels:    labels,
+			Name:        r.Name,
+			Namespace:   r.Namespace,
+			Labels:      labels,
+			Annotations: annotations,
 		},
 		Spec: apiv1.PodSpec{
-			Volumes:    volumes,
-			Containers: []apiv1.Container{container},
+			Volumes:        volumes,
+			InitContainers: initContainers,
+			Containers:     []apiv1.Container{container},
 		},
+	})
+	if err != nil {
+		return appsv1.Deployment{}, err
 	}
 
 	deployment := appsv1.Deployment{
@@ -168,18 +210,372 @@ func (r *Runtime) Deployment() appsv1.Deployment {
 				MatchLabels: labels,
 			},
 			Template: template,
-			Replicas: r.Spec.Replicas,
 		},
 	}
+	// Leave spec.replicas to the autoscaler, applying it would take it back on every reconcile
+	switch {
+	case r.Status.ScaledToZero:
+		zero := int32(0)
+		deployment.Spec.Replicas = &zero
+	case r.Spec.Autoscaling == nil:
+		deployment.Spec.Replicas = r.Spec.Replicas
+	}
+
+	return deployment, nil
+}
+
+// HorizontalPodAutoscaler bulabula
+func (r *Runtime) HorizontalPodAutoscaler() autoscalingv2beta2.HorizontalPodAutoscaler {
+	var (
+		labels      = r.Labels()
+		autoscaling = r.Spec.Autoscaling
+		metrics     []autoscalingv2beta2.MetricSpec
+	)
+
+	if autoscaling.TargetCPUUtilizationPercentage != nil {
+		metrics = append(metrics, resourceUtilizationMetric(apiv1.ResourceCPU, *autoscaling.TargetCPUUtilizationPercentage))
+	}
+	if autoscaling.TargetMemoryUtilizationPercentage != nil {
+		metrics = append(metrics, resourceUtilizationMetric(apiv1.ResourceMemory, *autoscaling.TargetMemoryUtilizationPercentage))
+	}
+	metrics = append(metrics, autoscaling.Metrics...)
+
+	return autoscalingv2beta2.HorizontalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "HorizontalPodAutoscaler",
+			APIVersion: "autoscaling/v2beta2",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.Name,
+			Namespace: r.Namespace,
+			Labels:    labels,
+		},
+		Spec: autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       r.Name,
+			},
+			MinReplicas: autoscaling.MinReplicas,
+			MaxReplicas: autoscaling.MaxReplicas,
+			Metrics:     metrics,
+		},
+	}
+}
+
+func resourceUtilizationMetric(name apiv1.ResourceName, utilization int32) autoscalingv2beta2.MetricSpec {
+	return autoscalingv2beta2.MetricSpec{
+		Type: autoscalingv2beta2.ResourceMetricSourceType,
+		Resource: &autoscalingv2beta2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2beta2.MetricTarget{
+				Type:               autoscalingv2beta2.UtilizationMetricType,
+				AverageUtilization: &utilization,
+			},
+		},
+	}
+}
+
+// ConfigMapVolumes returns the volumes and mounts of the function and library config maps
+func (r *Runtime) ConfigMapVolumes() ([]apiv1.Volume, []apiv1.VolumeMount, error) {
+	var (
+		volumes []apiv1.Volume
+		mounts  []apiv1.VolumeMount
+	)
+
+	switch r.Spec.Volume.Type {
+	case ChunkedRuntimeVolumeType:
+		if err := r.checkRestartStrategy(); err != nil {
+			return nil, nil, err
+		}
+		return r.chunkedVolumes()
+	case ProjectedRuntimeVolumeType:
+		if err := r.checkNotChunked(); err != nil {
+			return nil, nil, err
+		}
+		return r.projectedVolumes()
+	}
+	if err := r.checkNotChunked(); err != nil {
+		return nil, nil, err
+	}
+
+	// Functions ConfigMap Volumes
+	{
+		for _, fn := range sortedRuntimeConfigMaps(r.Status.Functions) {
+			volumes = append(volumes, configMapVolume(fn))
+			mounts = append(mounts, apiv1.VolumeMount{
+				Name:      fn.Name,
+				MountPath: fn.Mount,
+			})
+		}
+	}
+
+	// Libraries ConfigMap Volumes
+	{
+		for _, lib := range sortedRuntimeConfigMaps(r.Status.Libraries) {
+			volumes = append(volumes, configMapVolume(lib))
+			mounts = append(mounts, apiv1.VolumeMount{
+				Name:      lib.Name,
+				MountPath: lib.Mount,
+			})
+		}
+	}
+
+	return volumes, mounts, nil
+}
+
+// projectedVolumes mounts the function and library config maps through one projected volume per mount, the config maps
+// sharing a mount are merged into its volume, every config map is projected as a whole, so its keys are laid out as with
+// config map volumes and new keys never change the pod template, the function config maps sharing a mount would collide
+// on their manifests
+func (r *Runtime) projectedVolumes() ([]apiv1.Volume, []apiv1.VolumeMount, error) {
+	var (
+		volumes   []apiv1.Volume
+		mounts    []apiv1.VolumeMount
+		indexes   = make(map[string]int)
+		manifests = make(map[string]string)
+	)
+
+	for _, fn := range sortedRuntimeConfigMaps(r.Status.Functions) {
+		mount := path.Clean(fn.Mount)
+		if other, ok := manifests[mount]; ok {
+			return nil, nil, fmt.Errorf("function config maps %q and %q share mount %q, their manifests would collide", other, fn.Name, fn.Mount)
+		}
+		manifests[mount] = fn.Name
+	}
+
+	runtimeConfigMaps := append(sortedRuntimeConfigMaps(r.Status.Functions), sortedRuntimeConfigMaps(r.Status.Libraries)...)
+	for _, cm := range runtimeConfigMaps {
+		mount := path.Clean(cm.Mount)
+		i, ok := indexes[mount]
+		if !ok {
+			i = len(volumes)
+			indexes[mount] = i
+			volumes = append(volumes, apiv1.Volume{
+				Name: cm.Name,
+				VolumeSource: apiv1.VolumeSource{
+					Projected: &apiv1.ProjectedVolumeSource{},
+				},
+			})
+			mounts = append(mounts, apiv1.VolumeMount{
+				Name:      cm.Name,
+				MountPath: cm.Mount,
+			})
+		}
+		volumes[i].Projected.Sources = append(volumes[i].Projected.Sources, configMapProjection(cm.Name, nil))
+		volumes[i].Projected.Sources = append(volumes[i].Projected.Sources, secretProjections(cm)...)
+	}
+
+	return volumes, mounts, nil
+}
+
+// checkRestartStrategy fails unless runtime pods are rolled on content changes, which the chunked volume
+// assembled at pod start needs to pick up new content
+func (r *Runtime) checkRestartStrategy() error {
+	if strategy := r.Spec.UpdateStrategy.Type; strategy != RestartRuntimeUpdateStrategyType && strategy != "" {
+		return fmt.Errorf("spec.volume.type %s needs the %s update strategy, got %s", r.Spec.Volume.Type, RestartRuntimeUpdateStrategyType, strategy)
+	}
+	return nil
+}
+
+// checkNotChunked fails when a mounted config map is chunked, which only the Chunked volume reassembles
+func (r *Runtime) checkNotChunked() error {
+	runtimeConfigMaps := append(sortedRuntimeConfigMaps(r.Status.Functions), sortedRuntimeConfigMaps(r.Status.Libraries)...)
+	for _, cm := range runtimeConfigMaps {
+		if len(cm.Chunks) > 0 {
+			return fmt.Errorf("config map %q is chunked, spec.volume.type must be %s to mount it", cm.Name, ChunkedRuntimeVolumeType)
+		}
+	}
+	return nil
+}
+
+// chunkedVolumes mounts every function and library config map as its own volume of the assembler container and projects
+// their chunks into one volume, the assembler writes every config map into its own directory of an empty dir at pod
+// start, the runtime container mounts each directory at the mount of its config map, so mount paths are the same as
+// with config map volumes, new content is only picked up by new pods, hence the Restart strategy
+func (r *Runtime) chunkedVolumes() ([]apiv1.Volume, []apiv1.VolumeMount, error) {
+	var (
+		volumes []apiv1.Volume
+		sources []apiv1.VolumeProjection
+		mounts  []apiv1.VolumeMount
+	)
+
+	runtimeConfigMaps := append(sortedRuntimeConfigMaps(r.Status.Functions), sortedRuntimeConfigMaps(r.Status.Libraries)...)
+	for _, cm := range runtimeConfigMaps {
+		volumes = append(volumes, configMapVolume(cm))
+		// Every chunk holds its content under the same key, so each one is laid out under its own directory
+		for _, chunk := range cm.Chunks {
+			sources = append(sources, configMapProjection(chunk, []apiv1.KeyToPath{{Key: ChunkKey, Path: path.Join(chunk, ChunkKey)}}))
+		}
+		mounts = append(mounts, apiv1.VolumeMount{
+			Name:      DefaultVolumeName,
+			MountPath: cm.Mount,
+			SubPath:   cm.Name,
+			ReadOnly:  true,
+		})
+	}
+
+	volumes = append(volumes,
+		apiv1.Volume{
+			Name: DefaultChunksVolumeName,
+			VolumeSource: apiv1.VolumeSource{
+				Projected: &apiv1.ProjectedVolumeSource{
+					Sources: sources,
+				},
+			},
+		},
+		apiv1.Volume{
+			Name: DefaultVolumeName,
+			VolumeSource: apiv1.VolumeSource{
+				EmptyDir: &apiv1.EmptyDirVolumeSource{},
+			},
+		},
+	)
+
+	return volumes, mounts, nil
+}
+
+// AssemblerContainer renders the init container reassembling the chunked config maps into the empty dir
+func (r *Runtime) AssemblerContainer() apiv1.Container {
+	root := "/kess-chunks"
+	var mounts []apiv1.VolumeMount
+	runtimeConfigMaps := append(sortedRuntimeConfigMaps(r.Status.Functions), sortedRuntimeConfigMaps(r.Status.Libraries)...)
+	for _, cm := range runtimeConfigMaps {
+		mounts = append(mounts, apiv1.VolumeMount{
+			Name:      cm.Name,
+			MountPath: path.Join(root, "configmaps", cm.Name),
+			ReadOnly:  true,
+		})
+	}
+	mounts = append(mounts,
+		apiv1.VolumeMount{
+			Name:      DefaultChunksVolumeName,
+			MountPath: path.Join(root, "chunks"),
+			ReadOnly:  true,
+		},
+		apiv1.VolumeMount{
+			Name:      DefaultVolumeName,
+			MountPath: path.Join(root, "assembled"),
+		},
+	)
+
+	return apiv1.Container{
+		Name:    "kess-assembler",
+		Image:   r.Spec.Volume.Image,
+		Command: []string{"/assembler"},
+		Args: []string{
+			"--configmaps=" + path.Join(root, "configmaps"),
+			"--chunks=" + path.Join(root, "chunks"),
+			"--out=" + path.Join(root, "assembled"),
+		},
+		VolumeMounts: mounts,
+	}
+}
+
+// configMapVolume renders the volume of a mounted config map, a projected one when secrets are projected next to its keys
+func configMapVolume(cm RuntimeConfigMap) apiv1.Volume {
+	if len(cm.Secrets) > 0 {
+		return apiv1.Volume{
+			Name: cm.Name,
+			VolumeSource: apiv1.VolumeSource{
+				Projected: &apiv1.ProjectedVolumeSource{
+					Sources: append([]apiv1.VolumeProjection{configMapProjection(cm.Name, nil)}, secretProjections(cm)...),
+				},
+			},
+		}
+	}
+	return apiv1.Volume{
+		Name: cm.Name,
+		VolumeSource: apiv1.VolumeSource{
+			ConfigMap: &apiv1.ConfigMapVolumeSource{
+				LocalObjectReference: apiv1.LocalObjectReference{
+					Name: cm.Name,
+				},
+			},
+		},
+	}
+}
+
+// secretProjections returns the secrets projected next to the keys of a mounted config map
+func secretProjections(cm RuntimeConfigMap) []apiv1.VolumeProjection {
+	var sources []apiv1.VolumeProjection
+	for _, secret := range cm.Secrets {
+		sources = append(sources, apiv1.VolumeProjection{
+			Secret: &apiv1.SecretProjection{
+				LocalObjectReference: apiv1.LocalObjectReference{
+					Name: secret.Name,
+				},
+				Items:    secret.Items,
+				Optional: secret.Optional,
+			},
+		})
+	}
+	return sources
+}
 
-	return deployment
+func configMapProjection(configMap string, items []apiv1.KeyToPath) apiv1.VolumeProjection {
+	return apiv1.VolumeProjection{
+		ConfigMap: &apiv1.ConfigMapProjection{
+			LocalObjectReference: apiv1.LocalObjectReference{
+				Name: configMap,
+			},
+			Items: items,
+		},
+	}
 }
 
 // UpdateDeployment bulabula
-func (r *Runtime) UpdateDeployment(out *appsv1.Deployment) {
-	in := r.Deployment()
+func (r *Runtime) UpdateDeployment(out *appsv1.Deployment) error {
+	in, err := r.Deployment()
+	if err != nil {
+		return err
+	}
 	out.ObjectMeta = in.ObjectMeta
 	out.Spec = in.Spec
+	return nil
+}
+
+// MergePodTemplate strategically merges spec.template over the generated pod template,
+// containers without a name in spec.template are merged into the runtime container
+func (r *Runtime) MergePodTemplate(generated apiv1.PodTemplateSpec) (apiv1.PodTemplateSpec, error) {
+	if r.Spec.Template == nil {
+		return generated, nil
+	}
+
+	custom := r.Spec.Template.DeepCopy()
+	for i := range custom.Spec.Containers {
+		if custom.Spec.Containers[i].Name == "" {
+			custom.Spec.Containers[i].Name = r.Name
+		}
+	}
+
+	original, err := json.Marshal(generated)
+	if err != nil {
+		return generated, err
+	}
+	patch, err := utilsjson.MarshalWithoutNulls(custom)
+	if err != nil {
+		return generated, err
+	}
+	merged, err := strategicpatch.StrategicMergePatch(original, patch, apiv1.PodTemplateSpec{})
+	if err != nil {
+		return generated, fmt.Errorf("unable to merge spec.template: %v", err)
+	}
+
+	var template apiv1.PodTemplateSpec
+	if err := json.Unmarshal(merged, &template); err != nil {
+		return generated, err
+	}
+
+	// The selector labels must survive the merge
+	if template.Labels == nil {
+		template.Labels = make(map[string]string)
+	}
+	for k, v := range r.Labels() {
+		template.Labels[k] = v
+	}
+
+	return template, nil
 }
 
 // Service bulabula
@@ -193,6 +589,11 @@ func (r *Runtime) Service() apiv1.Service {
 		Protocol:   apiv1.ProtocolTCP,
 	}
 
+	selector := labels
+	if r.Spec.ScaleToZero != nil {
+		selector = r.ActivatorLabels()
+	}
+
 	service := apiv1.Service{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Service",
@@ -204,7 +605,7 @@ func (r *Runtime) Service() apiv1.Service {
 			Labels:    labels,
 		},
 		Spec: apiv1.ServiceSpec{
-			Selector:  labels,
+			Selector:  selector,
 			ClusterIP: r.Spec.ClusterIP,
 			Ports:     []apiv1.ServicePort{port},
 		},
@@ -213,6 +614,117 @@ func (r *Runtime) Service() apiv1.Service {
 	return service
 }
 
+// PrivateService bulabula
+func (r *Runtime) PrivateService() apiv1.Service {
+	var (
+		labels         = r.Labels()
+		namespacedName = r.PrivateServiceNamespacedName()
+	)
+
+	port := apiv1.ServicePort{
+		Name:       r.Spec.PortName,
+		Port:       r.Spec.Port,
+		TargetPort: intstr.FromInt(int(r.Spec.Port)),
+		Protocol:   apiv1.ProtocolTCP,
+	}
+
+	return apiv1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      namespacedName.Name,
+			Namespace: namespacedName.Namespace,
+			Labels:    labels,
+		},
+		Spec: apiv1.ServiceSpec{
+			Selector: labels,
+			Ports:    []apiv1.ServicePort{port},
+		},
+	}
+}
+
+// ActivatorDeployment renders the activator sitting behind the runtime service, it listens on the runtime port
+// and proxies to the private service, reporting requests to activityURL so that runtime is scaled up from zero
+func (r *Runtime) ActivatorDeployment(image, activityURL string) appsv1.Deployment {
+	var (
+		labels         = r.ActivatorLabels()
+		namespacedName = r.ActivatorNamespacedName()
+		privateService = r.PrivateServiceNamespacedName()
+		replicas       = int32(1)
+	)
+
+	port := apiv1.ContainerPort{
+		Name:          r.Spec.PortName,
+		ContainerPort: r.Spec.Port,
+		Protocol:      apiv1.ProtocolTCP,
+	}
+
+	container := apiv1.Container{
+		Name:    TypeActivator,
+		Image:   image,
+		Command: []string{"/activator"},
+		Args: []string{
+			fmt.Sprintf("--addr=:%d", r.Spec.Port),
+			fmt.Sprintf("--backend=http://%s.%s.svc:%d", privateService.Name, privateService.Namespace, r.Spec.Port),
+			"--runtime-name=" + r.Name,
+			"--runtime-namespace=" + r.Namespace,
+			"--activity-url=" + activityURL,
+			"--activation-timeout=" + r.Spec.ScaleToZero.ActivationTimeout.Duration.String(),
+		},
+		Ports: []apiv1.ContainerPort{port},
+		ReadinessProbe: &apiv1.Probe{
+			Handler: apiv1.Handler{
+				TCPSocket: &apiv1.TCPSocketAction{Port: intstr.FromInt(int(r.Spec.Port))},
+			},
+		},
+	}
+
+	return appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Deployment",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      namespacedName.Name,
+			Namespace: namespacedName.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: apiv1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: apiv1.PodSpec{
+					ServiceAccountName: namespacedName.Name,
+					Containers:         []apiv1.Container{container},
+				},
+			},
+			Replicas: &replicas,
+		},
+	}
+}
+
+// ActivatorServiceAccount renders the service account the activator authenticates with
+func (r *Runtime) ActivatorServiceAccount() apiv1.ServiceAccount {
+	namespacedName := r.ActivatorNamespacedName()
+	return apiv1.ServiceAccount{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ServiceAccount",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      namespacedName.Name,
+			Namespace: namespacedName.Namespace,
+			Labels:    r.ActivatorLabels(),
+		},
+	}
+}
+
 // UpdateService bulabula
 func (r *Runtime) UpdateService(out *apiv1.Service) {
 	in := r.Service()
@@ -220,43 +732,159 @@ func (r *Runtime) UpdateService(out *apiv1.Service) {
 	out.Spec = in.Spec
 }
 
-// UpdateStatusFunctions bulabula
-func (r *Runtime) UpdateStatusFunctions(fn *Function) {
-	r.DefaultStatus()
-	runtimeConfigMap := fn.RuntimeConfigMap()
-	r.Status.Functions[runtimeConfigMap.Name] = runtimeConfigMap
+// UpdateStatusConfigMaps recomputes the mounted config maps from the functions and libraries of runtime,
+// the secrets of the functions or libraries sharing a config map are all projected next to it
+func (r *Runtime) UpdateStatusConfigMaps(fns []Function, libs []Library) {
+	r.Status.Functions = make(map[string]RuntimeConfigMap)
+	r.Status.Libraries = make(map[string]RuntimeConfigMap)
+
+	for _, fn := range fns {
+		if fn.Spec.Runtime != r.Name || !fn.DeletionTimestamp.IsZero() {
+			continue
+		}
+		runtimeConfigMap := fn.RuntimeConfigMap()
+		if mounted, ok := r.Status.Functions[runtimeConfigMap.Name]; ok {
+			runtimeConfigMap.Secrets = append(mounted.Secrets, runtimeConfigMap.Secrets...)
+		}
+		r.Status.Functions[runtimeConfigMap.Name] = runtimeConfigMap
+	}
+	for _, lib := range libs {
+		if lib.Spec.Runtime != r.Name || !lib.DeletionTimestamp.IsZero() {
+			continue
+		}
+		runtimeConfigMap := lib.RuntimeConfigMap()
+		if mounted, ok := r.Status.Libraries[runtimeConfigMap.Name]; ok {
+			runtimeConfigMap.Secrets = append(mounted.Secrets, runtimeConfigMap.Secrets...)
+		}
+		r.Status.Libraries[runtimeConfigMap.Name] = runtimeConfigMap
+	}
+
+	// Listing order must not change the pod template
+	for _, runtimeConfigMaps := range []map[string]RuntimeConfigMap{r.Status.Functions, r.Status.Libraries} {
+		for _, runtimeConfigMap := range runtimeConfigMaps {
+			sortRuntimeSecrets(runtimeConfigMap.Secrets)
+		}
+	}
 }
 
-// DeleteStatusFunctions bulabula
-func (r *Runtime) DeleteStatusFunctions(fn *Function) {
-	r.DefaultStatus()
-	if len(r.Status.Functions) == 0 {
-		return
+// SetCondition bulabula
+func (r *Runtime) SetCondition(condition Condition) {
+	condition.ObservedGeneration = r.Generation
+	SetCondition(&r.Status.Conditions, condition)
+}
+
+// ConfigMapNamespacedNames returns the mounted function and library config maps sorted by name
+func (r *Runtime) ConfigMapNamespacedNames() []types.NamespacedName {
+	var names []string
+	for name := range r.Status.Functions {
+		names = append(names, name)
+	}
+	for name := range r.Status.Libraries {
+		if _, ok := r.Status.Functions[name]; !ok {
+			names = append(names, name)
+		}
 	}
-	runtimeConfigMap := fn.RuntimeConfigMap()
-	delete(r.Status.Libraries, runtimeConfigMap.Name)
+	sort.Strings(names)
+
+	namespacedNames := make([]types.NamespacedName, 0, len(names))
+	for _, name := range names {
+		namespacedNames = append(namespacedNames, r.ConfigMapNamespacedName(name))
+	}
+	return namespacedNames
 }
 
-// UpdateStatusLibraries bulabula
-func (r *Runtime) UpdateStatusLibraries(lib *Library) {
-	r.DefaultStatus()
-	runtimeConfigMap := lib.RuntimeConfigMap()
-	r.Status.Libraries[runtimeConfigMap.Name] = runtimeConfigMap
+// UpdateStatusConfigMapsHash stamps the content hash of the mounted config maps and records the one each of them holds,
+// the config maps must be passed in the order of ConfigMapNamespacedNames
+func (r *Runtime) UpdateStatusConfigMapsHash(cms []apiv1.ConfigMap) {
+	hash := sha256.New()
+	for _, cm := range cms {
+		fmt.Fprintf(hash, "%s\n", cm.Name)
+		for _, key := range sortedKeys(cm.Data) {
+			fmt.Fprintf(hash, "%s=%x\n", key, sha256.Sum256([]byte(cm.Data[key])))
+		}
+		binaryKeys := make([]string, 0, len(cm.BinaryData))
+		for key := range cm.BinaryData {
+			binaryKeys = append(binaryKeys, key)
+		}
+		sort.Strings(binaryKeys)
+		for _, key := range binaryKeys {
+			fmt.Fprintf(hash, "%s=%x\n", key, sha256.Sum256(cm.BinaryData[key]))
+		}
+		// The content of projected secrets is not read, their resource versions stand for it
+		for _, runtimeConfigMap := range []RuntimeConfigMap{r.Status.Functions[cm.Name], r.Status.Libraries[cm.Name]} {
+			for _, secret := range runtimeConfigMap.Secrets {
+				fmt.Fprintf(hash, "secret %s@%s\n", secret.Name, secret.ResourceVersion)
+			}
+		}
+
+		contentHash := cm.Data[ContentHashKey(cm.Name)]
+		if runtimeConfigMap, ok := r.Status.Functions[cm.Name]; ok {
+			runtimeConfigMap.ContentHash = contentHash
+			r.Status.Functions[cm.Name] = runtimeConfigMap
+		}
+		if runtimeConfigMap, ok := r.Status.Libraries[cm.Name]; ok {
+			runtimeConfigMap.ContentHash = contentHash
+			r.Status.Libraries[cm.Name] = runtimeConfigMap
+		}
+	}
+	r.Status.ConfigMapsHash = hex.EncodeToString(hash.Sum(nil))[:16]
 }
 
-// DeleteStatusLibraries bulabula
-func (r *Runtime) DeleteStatusLibraries(lib *Library) {
-	r.DefaultStatus()
-	if len(r.Status.Libraries) == 0 {
-		return
+// ContentHashes returns the content hash every mounted config map holds by the path runtime pods read it at,
+// the content of projected secrets is not covered
+func (r *Runtime) ContentHashes() map[string]string {
+	hashes := make(map[string]string)
+	for _, runtimeConfigMaps := range []map[string]RuntimeConfigMap{r.Status.Functions, r.Status.Libraries} {
+		for _, cm := range runtimeConfigMaps {
+			if cm.ContentHash != "" {
+				hashes[path.Join(cm.Mount, ContentHashKey(cm.Name))] = cm.ContentHash
+			}
+		}
+	}
+	return hashes
+}
+
+// UpdateStatusConfigMapChunks records the chunk config maps of the mounted config maps, which the chunked volume projects,
+// the config maps must be passed in the order of ConfigMapNamespacedNames
+func (r *Runtime) UpdateStatusConfigMapChunks(cms []apiv1.ConfigMap) {
+	for _, cm := range cms {
+		chunks := ChunkConfigMapNames(&cm)
+		if runtimeConfigMap, ok := r.Status.Functions[cm.Name]; ok {
+			runtimeConfigMap.Chunks = chunks
+			r.Status.Functions[cm.Name] = runtimeConfigMap
+		}
+		if runtimeConfigMap, ok := r.Status.Libraries[cm.Name]; ok {
+			runtimeConfigMap.Chunks = chunks
+			r.Status.Libraries[cm.Name] = runtimeConfigMap
+		}
 	}
-	runtimeConfigMap := lib.RuntimeConfigMap()
-	delete(r.Status.Libraries, runtimeConfigMap.Name)
 }
 
 // UpdateStatusReady bulabula
 func (r *Runtime) UpdateStatusReady(deploy *appsv1.Deployment) {
 	r.DefaultStatus()
+	if deploy.Name == "" {
+		r.SetCondition(NewCondition(ConditionRolloutComplete, false, ReasonDeploymentNotFound,
+			fmt.Sprintf("deployment %q is not found", r.Name)))
+	} else if message, complete := deploymentRolloutStatus(deploy); complete {
+		r.SetCondition(NewCondition(ConditionRolloutComplete, true, ReasonReconciled, message))
+	} else {
+		r.SetCondition(NewCondition(ConditionRolloutComplete, false, ReasonRolloutInProgress, message))
+	}
+	if rollout := FindCondition(r.Status.Conditions, ConditionRolloutComplete); rollout.Status == metav1.ConditionTrue {
+		r.SetCondition(NewCondition(ConditionReady, true, ReasonReconciled, ""))
+	} else {
+		r.SetCondition(NewCondition(ConditionReady, false, rollout.Reason, rollout.Message))
+	}
+	r.Status.ObservedGeneration = r.Generation
+
+	r.Status.Selector = labels.SelectorFromSet(r.Labels()).String()
+	r.Status.Replicas = deploy.Status.Replicas
+	r.Status.DesiredReplicas = 0
+	if deploy.Spec.Replicas != nil {
+		r.Status.DesiredReplicas = *deploy.Spec.Replicas
+	}
+
 	r.Status.Ready = utilsstrings.Format(r.Spec.ReadyFormat, map[string]interface{}{
 		"Replicas":            strconv.Itoa(int(deploy.Status.Replicas)),
 		"UpdatedReplicas":     strconv.Itoa(int(deploy.Status.UpdatedReplicas)),
@@ -265,3 +893,116 @@ func (r *Runtime) UpdateStatusReady(deploy *appsv1.Deployment) {
 		"UnavailableReplicas": strconv.Itoa(int(deploy.Status.UnavailableReplicas)),
 	})
 }
+
+// UpdateStatusActivity decides whether runtime is idle from the last activity reported by the activator,
+// and returns how long until it becomes idle, zero when it already is or scale-to-zero is disabled
+func (r *Runtime) UpdateStatusActivity(now time.Time) time.Duration {
+	if r.Spec.ScaleToZero == nil {
+		r.Status.LastActivityTime = nil
+		r.Status.ScaledToZero = false
+		return 0
+	}
+
+	// A runtime without any reported request stays up for one idle window after it is created
+	lastActivity := r.CreationTimestamp.Time
+	if value, ok := r.Annotations[AnnotationLastActivity]; ok {
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil && t.After(lastActivity) {
+			lastActivity = t
+		}
+	}
+	r.Status.LastActivityTime = &metav1.Time{Time: lastActivity}
+
+	remaining := r.Spec.ScaleToZero.IdleWindow.Duration - now.Sub(lastActivity)
+	r.Status.ScaledToZero = remaining <= 0
+	if r.Status.ScaledToZero {
+		return 0
+	}
+	return remaining
+}
+
+// ActivityOutdated tells whether lastActivity should be recorded in the last-activity annotation, a runtime
+// scaled to zero takes every newer activity so that it is scaled up right away, otherwise the annotation is
+// only moved forward by at least interval, which bounds how often activators get runtime reconciled
+func (r *Runtime) ActivityOutdated(lastActivity time.Time, interval time.Duration) bool {
+	value, ok := r.Annotations[AnnotationLastActivity]
+	if !ok {
+		return true
+	}
+	recorded, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return true
+	}
+	if r.Status.ScaledToZero {
+		return lastActivity.After(recorded)
+	}
+	return lastActivity.After(recorded) && !lastActivity.Before(recorded.Add(interval))
+}
+
+// UpdateStatusAutoscaling reports the replicas last desired by the autoscaler, hpa is empty when not found
+func (r *Runtime) UpdateStatusAutoscaling(hpa *autoscalingv2beta2.HorizontalPodAutoscaler) {
+	if r.Spec.Autoscaling == nil || hpa.Name == "" {
+		return
+	}
+	r.Status.DesiredReplicas = hpa.Status.DesiredReplicas
+}
+
+// deploymentRolloutStatus reports whether the latest deployment spec is fully rolled out, the same way kubectl rollout status does
+func deploymentRolloutStatus(deploy *appsv1.Deployment) (string, bool) {
+	if deploy.Generation > deploy.Status.ObservedGeneration {
+		return "waiting for deployment spec update to be observed", false
+	}
+	replicas := int32(1)
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+	if deploy.Status.UpdatedReplicas < replicas {
+		return fmt.Sprintf("%d out of %d new replicas have been updated", deploy.Status.UpdatedReplicas, replicas), false
+	}
+	if deploy.Status.Replicas > deploy.Status.UpdatedReplicas {
+		return fmt.Sprintf("%d old replicas are pending termination", deploy.Status.Replicas-deploy.Status.UpdatedReplicas), false
+	}
+	if deploy.Status.AvailableReplicas < deploy.Status.UpdatedReplicas {
+		return fmt.Sprintf("%d of %d updated replicas are available", deploy.Status.AvailableReplicas, deploy.Status.UpdatedReplicas), false
+	}
+	return fmt.Sprintf("%d replicas are available", deploy.Status.AvailableReplicas), true
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortRuntimeSecrets sorts secrets by name and then by the path of their first item
+func sortRuntimeSecrets(secrets []RuntimeSecret) {
+	firstPath := func(secret RuntimeSecret) string {
+		if len(secret.Items) == 0 {
+			return ""
+		}
+		return secret.Items[0].Path
+	}
+	sort.SliceStable(secrets, func(i, j int) bool {
+		if secrets[i].Name != secrets[j].Name {
+			return secrets[i].Name < secrets[j].Name
+		}
+		return firstPath(secrets[i]) < firstPath(secrets[j])
+	})
+}
+
+// sortedRuntimeConfigMaps returns the config maps sorted by name, so that rendering does not depend on map order
+func sortedRuntimeConfigMaps(m map[string]RuntimeConfigMap) []RuntimeConfigMap {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	runtimeConfigMaps := make([]RuntimeConfigMap, 0, len(names))
+	for _, name := range names {
+		runtimeConfigMaps = append(runtimeConfigMaps, m[name])
+	}
+	return runtimeConfigMaps
+}
@@ -0,0 +1,177 @@
+package v1
+
+import (
+	"path"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+func TestRuntimePorts(t *testing.T) {
+	rt := goldenRuntime()
+	if err := rt.validate(); err != nil {
+		t.Fatalf("runtime must be valid, got %v", err)
+	}
+
+	rt.Spec.Template = &apiv1.PodTemplateSpec{
+		Spec: apiv1.PodSpec{
+			Containers: []apiv1.Container{{
+				Ports: []apiv1.ContainerPort{{Name: "metrics", ContainerPort: 9090}},
+			}},
+		},
+	}
+	if err := rt.validate(); err != nil {
+		t.Errorf("template may add other ports, got %v", err)
+	}
+
+	rt.Spec.Template.Spec.Containers[0].Ports = []apiv1.ContainerPort{{Name: "web", ContainerPort: 8000}}
+	if err := rt.validate(); err == nil {
+		t.Error("template must not rename the port the service targets")
+	}
+
+	rt.Spec.Template.Spec.Containers[0].Ports = []apiv1.ContainerPort{{Name: "http", ContainerPort: 8080}}
+	if err := rt.validate(); err == nil {
+		t.Error("template must not give the port name to another container port")
+	}
+}
+
+func TestRuntimeVolumeUpdateStrategy(t *testing.T) {
+	rt := goldenMountedRuntime(ConfigMapRuntimeVolumeType)
+	rt.Spec.UpdateStrategy.Type = SignalRuntimeUpdateStrategyType
+	for _, volumeType := range []RuntimeVolumeType{ConfigMapRuntimeVolumeType, ProjectedRuntimeVolumeType} {
+		rt.Spec.Volume.Type = volumeType
+		if _, err := rt.Deployment(); err != nil {
+			t.Errorf("%s volume must be updated in place, got %v", volumeType, err)
+		}
+		if err := rt.validate(); err != nil {
+			t.Errorf("%s volume must not need the Restart strategy, got %v", volumeType, err)
+		}
+	}
+
+	rt.Spec.Volume.Type = ChunkedRuntimeVolumeType
+	rt.Spec.UpdateStrategy.Type = NoneRuntimeUpdateStrategyType
+	if _, err := rt.Deployment(); err == nil {
+		t.Error("chunked volume must not be rendered without the Restart strategy")
+	}
+	if err := rt.validate(); err == nil {
+		t.Error("chunked volume must need the Restart strategy")
+	}
+}
+
+func TestRuntimeProjectedVolume(t *testing.T) {
+	rt := goldenMountedRuntime(ProjectedRuntimeVolumeType)
+	util := rt.Status.Libraries["lib-util-v1"]
+	util.Mount = rt.Status.Libraries["lib-base-v1"].Mount
+	rt.Status.Libraries["lib-util-v1"] = util
+	deploy, err := rt.Deployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	volumes := deploy.Spec.Template.Spec.Volumes
+	if len(volumes) != 4 {
+		t.Fatalf("config maps sharing a mount must share a volume, got %d volumes", len(volumes))
+	}
+	if sources := volumes[3].Projected.Sources; len(sources) != 2 || sources[0].ConfigMap.Items != nil || sources[1].ConfigMap.Items != nil {
+		t.Errorf("config maps must be projected as a whole into the volume of their mount, got %v", sources)
+	}
+
+	other := rt.Status.Functions["fn-other"]
+	other.Mount = rt.Status.Functions["fn-sample"].Mount
+	rt.Status.Functions["fn-other"] = other
+	if _, err := rt.Deployment(); err == nil {
+		t.Error("function config maps sharing a mount must not be projected into one volume")
+	}
+}
+
+func TestRuntimeSecretVolumes(t *testing.T) {
+	rt := goldenMountedRuntime(ConfigMapRuntimeVolumeType)
+	sample := goldenFunction("sample-v3")
+	sample.Spec.Data = ""
+	sample.Spec.Source = &FunctionSource{SecretKeyRef: &apiv1.SecretKeySelector{
+		LocalObjectReference: apiv1.LocalObjectReference{Name: "sample-token"},
+		Key:                  "handler.py",
+	}}
+	sample.Status.Reference = &ReferenceStatus{Kind: ReferenceKindSecret, Name: "sample-token", ResourceVersion: "1"}
+	rt.UpdateStatusConfigMaps([]Function{*goldenFunction("sample-v1"), *sample}, nil)
+	cms := []apiv1.ConfigMap{goldenFunction("sample-v1").ConfigMap()}
+	rt.UpdateStatusConfigMapsHash(cms)
+	hash := rt.Status.ConfigMapsHash
+
+	for _, volumeType := range []RuntimeVolumeType{ConfigMapRuntimeVolumeType, ProjectedRuntimeVolumeType} {
+		rt.Spec.Volume.Type = volumeType
+		deploy, err := rt.Deployment()
+		if err != nil {
+			t.Fatal(err)
+		}
+		volumes := deploy.Spec.Template.Spec.Volumes
+		if len(volumes) != 1 || volumes[0].Projected == nil || len(volumes[0].Projected.Sources) != 2 {
+			t.Fatalf("secret must be projected next to its config map by the %s volume, got %+v", volumeType, volumes)
+		}
+		secret := volumes[0].Projected.Sources[1].Secret
+		if secret == nil || secret.Name != "sample-token" || len(secret.Items) != 1 || secret.Items[0].Path != "v3.py" {
+			t.Errorf("secret key must be projected at the file of function, got %+v", secret)
+		}
+	}
+
+	sample.Status.Reference.ResourceVersion = "2"
+	rt.UpdateStatusConfigMaps([]Function{*goldenFunction("sample-v1"), *sample}, nil)
+	rt.UpdateStatusConfigMapsHash(cms)
+	if rt.Status.ConfigMapsHash == hash {
+		t.Error("a changed secret must change the config maps hash")
+	}
+}
+
+func TestRuntimeContentHashes(t *testing.T) {
+	rt := goldenRuntime()
+	v1, v2 := goldenFunction("sample-v1"), goldenFunction("sample-v2")
+	rt.UpdateStatusConfigMaps([]Function{*v1, *v2}, nil)
+	cm := v1.ConfigMap()
+	rt.UpdateStatusConfigMapsHash([]apiv1.ConfigMap{cm})
+
+	mounted := rt.Status.Functions[cm.Name]
+	hash := cm.Data[ContentHashKey(cm.Name)]
+	if hash == "" || hash != ConfigMapContentHash(&cm) {
+		t.Fatalf("config map must hold its content hash, got %q", hash)
+	}
+	hashes := rt.ContentHashes()
+	if len(hashes) != 1 || hashes[path.Join(mounted.Mount, ContentHashKey(cm.Name))] != hash {
+		t.Errorf("content hash must be read at its key under the mount, got %v", hashes)
+	}
+
+	v2.SetConfigMap(&cm)
+	if cm.Data[ContentHashKey(cm.Name)] == hash {
+		t.Error("a function added to config map must change its content hash")
+	}
+	v2.UnsetConfigMap(&cm)
+	if cm.Data[ContentHashKey(cm.Name)] != hash {
+		t.Error("content hash must only depend on the content of config map")
+	}
+	v1.UnsetConfigMap(&cm)
+	if len(cm.Data) != 0 {
+		t.Errorf("content hash must be dropped with the last function, got keys %v", sortedKeys(cm.Data))
+	}
+}
+
+func TestRuntimeActivityOutdated(t *testing.T) {
+	rt := goldenScaleToZeroRuntime()
+	recorded := rt.CreationTimestamp.Add(time.Minute)
+	if !rt.ActivityOutdated(recorded, 30*time.Second) {
+		t.Fatal("first activity must be recorded")
+	}
+	rt.Annotations = map[string]string{AnnotationLastActivity: recorded.Format(time.RFC3339Nano)}
+
+	if rt.ActivityOutdated(recorded.Add(10*time.Second), 30*time.Second) {
+		t.Error("activity within the interval must not be recorded while runtime is running")
+	}
+	if !rt.ActivityOutdated(recorded.Add(30*time.Second), 30*time.Second) {
+		t.Error("activity after the interval must be recorded")
+	}
+	rt.Status.ScaledToZero = true
+	if !rt.ActivityOutdated(recorded.Add(time.Second), 30*time.Second) {
+		t.Error("any newer activity must scale runtime up from zero")
+	}
+	if rt.ActivityOutdated(recorded, 30*time.Second) {
+		t.Error("recorded activity must not be recorded again")
+	}
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package v1
 
 import (
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -25,8 +27,121 @@ import (
 
 // RuntimeConfigMap bulabula
 type RuntimeConfigMap struct {
-	Name  string `json:"name,omitempty"`
-	Mount string `json:"mount,omitempty"`
+	Name    string          `json:"name,omitempty"`
+	Mount   string          `json:"mount,omitempty"`
+	Chunks  []string        `json:"chunks,omitempty"`
+	Secrets []RuntimeSecret `json:"secrets,omitempty"`
+	// The content hash config map holds, which signaled runtime pods must read from their mount
+	ContentHash string `json:"contentHash,omitempty"`
+}
+
+// RuntimeSecret is a secret projected next to the keys of a mounted config map, referenced secret content is mounted
+// from the secret itself and never copied into a config map
+type RuntimeSecret struct {
+	Name string `json:"name,omitempty"`
+	// The resource version of secret last read by the function or library, which is part of the config maps hash
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+	// The keys of secret and their paths relative to the mount, every key at its own path if empty
+	Items    []apiv1.KeyToPath `json:"items,omitempty"`
+	Optional *bool             `json:"optional,omitempty"`
+}
+
+// RuntimeUpdateStrategyType bulabula
+// +kubebuilder:validation:Enum=Restart;Signal;None
+type RuntimeUpdateStrategyType string
+
+// RuntimeUpdateStrategyType Constants bulabula
+const (
+	// Roll the runtime pods when the mounted content changes
+	RestartRuntimeUpdateStrategyType RuntimeUpdateStrategyType = "Restart"
+	// POST the reload endpoint of each runtime pod when the mounted content changes
+	SignalRuntimeUpdateStrategyType RuntimeUpdateStrategyType = "Signal"
+	// Leave the runtime pods alone when the mounted content changes
+	NoneRuntimeUpdateStrategyType RuntimeUpdateStrategyType = "None"
+)
+
+// RuntimeUpdateStrategy bulabula
+type RuntimeUpdateStrategy struct {
+	// The way runtime pods pick up changed function or library content
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=Restart
+	Type RuntimeUpdateStrategyType `json:"type,omitempty"`
+
+	// The reload endpoint path on spec.port used by the Signal strategy, it is posted the config maps hash and
+	// the content hash every mounted file must hold, and answers 409 Conflict until the files of pod hold them
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="/-/reload"
+	Path string `json:"path,omitempty"`
+}
+
+// RuntimeVolumeType bulabula
+// +kubebuilder:validation:Enum=ConfigMap;Projected;Chunked
+type RuntimeVolumeType string
+
+// RuntimeVolumeType Constants bulabula
+const (
+	// Mount every function and library config map as its own volume
+	ConfigMapRuntimeVolumeType RuntimeVolumeType = "ConfigMap"
+	// Mount function and library config maps through one projected volume per mount, merging the ones sharing a mount
+	ProjectedRuntimeVolumeType RuntimeVolumeType = "Projected"
+	// Reassemble every function and library config map, chunked ones included, into an empty dir by an init container,
+	// which needs the Restart strategy
+	ChunkedRuntimeVolumeType RuntimeVolumeType = "Chunked"
+)
+
+// RuntimeVolume bulabula
+type RuntimeVolume struct {
+	// The way function and library config maps are mounted
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=ConfigMap
+	Type RuntimeVolumeType `json:"type,omitempty"`
+
+	// The image of the init container reassembling chunked config maps, which must provide /assembler
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="yamajik/kess:latest"
+	Image string `json:"image,omitempty"`
+}
+
+// RuntimeAutoscaling bulabula
+type RuntimeAutoscaling struct {
+	// The lower limit of replicas of runtime
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// The upper limit of replicas of runtime
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// Optional target average CPU utilization over all runtime pods
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+
+	// Optional target average memory utilization over all runtime pods
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	TargetMemoryUtilizationPercentage *int32 `json:"targetMemoryUtilizationPercentage,omitempty"`
+
+	// Optional custom metric targets appended to the CPU and memory targets
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Metrics []autoscalingv2beta2.MetricSpec `json:"metrics,omitempty"`
+}
+
+// RuntimeScaleToZero bulabula
+type RuntimeScaleToZero struct {
+	// How long runtime may receive no request before it is scaled to zero
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="15m"
+	IdleWindow metav1.Duration `json:"idleWindow,omitempty"`
+
+	// How long the activator buffers a request while runtime is scaled up from zero
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="2m"
+	ActivationTimeout metav1.Duration `json:"activationTimeout,omitempty"`
 }
 
 // RuntimeSpec defines the desired state of Runtime
@@ -60,6 +175,14 @@ type RuntimeSpec struct {
 	// +kubebuilder:default=1
 	Replicas *int32 `json:"replicas,omitempty"`
 
+	// Optional autoscaling of runtime, spec.replicas is left to the autoscaler when set
+	// +kubebuilder:validation:Optional
+	Autoscaling *RuntimeAutoscaling `json:"autoscaling,omitempty"`
+
+	// Optional scale-to-zero policy of runtime, requests are routed through an activator when set
+	// +kubebuilder:validation:Optional
+	ScaleToZero *RuntimeScaleToZero `json:"scaleToZero,omitempty"`
+
 	// Optional cluster IP spec of runtime
 	// +kubebuilder:validation:Optional
 	ClusterIP string `json:"clusterIP,omitempty"`
@@ -68,6 +191,20 @@ type RuntimeSpec struct {
 	// +kubebuilder:validation:Optional
 	// +kubebuilder:default="{AvailableReplicas}/{AvailableReplicas}"
 	ReadyFormat string `json:"readyFormat,omitempty"`
+
+	// Optional volume layout of function and library config maps
+	// +kubebuilder:validation:Optional
+	Volume RuntimeVolume `json:"volume,omitempty"`
+
+	// Optional update strategy of runtime pods on mounted content changes
+	// +kubebuilder:validation:Optional
+	UpdateStrategy RuntimeUpdateStrategy `json:"updateStrategy,omitempty"`
+
+	// Optional pod template strategically merged over the generated one,
+	// a container without name is merged into the runtime container
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Template *apiv1.PodTemplateSpec `json:"template,omitempty"`
 }
 
 // RuntimeStatus defines the observed state of Runtime
@@ -86,6 +223,44 @@ type RuntimeStatus struct {
 	// Optional ready string of runtime for show
 	// +kubebuilder:validation:Optional
 	Ready string `json:"ready,omitempty"`
+
+	// Current number of replicas of runtime
+	// +kubebuilder:validation:Optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Desired number of replicas of runtime, as last calculated by the autoscaler if any
+	// +kubebuilder:validation:Optional
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
+
+	// Label selector of runtime pods for the scale subresource
+	// +kubebuilder:validation:Optional
+	Selector string `json:"selector,omitempty"`
+
+	// Last time a request to runtime was reported by the activator
+	// +kubebuilder:validation:Optional
+	LastActivityTime *metav1.Time `json:"lastActivityTime,omitempty"`
+
+	// Whether runtime is scaled to zero for being idle
+	// +kubebuilder:validation:Optional
+	ScaledToZero bool `json:"scaledToZero,omitempty"`
+
+	// Optional content hash of the mounted config maps
+	// +kubebuilder:validation:Optional
+	ConfigMapsHash string `json:"configMapsHash,omitempty"`
+
+	// Optional content hash last signaled to the runtime pods
+	// +kubebuilder:validation:Optional
+	SignaledHash string `json:"signaledHash,omitempty"`
+
+	// The generation observed by the runtime controller
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Optional conditions of runtime
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:resource:categories="kess",shortName="rt"
@@ -0,0 +1,143 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var runtimelog = logf.Log.WithName("runtime-resource")
+
+// SetupWebhookWithManager bulabula
+func (r *Runtime) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-core-kess-io-v1-runtime,mutating=true,failurePolicy=fail,groups=core.kess.io,resources=runtimes,verbs=create;update,versions=v1,name=mruntime.kb.io
+
+var _ webhook.Defaulter = &Runtime{}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-core-kess-io-v1-runtime,mutating=false,failurePolicy=fail,groups=core.kess.io,resources=runtimes,versions=v1,name=vruntime.kb.io
+
+var _ webhook.Validator = &Runtime{}
+
+// ValidateCreate bulabula
+func (r *Runtime) ValidateCreate() error {
+	runtimelog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate bulabula
+func (r *Runtime) ValidateUpdate(old runtime.Object) error {
+	runtimelog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateDelete bulabula
+func (r *Runtime) ValidateDelete() error {
+	return nil
+}
+
+func (r *Runtime) validate() error {
+	var (
+		allErrs  field.ErrorList
+		specPath = field.NewPath("spec")
+	)
+
+	for _, msg := range validation.IsDNS1123Label(r.Name) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("metadata", "name"), r.Name, "runtime name is used as container name: "+msg))
+	}
+	if r.Spec.Image == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("image"), "runtime must specify a container image"))
+	}
+	for _, msg := range validation.IsValidPortNum(int(r.Spec.Port)) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("port"), r.Spec.Port, msg))
+	}
+	for _, msg := range validation.IsValidPortName(r.Spec.PortName) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("portName"), r.Spec.PortName, msg))
+	}
+	if r.Spec.Volume.Type == ChunkedRuntimeVolumeType && r.checkRestartStrategy() != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("updateStrategy", "type"), r.Spec.UpdateStrategy.Type, "the chunked volume is assembled at pod start, content changes need the Restart strategy"))
+	}
+	if autoscaling := r.Spec.Autoscaling; autoscaling != nil {
+		autoscalingPath := specPath.Child("autoscaling")
+		if autoscaling.MinReplicas != nil && *autoscaling.MinReplicas > autoscaling.MaxReplicas {
+			allErrs = append(allErrs, field.Invalid(autoscalingPath.Child("maxReplicas"), autoscaling.MaxReplicas, "must be greater than or equal to minReplicas"))
+		}
+	}
+	if scaleToZero := r.Spec.ScaleToZero; scaleToZero != nil {
+		scaleToZeroPath := specPath.Child("scaleToZero")
+		if scaleToZero.IdleWindow.Duration < 0 {
+			allErrs = append(allErrs, field.Invalid(scaleToZeroPath.Child("idleWindow"), scaleToZero.IdleWindow.Duration.String(), "must not be negative"))
+		}
+		if scaleToZero.ActivationTimeout.Duration < 0 {
+			allErrs = append(allErrs, field.Invalid(scaleToZeroPath.Child("activationTimeout"), scaleToZero.ActivationTimeout.Duration.String(), "must not be negative"))
+		}
+	}
+	template, err := r.MergePodTemplate(apiv1.PodTemplateSpec{
+		Spec: apiv1.PodSpec{
+			Containers: []apiv1.Container{{
+				Name:  r.Name,
+				Ports: []apiv1.ContainerPort{{Name: r.Spec.PortName, ContainerPort: r.Spec.Port, Protocol: apiv1.ProtocolTCP}},
+			}},
+		},
+	})
+	if err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("template"), "", err.Error()))
+	} else {
+		allErrs = append(allErrs, r.validatePorts(specPath, template)...)
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Runtime").GroupKind(), r.Name, allErrs)
+}
+
+// validatePorts checks that the port named portName of the runtime container is the port the service targets,
+// spec.template may rename the port or give the name to another one
+func (r *Runtime) validatePorts(specPath *field.Path, template apiv1.PodTemplateSpec) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, container := range template.Spec.Containers {
+		if container.Name != r.Name {
+			continue
+		}
+		for _, port := range container.Ports {
+			switch {
+			case port.Name == r.Spec.PortName && port.ContainerPort != r.Spec.Port:
+				allErrs = append(allErrs, field.Invalid(specPath.Child("portName"), r.Spec.PortName,
+					fmt.Sprintf("names container port %d, but the service targets port %d", port.ContainerPort, r.Spec.Port)))
+			case port.ContainerPort == r.Spec.Port && port.Name != r.Spec.PortName:
+				allErrs = append(allErrs, field.Invalid(specPath.Child("port"), r.Spec.Port,
+					fmt.Sprintf("is named %q in the container, but the service port is named %q", port.Name, r.Spec.PortName)))
+			}
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,154 @@
+package v1
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ArchiveSource is a remote tar.gz archive verified by its sha256
+type ArchiveSource struct {
+	// The http or https url of archive, which may use the {Name} and {Version} templates
+	// +kubebuilder:validation:Required
+	URL string `json:"url"`
+
+	// The hex encoded sha256 of archive
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[0-9a-fA-F]{64}$`
+	SHA256 string `json:"sha256"`
+
+	// Optional path in archive, the file or the directory of files of a function, or the directory of a library
+	// +kubebuilder:validation:Optional
+	Path string `json:"path,omitempty"`
+}
+
+// Reference Kind Constants bulabula
+const (
+	ReferenceKindConfigMap = "ConfigMap"
+	ReferenceKindSecret    = "Secret"
+)
+
+// ContentKey selects a key of the referenced config map or secret
+type ContentKey struct {
+	// The key in config map or secret
+	// +kubebuilder:validation:Required
+	Key string `json:"key"`
+
+	// Optional key in the config map of library, the same key if empty
+	// +kubebuilder:validation:Optional
+	Path string `json:"path,omitempty"`
+}
+
+// ContentReference references an existing config map or secret in the namespace, every key of it is
+// content unless items select some
+type ContentReference struct {
+	// The name of config map or secret
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Optional keys selected
+	// +kubebuilder:validation:Optional
+	Items []ContentKey `json:"items,omitempty"`
+}
+
+// ReferenceStatus is the config map or secret content was last read from
+type ReferenceStatus struct {
+	// The kind of object, ConfigMap or Secret
+	// +kubebuilder:validation:Optional
+	Kind string `json:"kind,omitempty"`
+
+	// The name of object
+	// +kubebuilder:validation:Optional
+	Name string `json:"name,omitempty"`
+
+	// The resource version of object content was read at, the generated config map is synced again once it changes,
+	// and the runtime pods mounting a secret are rolled or signaled
+	// +kubebuilder:validation:Optional
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// referenceChanged tells whether object is the one referenced and was updated since content was last read
+func referenceChanged(ref, read *ReferenceStatus, kind, name, resourceVersion string) bool {
+	if ref == nil || ref.Kind != kind || ref.Name != name {
+		return false
+	}
+	return read == nil || read.Kind != kind || read.Name != name || read.ResourceVersion != resourceVersion
+}
+
+// configMapData returns the content of a config map by key
+func configMapData(cm *apiv1.ConfigMap) map[string][]byte {
+	data := make(map[string][]byte)
+	for key, value := range cm.Data {
+		data[key] = []byte(value)
+	}
+	for key, value := range cm.BinaryData {
+		data[key] = value
+	}
+	return data
+}
+
+// splitData keeps the utf-8 files as data and the others as binary data
+func splitData(files map[string][]byte) (map[string]string, map[string][]byte) {
+	var (
+		data       map[string]string
+		binaryData map[string][]byte
+	)
+	for name, content := range files {
+		if utf8.Valid(content) {
+			if data == nil {
+				data = make(map[string]string)
+			}
+			data[name] = string(content)
+		} else {
+			if binaryData == nil {
+				binaryData = make(map[string][]byte)
+			}
+			binaryData[name] = content
+		}
+	}
+	return data, binaryData
+}
+
+// validateArchiveSource checks the url archive renders to, its checksum and its path
+func validateArchiveSource(fldPath *field.Path, archive *ArchiveSource, renderedURL string) field.ErrorList {
+	var allErrs field.ErrorList
+	if u, err := url.Parse(renderedURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), archive.URL, fmt.Sprintf("renders to %q: must be an http or https url", renderedURL)))
+	}
+	if sum, err := hex.DecodeString(archive.SHA256); err != nil || len(sum) != 32 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("sha256"), archive.SHA256, "must be a hex encoded sha256"))
+	}
+	if archive.Path != "" {
+		if p := strings.TrimPrefix(archive.Path, "/"); path.Clean(p) != p || p == ".." || strings.HasPrefix(p, "../") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("path"), archive.Path, "must be a path in archive"))
+		}
+	}
+	return allErrs
+}
+
+// validateContentReference checks the name of a referenced config map or secret and the keys selected
+func validateContentReference(fldPath *field.Path, ref *ContentReference) field.ErrorList {
+	var allErrs field.ErrorList
+	if ref.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), "reference must have a name"))
+	}
+	for i, item := range ref.Items {
+		itemPath := fldPath.Child("items").Index(i)
+		for _, msg := range validation.IsConfigMapKey(item.Key) {
+			allErrs = append(allErrs, field.Invalid(itemPath.Child("key"), item.Key, msg))
+		}
+		if item.Path != "" {
+			for _, msg := range validation.IsConfigMapKey(item.Path) {
+				allErrs = append(allErrs, field.Invalid(itemPath.Child("path"), item.Path, msg))
+			}
+		}
+	}
+	return allErrs
+}
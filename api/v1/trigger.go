@@ -0,0 +1,40 @@
+package v1
+
+import (
+	"path"
+)
+
+// Default bulabula
+func (r *TriggerTarget) Default() {
+	if r.Method == "" {
+		r.Method = DefaultTriggerMethod
+	}
+	if r.Path == "" {
+		r.Path = "/"
+	}
+	if r.Timeout.Duration == 0 {
+		r.Timeout.Duration = DefaultTriggerTimeout
+	}
+}
+
+// URLPath returns the gateway path of the target in namespace, /fn/{namespace}/{function}/{version}/{path}
+func (r *TriggerTarget) URLPath(namespace string) string {
+	version := r.Version
+	if version == "" {
+		version = LatestVersion
+	}
+	p := path.Join("/fn", namespace, r.Function, version, r.Path)
+	if len(r.Path) > 1 && r.Path[len(r.Path)-1] == '/' {
+		p += "/"
+	}
+	return p
+}
+
+// prependInvocation records invocation as the newest of history, keeping at most limit invocations
+func prependInvocation(history []TriggerInvocation, invocation TriggerInvocation, limit int32) []TriggerInvocation {
+	history = append([]TriggerInvocation{invocation}, history...)
+	if limit > 0 && int32(len(history)) > limit {
+		history = history[:limit]
+	}
+	return history
+}
@@ -0,0 +1,72 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TriggerTarget is the function invoked by a trigger through the gateway
+type TriggerTarget struct {
+	// The function name invoked by trigger
+	// +kubebuilder:validation:Required
+	Function string `json:"function"`
+
+	// Optional version or alias of function, the latest version when empty
+	// +kubebuilder:validation:Optional
+	Version string `json:"version,omitempty"`
+
+	// Optional HTTP method of invocations
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="POST"
+	Method string `json:"method,omitempty"`
+
+	// Optional path of invocations under the function
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="/"
+	Path string `json:"path,omitempty"`
+
+	// Optional headers of invocations
+	// +kubebuilder:validation:Optional
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Optional timeout of invocations, defaults to 30s
+	// +kubebuilder:validation:Optional
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+}
+
+// TriggerInvocation records an invocation of a function by a trigger
+type TriggerInvocation struct {
+	// The time of invocation
+	Time metav1.Time `json:"time"`
+
+	// Optional status code answered by function, unset when the invocation failed before
+	// +kubebuilder:validation:Optional
+	StatusCode int32 `json:"statusCode,omitempty"`
+
+	// The duration of invocation
+	Duration metav1.Duration `json:"duration"`
+
+	// Optional error of invocation
+	// +kubebuilder:validation:Optional
+	Error string `json:"error,omitempty"`
+}
+
+// Succeeded reports whether function answered with a 2xx or 3xx code
+func (r *TriggerInvocation) Succeeded() bool {
+	return r.Error == "" && r.StatusCode > 0 && r.StatusCode < 400
+}
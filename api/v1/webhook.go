@@ -0,0 +1,109 @@
+package v1
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// webhookClient is used by the validating webhooks to look up sibling objects,
+// it is nil until SetupWebhooksWithManager is called.
+var webhookClient client.Reader
+
+// SetupWebhooksWithManager registers the defaulting and validating webhooks of all kess types
+func SetupWebhooksWithManager(mgr ctrl.Manager) error {
+	webhookClient = mgr.GetClient()
+
+	if err := (&Runtime{}).SetupWebhookWithManager(mgr); err != nil {
+		return err
+	}
+	if err := (&Function{}).SetupWebhookWithManager(mgr); err != nil {
+		return err
+	}
+	if err := (&Library{}).SetupWebhookWithManager(mgr); err != nil {
+		return err
+	}
+	if err := (&FunctionAlias{}).SetupWebhookWithManager(mgr); err != nil {
+		return err
+	}
+	if err := (&Rollout{}).SetupWebhookWithManager(mgr); err != nil {
+		return err
+	}
+	if err := (&CronTrigger{}).SetupWebhookWithManager(mgr); err != nil {
+		return err
+	}
+	if err := (&MQTTTrigger{}).SetupWebhookWithManager(mgr); err != nil {
+		return err
+	}
+	if err := (&K8sEventTrigger{}).SetupWebhookWithManager(mgr); err != nil {
+		return err
+	}
+	if err := (&Workflow{}).SetupWebhookWithManager(mgr); err != nil {
+		return err
+	}
+	if err := (&WorkflowRun{}).SetupWebhookWithManager(mgr); err != nil {
+		return err
+	}
+	if err := setupSecretReferencesWebhookWithManager(mgr); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateConfigMapName checks that a rendered config map name template is a valid DNS subdomain
+func validateConfigMapName(fldPath *field.Path, template, rendered string) field.ErrorList {
+	var allErrs field.ErrorList
+	if rendered == "" {
+		return append(allErrs, field.Required(fldPath, "config map name template must not be empty"))
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(rendered) {
+		allErrs = append(allErrs, field.Invalid(fldPath, template, fmt.Sprintf("renders to %q: %s", rendered, msg)))
+	}
+	return allErrs
+}
+
+// validateConfigMapMount checks that a rendered mount template is an absolute path
+func validateConfigMapMount(fldPath *field.Path, template, rendered string) field.ErrorList {
+	var allErrs field.ErrorList
+	if rendered == "" {
+		return append(allErrs, field.Required(fldPath, "mount template must not be empty"))
+	}
+	if !path.IsAbs(rendered) {
+		allErrs = append(allErrs, field.Invalid(fldPath, template, fmt.Sprintf("renders to %q: must be an absolute path", rendered)))
+	}
+	if strings.Contains(rendered, ":") {
+		allErrs = append(allErrs, field.Invalid(fldPath, template, fmt.Sprintf("renders to %q: must not contain ':'", rendered)))
+	}
+	return allErrs
+}
+
+// validateConfigMapKey checks that a rendered file name template is a valid config map key
+func validateConfigMapKey(fldPath *field.Path, template, rendered string) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, msg := range validation.IsConfigMapKey(rendered) {
+		allErrs = append(allErrs, field.Invalid(fldPath, template, fmt.Sprintf("renders to %q: %s", rendered, msg)))
+	}
+	return allErrs
+}
+
+// validateTriggerTarget checks the function and request of a trigger
+func validateTriggerTarget(fldPath *field.Path, target *TriggerTarget) field.ErrorList {
+	var allErrs field.ErrorList
+	if target.Function == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("function"), "trigger must reference a function"))
+	}
+	if target.Path != "" && !path.IsAbs(target.Path) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("path"), target.Path, "must be an absolute path"))
+	}
+	for name := range target.Headers {
+		if name == "" || strings.ContainsAny(name, " :\r\n") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("headers"), name, "must be a valid header name"))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,269 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Default bulabula
+func (r *Workflow) Default() {
+	if r.ObjectMeta.Labels == nil {
+		r.ObjectMeta.Labels = make(map[string]string)
+	}
+	for k, v := range r.Labels() {
+		r.ObjectMeta.Labels[k] = v
+	}
+
+	r.Spec.Default()
+}
+
+// DefaultStatus bulabula
+func (r *Workflow) DefaultStatus() {}
+
+// Labels bulabula
+func (r *Workflow) Labels() map[string]string {
+	return map[string]string{
+		LabelType: TypeWorkflow,
+	}
+}
+
+// NamespacedName bulabula
+func (r *Workflow) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      r.Name,
+		Namespace: r.Namespace,
+	}
+}
+
+// SetCondition bulabula
+func (r *Workflow) SetCondition(condition Condition) {
+	condition.ObservedGeneration = r.Generation
+	SetCondition(&r.Status.Conditions, condition)
+}
+
+// UpdateStatusValidated records whether the steps of workflow are valid
+func (r *Workflow) UpdateStatusValidated(errs field.ErrorList) {
+	if len(errs) == 0 {
+		r.SetCondition(NewCondition(ConditionReady, true, ReasonReconciled, ""))
+	} else {
+		r.SetCondition(NewCondition(ConditionReady, false, ReasonInvalidWorkflow, errs.ToAggregate().Error()))
+	}
+	r.Status.ObservedGeneration = r.Generation
+}
+
+// Default bulabula
+func (r *WorkflowSpec) Default() {
+	if r.Start == "" && len(r.Steps) > 0 {
+		r.Start = r.Steps[0].Name
+	}
+	for i := range r.Steps {
+		if fn := r.Steps[i].Function; fn != nil {
+			fn.TriggerTarget.Default()
+		}
+	}
+}
+
+// Step returns the step with name, or nil
+func (r *WorkflowSpec) Step(name string) *WorkflowStep {
+	for i := range r.Steps {
+		if r.Steps[i].Name == name {
+			return &r.Steps[i]
+		}
+	}
+	return nil
+}
+
+// Children returns the names of the steps run by step
+func (r *WorkflowStep) Children() []string {
+	switch {
+	case len(r.Sequence) > 0:
+		return r.Sequence
+	case len(r.Parallel) > 0:
+		return r.Parallel
+	case r.Foreach != nil:
+		return []string{r.Foreach.Step}
+	case r.Switch != nil:
+		var children []string
+		for _, c := range r.Switch.Cases {
+			children = append(children, c.Step)
+		}
+		if r.Switch.Default != "" {
+			children = append(children, r.Switch.Default)
+		}
+		return children
+	}
+	return nil
+}
+
+// SplitWorkflowPath splits a path of a json value into its keys and array indexes, a path is made of keys separated
+// by dots and may start with $, an empty path or $ is the value itself
+func SplitWorkflowPath(p string) ([]string, error) {
+	p = strings.TrimPrefix(strings.TrimPrefix(p, "$"), ".")
+	if p == "" {
+		return nil, nil
+	}
+	segments := strings.Split(p, ".")
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, fmt.Errorf("path %q has an empty key", p)
+		}
+	}
+	return segments, nil
+}
+
+// Validate returns the errors of the steps of workflow, steps must reference existing steps without cycles
+func (r *WorkflowSpec) Validate(fldPath *field.Path) field.ErrorList {
+	var (
+		allErrs   field.ErrorList
+		stepsPath = fldPath.Child("steps")
+		names     = make(map[string]bool, len(r.Steps))
+	)
+
+	if len(r.Steps) == 0 {
+		return append(allErrs, field.Required(stepsPath, "workflow must have steps"))
+	}
+	for i := range r.Steps {
+		step := &r.Steps[i]
+		stepPath := stepsPath.Index(i)
+		if step.Name == "" {
+			allErrs = append(allErrs, field.Required(stepPath.Child("name"), "step must have a name"))
+		} else if names[step.Name] {
+			allErrs = append(allErrs, field.Duplicate(stepPath.Child("name"), step.Name))
+		}
+		names[step.Name] = true
+	}
+	for i := range r.Steps {
+		allErrs = append(allErrs, validateWorkflowStep(stepsPath.Index(i), &r.Steps[i], names)...)
+	}
+
+	if r.Start != "" && !names[r.Start] {
+		allErrs = append(allErrs, field.NotFound(fldPath.Child("start"), r.Start))
+	}
+	if len(allErrs) == 0 {
+		allErrs = append(allErrs, r.validateCycles(stepsPath)...)
+	}
+	return allErrs
+}
+
+func validateWorkflowStep(fldPath *field.Path, step *WorkflowStep, names map[string]bool) field.ErrorList {
+	var allErrs field.ErrorList
+
+	kinds := 0
+	for _, set := range []bool{step.Function != nil, len(step.Sequence) > 0, len(step.Parallel) > 0, step.Foreach != nil, step.Switch != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath, step.Name,
+			"step must set exactly one of function, sequence, parallel, foreach or switch"))
+	}
+
+	reference := func(refPath *field.Path, name string) {
+		if !names[name] {
+			allErrs = append(allErrs, field.NotFound(refPath, name))
+		}
+	}
+	if step.Function != nil {
+		allErrs = append(allErrs, validateTriggerTarget(fldPath.Child("function"), &step.Function.TriggerTarget)...)
+	}
+	for i, name := range step.Sequence {
+		reference(fldPath.Child("sequence").Index(i), name)
+	}
+	parallel := make(map[string]bool, len(step.Parallel))
+	for i, name := range step.Parallel {
+		reference(fldPath.Child("parallel").Index(i), name)
+		if parallel[name] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("parallel").Index(i), name))
+		}
+		parallel[name] = true
+	}
+	if foreach := step.Foreach; foreach != nil {
+		reference(fldPath.Child("foreach", "step"), foreach.Step)
+		allErrs = append(allErrs, validateWorkflowPath(fldPath.Child("foreach", "itemsPath"), foreach.ItemsPath)...)
+	}
+	if sw := step.Switch; sw != nil {
+		for i, c := range sw.Cases {
+			casePath := fldPath.Child("switch", "cases").Index(i)
+			reference(casePath.Child("step"), c.Step)
+			if len(c.Conditions) == 0 {
+				allErrs = append(allErrs, field.Required(casePath.Child("conditions"), "case must have conditions"))
+			}
+			for j := range c.Conditions {
+				allErrs = append(allErrs, validateWorkflowCondition(casePath.Child("conditions").Index(j), &c.Conditions[j])...)
+			}
+		}
+		if sw.Default != "" {
+			reference(fldPath.Child("switch", "default"), sw.Default)
+		}
+	}
+
+	allErrs = append(allErrs, validateWorkflowPath(fldPath.Child("inputPath"), step.InputPath)...)
+	allErrs = append(allErrs, validateWorkflowPath(fldPath.Child("resultPath"), step.ResultPath)...)
+	return allErrs
+}
+
+func validateWorkflowCondition(fldPath *field.Path, condition *WorkflowCondition) field.ErrorList {
+	var allErrs field.ErrorList
+
+	allErrs = append(allErrs, validateWorkflowPath(fldPath.Child("path"), condition.Path)...)
+	switch condition.Operator {
+	case EqualsWorkflowOperator, NotEqualsWorkflowOperator, ExistsWorkflowOperator, NotExistsWorkflowOperator:
+	case GreaterThanWorkflowOperator, LessThanWorkflowOperator:
+		var number float64
+		if err := json.Unmarshal([]byte(condition.Value), &number); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("value"), condition.Value, "must be a number"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("operator"), condition.Operator, []string{
+			string(EqualsWorkflowOperator), string(NotEqualsWorkflowOperator), string(ExistsWorkflowOperator),
+			string(NotExistsWorkflowOperator), string(GreaterThanWorkflowOperator), string(LessThanWorkflowOperator),
+		}))
+	}
+	return allErrs
+}
+
+func validateWorkflowPath(fldPath *field.Path, p string) field.ErrorList {
+	var allErrs field.ErrorList
+	if _, err := SplitWorkflowPath(p); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, p, err.Error()))
+	}
+	return allErrs
+}
+
+// validateCycles rejects steps which run themselves through their children
+func (r *WorkflowSpec) validateCycles(fldPath *field.Path) field.ErrorList {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	var (
+		allErrs field.ErrorList
+		states  = make(map[string]int, len(r.Steps))
+		visit   func(name string, path []string)
+	)
+	visit = func(name string, path []string) {
+		switch states[name] {
+		case visiting:
+			allErrs = append(allErrs, field.Invalid(fldPath, name,
+				fmt.Sprintf("steps must not run themselves: %s", strings.Join(append(path, name), " -> "))))
+			return
+		case visited:
+			return
+		}
+		states[name] = visiting
+		for _, child := range r.Step(name).Children() {
+			visit(child, append(path, name))
+		}
+		states[name] = visited
+	}
+
+	for _, step := range r.Steps {
+		visit(step.Name, nil)
+	}
+	return allErrs
+}
@@ -0,0 +1,56 @@
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestWorkflowValidate(t *testing.T) {
+	function := func(name string) WorkflowStep {
+		return WorkflowStep{Name: name, Function: &WorkflowFunction{TriggerTarget: TriggerTarget{Function: name}}}
+	}
+
+	cases := []struct {
+		name  string
+		spec  WorkflowSpec
+		error string
+	}{
+		{"valid", WorkflowSpec{Steps: []WorkflowStep{
+			{Name: "main", Sequence: []string{"fetch", "each"}},
+			function("fetch"),
+			{Name: "each", Foreach: &WorkflowForeach{ItemsPath: "$.items", Step: "route"}},
+			{Name: "route", Switch: &WorkflowSwitch{
+				Cases:   []WorkflowSwitchCase{{Conditions: []WorkflowCondition{{Path: "price", Operator: GreaterThanWorkflowOperator, Value: "10"}}, Step: "fetch"}},
+				Default: "fetch",
+			}},
+		}}, ""},
+		{"missing step", WorkflowSpec{Steps: []WorkflowStep{{Name: "main", Parallel: []string{"fetch"}}}}, "spec.steps[0].parallel[0]: Not found"},
+		{"several kinds", WorkflowSpec{Steps: []WorkflowStep{
+			{Name: "main", Function: &WorkflowFunction{TriggerTarget: TriggerTarget{Function: "main"}}, Sequence: []string{"main"}},
+		}}, "exactly one of"},
+		{"cycle", WorkflowSpec{Steps: []WorkflowStep{
+			{Name: "main", Sequence: []string{"loop"}},
+			{Name: "loop", Foreach: &WorkflowForeach{Step: "main"}},
+		}}, "main -> loop -> main"},
+		{"start", WorkflowSpec{Start: "other", Steps: []WorkflowStep{function("main")}}, "spec.start: Not found"},
+		{"threshold", WorkflowSpec{Steps: []WorkflowStep{
+			{Name: "main", Switch: &WorkflowSwitch{Cases: []WorkflowSwitchCase{
+				{Conditions: []WorkflowCondition{{Operator: LessThanWorkflowOperator, Value: "ten"}}, Step: "fetch"},
+			}}},
+			function("fetch"),
+		}}, "must be a number"},
+		{"path", WorkflowSpec{Steps: []WorkflowStep{{Name: "main", Function: &WorkflowFunction{TriggerTarget: TriggerTarget{Function: "main"}}, ResultPath: "a..b"}}}, "empty key"},
+	}
+	for _, c := range cases {
+		c.spec.Default()
+		errs := c.spec.Validate(field.NewPath("spec"))
+		switch {
+		case c.error == "" && len(errs) > 0:
+			t.Errorf("%s: workflow must be valid, got %v", c.name, errs)
+		case c.error != "" && (len(errs) == 0 || !strings.Contains(errs.ToAggregate().Error(), c.error)):
+			t.Errorf("%s: workflow must be invalid with %q, got %v", c.name, c.error, errs)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WorkflowOperator bulabula
+// +kubebuilder:validation:Enum=Equals;NotEquals;Exists;NotExists;GreaterThan;LessThan
+type WorkflowOperator string
+
+// WorkflowOperator Constants bulabula
+const (
+	EqualsWorkflowOperator      WorkflowOperator = "Equals"
+	NotEqualsWorkflowOperator   WorkflowOperator = "NotEquals"
+	ExistsWorkflowOperator      WorkflowOperator = "Exists"
+	NotExistsWorkflowOperator   WorkflowOperator = "NotExists"
+	GreaterThanWorkflowOperator WorkflowOperator = "GreaterThan"
+	LessThanWorkflowOperator    WorkflowOperator = "LessThan"
+)
+
+// WorkflowFunction is a function invoked by a workflow step with the input of step as json body,
+// the json response of function is the output of step
+type WorkflowFunction struct {
+	TriggerTarget `json:",inline"`
+
+	// Optional number of times a failed invocation is retried
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	Retries int32 `json:"retries,omitempty"`
+}
+
+// WorkflowForeach runs a step for every item of an array of the input, its output is the array of outputs
+type WorkflowForeach struct {
+	// Optional path of the array in the input, the input itself when empty
+	// +kubebuilder:validation:Optional
+	ItemsPath string `json:"itemsPath,omitempty"`
+
+	// The name of step run for every item
+	// +kubebuilder:validation:Required
+	Step string `json:"step"`
+
+	// Optional number of items run concurrently, all of them when zero
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	Concurrency int32 `json:"concurrency,omitempty"`
+}
+
+// WorkflowCondition compares the value at a path of the input
+type WorkflowCondition struct {
+	// The path of the compared value in the input, the input itself when empty
+	// +kubebuilder:validation:Optional
+	Path string `json:"path,omitempty"`
+
+	// The comparison of value
+	// +kubebuilder:validation:Required
+	Operator WorkflowOperator `json:"operator"`
+
+	// Optional json value compared with, a string if it is not valid json
+	// +kubebuilder:validation:Optional
+	Value string `json:"value,omitempty"`
+}
+
+// WorkflowSwitchCase bulabula
+type WorkflowSwitchCase struct {
+	// The conditions which all hold for the case
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	Conditions []WorkflowCondition `json:"conditions"`
+
+	// The name of step run for the case
+	// +kubebuilder:validation:Required
+	Step string `json:"step"`
+}
+
+// WorkflowSwitch runs the step of the first case holding for the input
+type WorkflowSwitch struct {
+	// The cases of switch, checked in order
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	Cases []WorkflowSwitchCase `json:"cases"`
+
+	// Optional name of step run when no case holds, the input is passed through when empty
+	// +kubebuilder:validation:Optional
+	Default string `json:"default,omitempty"`
+}
+
+// WorkflowStep is a step of workflow, exactly one of function, sequence, parallel, foreach or switch is set
+type WorkflowStep struct {
+	// The name of step, referenced by other steps
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Optional function invoked by step
+	// +kubebuilder:validation:Optional
+	Function *WorkflowFunction `json:"function,omitempty"`
+
+	// Optional names of steps run one after another, each one receiving the output of the previous one
+	// +kubebuilder:validation:Optional
+	Sequence []string `json:"sequence,omitempty"`
+
+	// Optional names of steps run concurrently with the same input, the output is an object of their outputs by name
+	// +kubebuilder:validation:Optional
+	Parallel []string `json:"parallel,omitempty"`
+
+	// Optional step run for every item of an array
+	// +kubebuilder:validation:Optional
+	Foreach *WorkflowForeach `json:"foreach,omitempty"`
+
+	// Optional steps run conditionally
+	// +kubebuilder:validation:Optional
+	Switch *WorkflowSwitch `json:"switch,omitempty"`
+
+	// Optional path of the part of the input passed to step, the whole input when empty
+	// +kubebuilder:validation:Optional
+	InputPath string `json:"inputPath,omitempty"`
+
+	// Optional path the output of step is set at in its input, the output replaces the input when empty
+	// +kubebuilder:validation:Optional
+	ResultPath string `json:"resultPath,omitempty"`
+}
+
+// WorkflowSpec defines the desired state of Workflow
+type WorkflowSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The steps of workflow
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	// +listType=map
+	// +listMapKey=name
+	Steps []WorkflowStep `json:"steps"`
+
+	// Optional name of the step workflow runs, the first step when empty
+	// +kubebuilder:validation:Optional
+	Start string `json:"start,omitempty"`
+}
+
+// WorkflowStatus defines the observed state of Workflow
+type WorkflowStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The generation observed by the workflow controller
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Optional conditions of workflow
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:resource:categories="kess",shortName="wf"
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Start",type=string,JSONPath=`.spec.start`,priority=0
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`,priority=0
+// +kubebuilder:object:root=true
+
+// Workflow is the Schema for the workflows API
+type Workflow struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WorkflowSpec   `json:"spec,omitempty"`
+	Status WorkflowStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WorkflowList contains a list of Workflow
+type WorkflowList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Workflow `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Workflow{}, &WorkflowList{})
+}
@@ -0,0 +1,69 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var workflowlog = logf.Log.WithName("workflow-resource")
+
+// SetupWebhookWithManager bulabula
+func (r *Workflow) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-core-kess-io-v1-workflow,mutating=true,failurePolicy=fail,groups=core.kess.io,resources=workflows,verbs=create;update,versions=v1,name=mworkflow.kb.io
+
+var _ webhook.Defaulter = &Workflow{}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-core-kess-io-v1-workflow,mutating=false,failurePolicy=fail,groups=core.kess.io,resources=workflows,versions=v1,name=vworkflow.kb.io
+
+var _ webhook.Validator = &Workflow{}
+
+// ValidateCreate bulabula
+func (r *Workflow) ValidateCreate() error {
+	workflowlog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate bulabula
+func (r *Workflow) ValidateUpdate(old runtime.Object) error {
+	workflowlog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateDelete bulabula
+func (r *Workflow) ValidateDelete() error {
+	return nil
+}
+
+func (r *Workflow) validate() error {
+	allErrs := r.Spec.Validate(field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Workflow").GroupKind(), r.Name, allErrs)
+}
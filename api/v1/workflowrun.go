@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"fmt"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// MaxWorkflowNodeOutputs bounds the total size of the outputs of nodes kept in the status of a run
+const MaxWorkflowNodeOutputs = 512 << 10
+
+// Default bulabula
+func (r *WorkflowRun) Default() {
+	if r.ObjectMeta.Labels == nil {
+		r.ObjectMeta.Labels = make(map[string]string)
+	}
+	for k, v := range r.Labels() {
+		r.ObjectMeta.Labels[k] = v
+	}
+}
+
+// DefaultStatus bulabula
+func (r *WorkflowRun) DefaultStatus() {
+	if r.Status.Phase == "" {
+		r.Status.Phase = PendingWorkflowRunPhase
+	}
+}
+
+// Labels bulabula
+func (r *WorkflowRun) Labels() map[string]string {
+	return map[string]string{
+		LabelType:     TypeWorkflowRun,
+		LabelWorkflow: r.Spec.Workflow,
+	}
+}
+
+// NamespacedName bulabula
+func (r *WorkflowRun) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      r.Name,
+		Namespace: r.Namespace,
+	}
+}
+
+// WorkflowNamespacedName bulabula
+func (r *WorkflowRun) WorkflowNamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      r.Spec.Workflow,
+		Namespace: r.Namespace,
+	}
+}
+
+// SetCondition bulabula
+func (r *WorkflowRun) SetCondition(condition Condition) {
+	condition.ObservedGeneration = r.Generation
+	SetCondition(&r.Status.Conditions, condition)
+}
+
+// IsFinished reports whether run succeeded or failed
+func (r *WorkflowRun) IsFinished() bool {
+	return r.Status.Phase == SucceededWorkflowRunPhase || r.Status.Phase == FailedWorkflowRunPhase
+}
+
+// Input returns the json input of run, an empty object when unset
+func (r *WorkflowRun) Input() []byte {
+	if r.Spec.Input == nil || len(r.Spec.Input.Raw) == 0 {
+		return []byte("{}")
+	}
+	return r.Spec.Input.Raw
+}
+
+// UpdateStatusPending records why run has not started
+func (r *WorkflowRun) UpdateStatusPending(reason, message string) {
+	r.Status.Phase = PendingWorkflowRunPhase
+	r.SetCondition(NewCondition(ConditionSucceeded, false, reason, message))
+}
+
+// UpdateStatusStarted starts run with the steps of workflow
+func (r *WorkflowRun) UpdateStatusStarted(workflow *WorkflowSpec, now time.Time) {
+	r.Status.Phase = RunningWorkflowRunPhase
+	r.Status.Workflow = workflow.DeepCopy()
+	r.Status.StartTime = &metav1.Time{Time: now}
+	r.SetCondition(NewCondition(ConditionSucceeded, false, ReasonRunning, ""))
+}
+
+// UpdateStatusNodes records the finished nodes, replacing the ones with the same identifier
+func (r *WorkflowRun) UpdateStatusNodes(nodes []WorkflowNodeStatus) {
+	for _, node := range nodes {
+		replaced := false
+		for i := range r.Status.Nodes {
+			if r.Status.Nodes[i].ID == node.ID {
+				r.Status.Nodes[i] = node
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			r.Status.Nodes = append(r.Status.Nodes, node)
+		}
+	}
+}
+
+// UpdateStatusNode records node, a node whose output would take the outputs of run beyond MaxWorkflowNodeOutputs
+// is recorded as failed without its output
+func (r *WorkflowRun) UpdateStatusNode(node WorkflowNodeStatus) {
+	if node.Output != nil {
+		size := len(node.Output.Raw)
+		for i := range r.Status.Nodes {
+			if r.Status.Nodes[i].ID != node.ID && r.Status.Nodes[i].Output != nil {
+				size += len(r.Status.Nodes[i].Output.Raw)
+			}
+		}
+		if size > MaxWorkflowNodeOutputs {
+			node.Phase = FailedWorkflowRunPhase
+			node.Output = nil
+			node.Error = fmt.Sprintf("outputs of run exceed %d bytes", MaxWorkflowNodeOutputs)
+		}
+	}
+	r.UpdateStatusNodes([]WorkflowNodeStatus{node})
+}
+
+// UpdateStatusSucceeded finishes run with the output of workflow
+func (r *WorkflowRun) UpdateStatusSucceeded(output []byte, now time.Time) {
+	r.Status.Phase = SucceededWorkflowRunPhase
+	r.Status.Output = &apiextensionsv1.JSON{Raw: output}
+	r.Status.CompletionTime = &metav1.Time{Time: now}
+	r.SetCondition(NewCondition(ConditionSucceeded, true, ReasonReconciled, ""))
+}
+
+// UpdateStatusFailed finishes run with the message of why it failed
+func (r *WorkflowRun) UpdateStatusFailed(reason, message string, now time.Time) {
+	r.Status.Phase = FailedWorkflowRunPhase
+	r.Status.Message = message
+	r.Status.CompletionTime = &metav1.Time{Time: now}
+	r.SetCondition(NewCondition(ConditionSucceeded, false, reason, message))
+}
@@ -0,0 +1,31 @@
+package v1
+
+import (
+	"bytes"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestWorkflowRunUpdateStatusNode(t *testing.T) {
+	output := func(size int) *apiextensionsv1.JSON {
+		return &apiextensionsv1.JSON{Raw: append(append([]byte(`"`), bytes.Repeat([]byte("x"), size-2)...), '"')}
+	}
+
+	run := &WorkflowRun{}
+	run.UpdateStatusNode(WorkflowNodeStatus{ID: "main/0/fetch", Phase: SucceededWorkflowRunPhase, Output: output(MaxWorkflowNodeOutputs / 2)})
+	run.UpdateStatusNode(WorkflowNodeStatus{ID: "main/1/fetch", Phase: SucceededWorkflowRunPhase, Output: output(MaxWorkflowNodeOutputs / 2)})
+	if len(run.Status.Nodes) != 2 || run.Status.Nodes[1].Phase != SucceededWorkflowRunPhase {
+		t.Fatalf("nodes within the limit must be recorded, got %+v", run.Status.Nodes)
+	}
+
+	run.UpdateStatusNode(WorkflowNodeStatus{ID: "main/2/fetch", Phase: SucceededWorkflowRunPhase, Output: output(2)})
+	if node := run.Status.Nodes[2]; node.Phase != FailedWorkflowRunPhase || node.Output != nil || node.Error == "" {
+		t.Errorf("node beyond the limit must fail without its output, got %+v", node)
+	}
+
+	run.UpdateStatusNode(WorkflowNodeStatus{ID: "main/1/fetch", Phase: SucceededWorkflowRunPhase, Output: output(2)})
+	if node := run.Status.Nodes[1]; node.Phase != SucceededWorkflowRunPhase || len(run.Status.Nodes) != 3 {
+		t.Errorf("replaced node must not count its previous output, got %+v", node)
+	}
+}
@@ -0,0 +1,154 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WorkflowRunPhase bulabula
+type WorkflowRunPhase string
+
+// WorkflowRunPhase Constants bulabula
+const (
+	PendingWorkflowRunPhase   WorkflowRunPhase = "Pending"
+	RunningWorkflowRunPhase   WorkflowRunPhase = "Running"
+	SucceededWorkflowRunPhase WorkflowRunPhase = "Succeeded"
+	FailedWorkflowRunPhase    WorkflowRunPhase = "Failed"
+)
+
+// WorkflowRunSpec defines the desired state of WorkflowRun
+type WorkflowRunSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The name of workflow run in the same namespace
+	// +kubebuilder:validation:Required
+	Workflow string `json:"workflow"`
+
+	// Optional json input of workflow, an empty object when unset
+	// +kubebuilder:validation:Optional
+	Input *apiextensionsv1.JSON `json:"input,omitempty"`
+}
+
+// WorkflowNodeStatus is the state of a function step run by a workflow run
+type WorkflowNodeStatus struct {
+	// The identifier of node, the path of steps leading to it from the start step
+	// +kubebuilder:validation:Required
+	ID string `json:"id"`
+
+	// The name of step
+	// +kubebuilder:validation:Required
+	Step string `json:"step"`
+
+	// The phase of node, either succeeded or failed
+	// +kubebuilder:validation:Required
+	Phase WorkflowRunPhase `json:"phase"`
+
+	// The number of invocations of function
+	// +kubebuilder:validation:Optional
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// Optional json output of node
+	// +kubebuilder:validation:Optional
+	Output *apiextensionsv1.JSON `json:"output,omitempty"`
+
+	// Optional error of the last invocation
+	// +kubebuilder:validation:Optional
+	Error string `json:"error,omitempty"`
+
+	// The time node started
+	// +kubebuilder:validation:Optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// The time node finished
+	// +kubebuilder:validation:Optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// WorkflowRunStatus defines the observed state of WorkflowRun
+type WorkflowRunStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The phase of run
+	// +kubebuilder:validation:Optional
+	Phase WorkflowRunPhase `json:"phase,omitempty"`
+
+	// The steps of workflow when run started, later changes of workflow do not affect run
+	// +kubebuilder:validation:Optional
+	Workflow *WorkflowSpec `json:"workflow,omitempty"`
+
+	// The function steps which finished
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=id
+	Nodes []WorkflowNodeStatus `json:"nodes,omitempty"`
+
+	// Optional json output of workflow
+	// +kubebuilder:validation:Optional
+	Output *apiextensionsv1.JSON `json:"output,omitempty"`
+
+	// Optional message of why run failed
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+
+	// The time run started
+	// +kubebuilder:validation:Optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// The time run finished
+	// +kubebuilder:validation:Optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Optional conditions of run
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:resource:categories="kess",shortName="wfr"
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Workflow",type=string,JSONPath=`.spec.workflow`,priority=0
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`,priority=0
+// +kubebuilder:printcolumn:name="Start",type=date,JSONPath=`.status.startTime`,priority=0
+// +kubebuilder:printcolumn:name="Completion",type=date,JSONPath=`.status.completionTime`,priority=0
+// +kubebuilder:object:root=true
+
+// WorkflowRun is the Schema for the workflowruns API
+type WorkflowRun struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WorkflowRunSpec   `json:"spec,omitempty"`
+	Status WorkflowRunStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WorkflowRunList contains a list of WorkflowRun
+type WorkflowRunList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WorkflowRun `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WorkflowRun{}, &WorkflowRunList{})
+}
@@ -0,0 +1,86 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"reflect"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var workflowrunlog = logf.Log.WithName("workflowrun-resource")
+
+// SetupWebhookWithManager bulabula
+func (r *WorkflowRun) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-core-kess-io-v1-workflowrun,mutating=true,failurePolicy=fail,groups=core.kess.io,resources=workflowruns,verbs=create;update,versions=v1,name=mworkflowrun.kb.io
+
+var _ webhook.Defaulter = &WorkflowRun{}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-core-kess-io-v1-workflowrun,mutating=false,failurePolicy=fail,groups=core.kess.io,resources=workflowruns,versions=v1,name=vworkflowrun.kb.io
+
+var _ webhook.Validator = &WorkflowRun{}
+
+// ValidateCreate bulabula
+func (r *WorkflowRun) ValidateCreate() error {
+	workflowrunlog.Info("validate create", "name", r.Name)
+	return r.validate(nil)
+}
+
+// ValidateUpdate bulabula
+func (r *WorkflowRun) ValidateUpdate(old runtime.Object) error {
+	workflowrunlog.Info("validate update", "name", r.Name)
+	return r.validate(old.(*WorkflowRun))
+}
+
+// ValidateDelete bulabula
+func (r *WorkflowRun) ValidateDelete() error {
+	return nil
+}
+
+func (r *WorkflowRun) validate(old *WorkflowRun) error {
+	var (
+		allErrs  field.ErrorList
+		specPath = field.NewPath("spec")
+	)
+
+	if r.Spec.Workflow == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("workflow"), "run must reference a workflow"))
+	}
+	if r.Spec.Input != nil && !json.Valid(r.Spec.Input.Raw) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("input"), string(r.Spec.Input.Raw), "must be valid json"))
+	}
+	if old != nil && !reflect.DeepEqual(r.Spec, old.Spec) {
+		allErrs = append(allErrs, field.Forbidden(specPath, "spec of a run is immutable"))
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("WorkflowRun").GroupKind(), r.Name, allErrs)
+}
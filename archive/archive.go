@@ -0,0 +1,214 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxSize bounds both the archives downloaded and the files unpacked from them
+const DefaultMaxSize = 16 << 20
+
+// ErrChecksumMismatch is returned when the sha256 of an archive is not the expected one
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Files are the regular files of an archive by their slash separated path
+type Files map[string][]byte
+
+// Fetcher downloads tar.gz archives verified by their sha256, archives are kept in Dir by their checksum,
+// so an archive is downloaded once and unpacked locally afterwards
+type Fetcher struct {
+	Client *http.Client
+
+	// Dir keeps the archives verified
+	Dir string
+
+	// MaxSize bounds both the archives downloaded and the files unpacked from them
+	MaxSize int64
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// NewFetcher bulabula
+func NewFetcher(dir string) *Fetcher {
+	return &Fetcher{
+		Client:  &http.Client{Timeout: time.Minute},
+		Dir:     dir,
+		MaxSize: DefaultMaxSize,
+	}
+}
+
+// Fetch returns the files of the tar.gz archive at url, which is downloaded unless an archive with the same
+// checksum was already verified
+func (f *Fetcher) Fetch(ctx context.Context, url, checksum string) (Files, error) {
+	checksum = strings.ToLower(checksum)
+	if _, err := hex.DecodeString(checksum); err != nil || len(checksum) != sha256.Size*2 {
+		return nil, fmt.Errorf("invalid sha256 %q", checksum)
+	}
+
+	file := filepath.Join(f.Dir, checksum+".tar.gz")
+	if err := f.ensure(ctx, url, checksum, file); err != nil {
+		return nil, err
+	}
+
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return Unpack(r, f.MaxSize)
+}
+
+// ensure downloads the archive at url to file unless it exists, an archive is downloaded once at a time
+func (f *Fetcher) ensure(ctx context.Context, url, checksum, file string) error {
+	unlock := f.lock(checksum)
+	defer unlock()
+
+	if _, err := os.Stat(file); err == nil {
+		return nil
+	}
+	return f.download(ctx, url, checksum, file)
+}
+
+// lock serializes the downloads of the archive with checksum
+func (f *Fetcher) lock(checksum string) func() {
+	f.mu.Lock()
+	if f.locks == nil {
+		f.locks = make(map[string]*sync.Mutex)
+	}
+	l, ok := f.locks[checksum]
+	if !ok {
+		l = &sync.Mutex{}
+		f.locks[checksum] = l
+	}
+	f.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// download writes the archive at url to file once its checksum is verified
+func (f *Fetcher) download(ctx context.Context, url, checksum, file string) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download %s: server answered %d", url, resp.StatusCode)
+	}
+
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(f.Dir, "download-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), f.limit(resp.Body))
+	if err != nil {
+		return fmt.Errorf("download %s: %w", url, err)
+	}
+	if f.MaxSize > 0 && n > f.MaxSize {
+		return fmt.Errorf("download %s: archive is larger than %d bytes", url, f.MaxSize)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != checksum {
+		return fmt.Errorf("%w: %s has sha256 %s, expected %s", ErrChecksumMismatch, url, sum, checksum)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// limit reads at most one byte more than MaxSize of r, so oversized content is detected
+func (f *Fetcher) limit(r io.Reader) io.Reader {
+	if f.MaxSize <= 0 {
+		return r
+	}
+	return io.LimitReader(r, f.MaxSize+1)
+}
+
+// Unpack returns the regular files of the tar.gz archive r, other entries are skipped, the files must not
+// exceed maxSize in total when it is positive
+func Unpack(r io.Reader, maxSize int64) (Files, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	defer gz.Close()
+
+	var (
+		files = Files{}
+		total int64
+		tr    = tar.NewReader(gz)
+	)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "/"))
+		if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("invalid archive: file %q is outside of archive", header.Name)
+		}
+		total += header.Size
+		if maxSize > 0 && total > maxSize {
+			return nil, fmt.Errorf("invalid archive: files are larger than %d bytes", maxSize)
+		}
+		data, err := ioutil.ReadAll(io.LimitReader(tr, header.Size))
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive: %w", err)
+		}
+		files[name] = data
+	}
+}
+
+// Dir returns the files under dir with their path relative to it, all files when dir is empty
+func (fs Files) Dir(dir string) Files {
+	dir = path.Clean(strings.TrimPrefix(dir, "/"))
+	if dir == "." {
+		return fs
+	}
+	out := Files{}
+	for name, data := range fs {
+		if rel := strings.TrimPrefix(name, dir+"/"); rel != name {
+			out[rel] = data
+		}
+	}
+	return out
+}
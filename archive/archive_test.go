@@ -0,0 +1,102 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+func testArchive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.WriteHeader(&tar.Header{Name: "hello/", Mode: 0755, Typeflag: tar.TypeDir})
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestFetcher(t *testing.T) {
+	bundle := testArchive(t, map[string]string{
+		"./hello/handler.py":   "def handler(): return 'hello'\n",
+		"hello/utils/greet.py": "GREETING = 'hello'\n",
+		"README.md":            "# hello\n",
+	})
+
+	var downloads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&downloads, 1)
+		if req.URL.Path != "/hello/v1.tar.gz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(bundle)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kess-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	fetcher := NewFetcher(dir)
+	url := server.URL + "/hello/v1.tar.gz"
+
+	if _, err := fetcher.Fetch(ctx, url, checksum([]byte("tampered"))); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("tampered archive must not be verified, got %v", err)
+	}
+	if _, err := fetcher.Fetch(ctx, server.URL+"/missing.tar.gz", checksum(bundle)); err == nil {
+		t.Fatal("missing archive must fail")
+	}
+
+	for i := 0; i < 2; i++ {
+		files, err := fetcher.Fetch(ctx, url, checksum(bundle))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 3 || string(files["hello/handler.py"]) != "def handler(): return 'hello'\n" {
+			t.Fatalf("unexpected files %v", files)
+		}
+		if hello := files.Dir("hello"); len(hello) != 2 || string(hello["utils/greet.py"]) != "GREETING = 'hello'\n" {
+			t.Errorf("unexpected hello files %v", hello)
+		}
+	}
+	if downloads != 3 {
+		t.Errorf("verified archive must be downloaded once, got %d downloads", downloads)
+	}
+
+	fetcher.MaxSize = 16
+	if _, err := fetcher.Fetch(ctx, url, checksum(bundle)); err == nil {
+		t.Error("files larger than max size must fail")
+	}
+}
+
+func TestUnpackOutside(t *testing.T) {
+	bundle := testArchive(t, map[string]string{"../etc/passwd": "root"})
+	if _, err := Unpack(bytes.NewReader(bundle), 0); err == nil {
+		t.Error("files outside of archive must fail")
+	}
+}
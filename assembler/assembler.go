@@ -0,0 +1,143 @@
+package assembler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+)
+
+// Assemble writes every config map mounted under configMapsDir into the directory of the same name in outDir,
+// the chunked keys of a config map are concatenated from the chunk config maps mounted under chunksDir and
+// verified against their sha256, there is nothing to assemble when configMapsDir does not exist
+func Assemble(configMapsDir, chunksDir, outDir string) error {
+	entries, err := ioutil.ReadDir(configMapsDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if err := assembleConfigMap(filepath.Join(configMapsDir, entry.Name()), chunksDir, filepath.Join(outDir, entry.Name())); err != nil {
+			return fmt.Errorf("config map %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+func assembleConfigMap(dir, chunksDir, outDir string) error {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	keys, err := configMapKeys(dir)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if key == corev1.ChunkIndexKey {
+			continue
+		}
+		if err := copyFile(filepath.Join(dir, key), keyFile(outDir, key)); err != nil {
+			return err
+		}
+	}
+
+	index := make(map[string]corev1.Chunk)
+	if data, err := ioutil.ReadFile(filepath.Join(dir, corev1.ChunkIndexKey)); err == nil {
+		if err := json.Unmarshal(data, &index); err != nil {
+			return fmt.Errorf("invalid chunk index: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	for key, chunk := range index {
+		if err := assembleChunk(chunk, chunksDir, keyFile(outDir, key)); err != nil {
+			return fmt.Errorf("key %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// configMapKeys returns the keys of a config map volume, skipping the ".." entries kubelet uses to swap content
+func configMapKeys(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "..") {
+			continue
+		}
+		keys = append(keys, entry.Name())
+	}
+	return keys, nil
+}
+
+// keyFile returns the file of a config map key under dir, the keys held under directories are laid out
+// at their path as config map volumes do
+func keyFile(dir, key string) string {
+	return filepath.Join(dir, filepath.FromSlash(corev1.KeyPath(key)))
+}
+
+func assembleChunk(chunk corev1.Chunk, chunksDir, file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	hash := sha256.New()
+	for _, name := range chunk.ConfigMaps {
+		in, err := os.Open(filepath.Join(chunksDir, name, corev1.ChunkKey))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(io.MultiWriter(out, hash), in)
+		in.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != chunk.SHA256 {
+		return fmt.Errorf("assembled content has sha256 %s, expected %s", sum, chunk.SHA256)
+	}
+	return out.Close()
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
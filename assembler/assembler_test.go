@@ -0,0 +1,97 @@
+package assembler
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+)
+
+// writeConfigMap lays out config map the way kubelet does, keys are links into a ".." directory
+func writeConfigMap(t *testing.T, dir string, cm apiv1.ConfigMap) {
+	data := filepath.Join(dir, "..2020_09_09_00_00_00.000000000")
+	if err := os.MkdirAll(data, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Base(data), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte)
+	for key, content := range cm.Data {
+		files[key] = []byte(content)
+	}
+	for key, content := range cm.BinaryData {
+		files[key] = content
+	}
+	for key, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(data, key), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join("..data", key), filepath.Join(dir, key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kess-assembler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	model := bytes.Repeat([]byte{0x6b, 0x65, 0x73, 0x73}, corev1.ChunkConfigMapLimit/2)
+	cm := apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "lib-model-v1", Namespace: "kess-samples"},
+		Data:       map[string]string{"__init__.py": "from .model import *\n", "utils..__init__.py": ""},
+		BinaryData: map[string][]byte{"model.bin": model},
+	}
+	chunks := corev1.ChunkConfigMap(&cm)
+	if len(chunks) == 0 {
+		t.Fatal("model must be chunked")
+	}
+
+	var (
+		configMapsDir = filepath.Join(dir, "configmaps")
+		chunksDir     = filepath.Join(dir, "chunks")
+		outDir        = filepath.Join(dir, "assembled")
+	)
+	writeConfigMap(t, filepath.Join(configMapsDir, cm.Name), cm)
+	for _, chunk := range chunks {
+		writeConfigMap(t, filepath.Join(chunksDir, chunk.Name), chunk)
+	}
+
+	if err := Assemble(configMapsDir, chunksDir, outDir); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ioutil.ReadDir(filepath.Join(outDir, cm.Name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("only the keys of config map must be assembled, got %d entries", len(entries))
+	}
+	if content, err := ioutil.ReadFile(filepath.Join(outDir, cm.Name, "model.bin")); err != nil || !bytes.Equal(content, model) {
+		t.Errorf("unexpected model of %d bytes, %v", len(content), err)
+	}
+	if content, err := ioutil.ReadFile(filepath.Join(outDir, cm.Name, "__init__.py")); err != nil || string(content) != cm.Data["__init__.py"] {
+		t.Errorf("unexpected init %q, %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, cm.Name, "utils", "__init__.py")); err != nil {
+		t.Errorf("keys under directories must be laid out at their path, %v", err)
+	}
+
+	tampered := filepath.Join(chunksDir, chunks[0].Name, "..2020_09_09_00_00_00.000000000", corev1.ChunkKey)
+	if err := ioutil.WriteFile(tampered, []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Assemble(configMapsDir, chunksDir, outDir); err == nil {
+		t.Error("tampered chunk must not be assembled")
+	}
+}
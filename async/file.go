@@ -0,0 +1,156 @@
+package async
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	fileExt  = ".json"
+	lockFile = ".lock"
+)
+
+// ErrLocked is returned when the directory of a file queue is used by another process
+var ErrLocked = errors.New("async: queue directory is locked by another process")
+
+var _ Queue = &FileQueue{}
+
+// FileQueue is an embedded queue keeping every invocation in a json file of a directory, so pending invocations
+// survive restarts, an invocation running when the process stopped is attempted again, the directory is locked
+// so that a single process uses it
+type FileQueue struct {
+	*MemoryQueue
+
+	dir  string
+	lock *os.File
+}
+
+// NewFileQueue locks and loads the invocations kept in dir, which is created if needed, ErrLocked is returned
+// while another queue holds dir
+func NewFileQueue(dir string) (*FileQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	lock, err := lockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := loadFileQueue(dir)
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	q.lock = lock
+	return q, nil
+}
+
+func loadFileQueue(dir string) (*FileQueue, error) {
+	q := &FileQueue{MemoryQueue: NewMemoryQueue(), dir: dir}
+	q.MemoryQueue.removed = q.remove
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var invocations []*Invocation
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), fileExt) {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var inv Invocation
+		if err := json.Unmarshal(data, &inv); err != nil {
+			return nil, err
+		}
+		if inv.Status == StatusRunning {
+			inv.Status = StatusPending
+			inv.NextAttemptAt = q.now()
+		}
+		invocations = append(invocations, &inv)
+	}
+
+	sort.Slice(invocations, func(i, j int) bool {
+		return invocations[i].UpdatedAt.Before(invocations[j].UpdatedAt)
+	})
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, inv := range invocations {
+		q.put(inv)
+	}
+	q.prune()
+	return q, nil
+}
+
+// Put writes inv to its file before it is queued, a new invocation over the limits of the queue is not written
+func (q *FileQueue) Put(ctx context.Context, inv *Invocation) error {
+	q.mu.Lock()
+	reserved, err := q.reserve(inv)
+	q.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := q.write(inv); err != nil {
+		if reserved {
+			q.mu.Lock()
+			q.track(inv.ID, 0, false)
+			q.mu.Unlock()
+		}
+		return err
+	}
+
+	return q.MemoryQueue.Put(ctx, inv)
+}
+
+// write writes inv to its file at once
+func (q *FileQueue) write(inv *Invocation) error {
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(q.dir, "."+inv.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), q.path(inv.ID)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Close releases the lock of the directory
+func (q *FileQueue) Close() error {
+	return q.lock.Close()
+}
+
+func (q *FileQueue) remove(id string) {
+	os.Remove(q.path(id))
+}
+
+func (q *FileQueue) path(id string) string {
+	return filepath.Join(q.dir, id+fileExt)
+}
+
+func lockPath(dir string) string {
+	return filepath.Join(dir, lockFile)
+}
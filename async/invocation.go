@@ -0,0 +1,168 @@
+package async
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+)
+
+// Status bulabula
+type Status string
+
+// Status Constants bulabula
+const (
+	// StatusPending waits for its next attempt
+	StatusPending Status = "Pending"
+	// StatusRunning is being attempted
+	StatusRunning Status = "Running"
+	// StatusSucceeded was answered with a 2xx or 3xx code
+	StatusSucceeded Status = "Succeeded"
+	// StatusFailed failed and could not be delivered to a dead-letter sink
+	StatusFailed Status = "Failed"
+	// StatusDeadLettered failed and was delivered to its dead-letter sink
+	StatusDeadLettered Status = "DeadLettered"
+)
+
+// IsFinished reports whether no more attempt is made
+func (s Status) IsFinished() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusDeadLettered
+}
+
+// Policy is how an invocation is retried and where it goes once it fails
+type Policy struct {
+	MaxAttempts int32                      `json:"maxAttempts"`
+	Backoff     time.Duration              `json:"backoff"`
+	MaxBackoff  time.Duration              `json:"maxBackoff"`
+	DeadLetter  *corev1.FunctionDeadLetter `json:"deadLetter,omitempty"`
+}
+
+// PolicyFor returns the policy of a function version, the default one when async is nil
+func PolicyFor(async *corev1.FunctionAsync) Policy {
+	policy := Policy{
+		MaxAttempts: corev1.DefaultAsyncMaxAttempts,
+		Backoff:     corev1.DefaultAsyncBackoff,
+		MaxBackoff:  corev1.DefaultAsyncMaxBackoff,
+	}
+	if async == nil {
+		return policy
+	}
+	if async.MaxAttempts != nil {
+		policy.MaxAttempts = *async.MaxAttempts
+	}
+	if async.Backoff.Duration > 0 {
+		policy.Backoff = async.Backoff.Duration
+	}
+	if async.MaxBackoff.Duration > 0 {
+		policy.MaxBackoff = async.MaxBackoff.Duration
+	}
+	if async.DeadLetter != nil {
+		policy.DeadLetter = async.DeadLetter.DeepCopy()
+	}
+	return policy
+}
+
+// Delay returns how long to wait after attempt before the next one, doubling from the backoff up to the
+// maximum backoff
+func (p Policy) Delay(attempt int32) time.Duration {
+	delay := p.Backoff
+	for i := int32(1); i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// Request is the request of an invocation, path is under the function
+type Request struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// Size returns roughly how many bytes req takes
+func (req Request) Size() int64 {
+	size := len(req.Method) + len(req.Path) + len(req.Body)
+	for key, values := range req.Header {
+		for _, value := range values {
+			size += len(key) + len(value)
+		}
+	}
+	return int64(size)
+}
+
+// Invocation is an asynchronous invocation of a function and its state
+type Invocation struct {
+	ID        string  `json:"id"`
+	Namespace string  `json:"namespace,omitempty"`
+	Function  string  `json:"function"`
+	Version   string  `json:"version,omitempty"`
+	Request   Request `json:"request"`
+	Policy    Policy  `json:"policy"`
+
+	Status   Status `json:"status"`
+	Attempts int32  `json:"attempts"`
+
+	// StatusCode and Response are the status code and the beginning of the body of the last response
+	StatusCode int    `json:"statusCode,omitempty"`
+	Response   []byte `json:"response,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	NextAttemptAt time.Time `json:"nextAttemptAt,omitempty"`
+}
+
+// NewInvocation returns a pending invocation of function with a random identifier
+func NewInvocation(namespace, function, version string, req Request, policy Policy, now time.Time) *Invocation {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return &Invocation{
+		ID:            hex.EncodeToString(id),
+		Namespace:     namespace,
+		Function:      function,
+		Version:       version,
+		Request:       req,
+		Policy:        policy,
+		Status:        StatusPending,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		NextAttemptAt: now,
+	}
+}
+
+// Retry schedules the next attempt after a failed one, false when every attempt is used
+func (inv *Invocation) Retry(now time.Time) bool {
+	if inv.Attempts >= inv.Policy.MaxAttempts {
+		return false
+	}
+	inv.Status = StatusPending
+	inv.UpdatedAt = now
+	inv.NextAttemptAt = now.Add(inv.Policy.Delay(inv.Attempts))
+	return true
+}
+
+// Finish records the final status of invocation
+func (inv *Invocation) Finish(status Status, now time.Time) {
+	inv.Status = status
+	inv.UpdatedAt = now
+	inv.NextAttemptAt = time.Time{}
+}
+
+// DeepCopy bulabula
+func (inv *Invocation) DeepCopy() *Invocation {
+	out := *inv
+	out.Request.Header = inv.Request.Header.Clone()
+	out.Request.Body = append([]byte(nil), inv.Request.Body...)
+	out.Response = append([]byte(nil), inv.Response...)
+	if inv.Policy.DeadLetter != nil {
+		out.Policy.DeadLetter = inv.Policy.DeadLetter.DeepCopy()
+	}
+	return &out
+}
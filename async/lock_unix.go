@@ -0,0 +1,25 @@
+//go:build !windows
+// +build !windows
+
+package async
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockDir takes an exclusive lock of dir, held until the returned file is closed
+func lockDir(dir string) (*os.File, error) {
+	f, err := os.OpenFile(lockPath(dir), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, ErrLocked
+		}
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,10 @@
+package async
+
+import (
+	"os"
+)
+
+// lockDir opens the lock file of dir, directories are not locked on windows
+func lockDir(dir string) (*os.File, error) {
+	return os.OpenFile(lockPath(dir), os.O_CREATE|os.O_RDWR, 0644)
+}
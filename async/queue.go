@@ -0,0 +1,227 @@
+package async
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned for unknown invocations
+var ErrNotFound = errors.New("invocation not found")
+
+// ErrFull is returned for new invocations over the limits of unfinished invocations
+var ErrFull = errors.New("async: too many unfinished invocations")
+
+// DefaultRetention is how long finished invocations stay queryable
+const DefaultRetention = time.Hour
+
+// Queue stores invocations and hands pending ones out once their next attempt is due
+type Queue interface {
+	// Put stores inv, it is handed out again by Next when it is pending, ErrFull is returned for a new
+	// invocation over the limits of the queue
+	Put(ctx context.Context, inv *Invocation) error
+	// Next blocks until a pending invocation is due and returns it marked as running
+	Next(ctx context.Context) (*Invocation, error)
+	// Get returns the invocation with id or ErrNotFound
+	Get(ctx context.Context, id string) (*Invocation, error)
+}
+
+var _ Queue = &MemoryQueue{}
+
+// MemoryQueue is a queue keeping invocations in memory, they are lost with the process
+type MemoryQueue struct {
+	// Retention is how long finished invocations stay queryable, DefaultRetention if zero
+	Retention time.Duration
+	// MaxUnfinished bounds the number of pending and running invocations, unbounded if zero
+	MaxUnfinished int
+	// MaxUnfinishedBytes bounds the size of the requests of pending and running invocations, unbounded if zero
+	MaxUnfinishedBytes int64
+
+	mu              sync.Mutex
+	invocations     map[string]*Invocation
+	pending         schedule
+	finished        []*Invocation
+	unfinished      map[string]int64
+	unfinishedBytes int64
+	changed         chan struct{}
+	now             func() time.Time
+	removed         func(id string)
+}
+
+// NewMemoryQueue bulabula
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{
+		invocations: make(map[string]*Invocation),
+		unfinished:  make(map[string]int64),
+		changed:     make(chan struct{}),
+		now:         time.Now,
+	}
+}
+
+// Put bulabula
+func (q *MemoryQueue) Put(ctx context.Context, inv *Invocation) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, err := q.reserve(inv); err != nil {
+		return err
+	}
+	q.put(inv.DeepCopy())
+	q.prune()
+	return nil
+}
+
+// reserve counts a new unfinished inv against the limits ahead of put, and tells whether it did, the invocations
+// already in the queue are always accepted so that their attempts are never lost
+func (q *MemoryQueue) reserve(inv *Invocation) (bool, error) {
+	if _, ok := q.invocations[inv.ID]; ok || inv.Status.IsFinished() {
+		return false, nil
+	}
+	if _, ok := q.unfinished[inv.ID]; ok {
+		return false, nil
+	}
+	size := inv.Request.Size()
+	if q.MaxUnfinished > 0 && len(q.unfinished) >= q.MaxUnfinished {
+		return false, ErrFull
+	}
+	if q.MaxUnfinishedBytes > 0 && q.unfinishedBytes+size > q.MaxUnfinishedBytes {
+		return false, ErrFull
+	}
+	q.track(inv.ID, size, true)
+	return true, nil
+}
+
+// track records whether the invocation id of size is unfinished
+func (q *MemoryQueue) track(id string, size int64, unfinished bool) {
+	q.unfinishedBytes -= q.unfinished[id]
+	delete(q.unfinished, id)
+	if unfinished {
+		q.unfinished[id] = size
+		q.unfinishedBytes += size
+	}
+}
+
+func (q *MemoryQueue) put(inv *Invocation) {
+	q.invocations[inv.ID] = inv
+	q.track(inv.ID, inv.Request.Size(), !inv.Status.IsFinished())
+	switch {
+	case inv.Status == StatusPending:
+		heap.Push(&q.pending, scheduled{id: inv.ID, at: inv.NextAttemptAt})
+		// wakes every waiting Next up
+		close(q.changed)
+		q.changed = make(chan struct{})
+	case inv.Status.IsFinished():
+		q.finished = append(q.finished, inv)
+	}
+}
+
+// prune forgets the finished invocations older than retention, they finish in order so the oldest come first
+func (q *MemoryQueue) prune() {
+	retention := q.Retention
+	if retention == 0 {
+		retention = DefaultRetention
+	}
+	deadline := q.now().Add(-retention)
+
+	i := 0
+	for ; i < len(q.finished) && q.finished[i].UpdatedAt.Before(deadline); i++ {
+		id := q.finished[i].ID
+		if current, ok := q.invocations[id]; ok && current == q.finished[i] {
+			delete(q.invocations, id)
+			if q.removed != nil {
+				q.removed(id)
+			}
+		}
+	}
+	q.finished = q.finished[i:]
+}
+
+// Next bulabula
+func (q *MemoryQueue) Next(ctx context.Context) (*Invocation, error) {
+	for {
+		q.mu.Lock()
+		inv, wait := q.due()
+		changed := q.changed
+		q.mu.Unlock()
+		if inv != nil {
+			return inv, nil
+		}
+
+		var (
+			timer *time.Timer
+			fired <-chan time.Time
+		)
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			fired = timer.C
+		}
+		select {
+		case <-changed:
+		case <-fired:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// due pops the first due invocation, otherwise it returns how long until the first one is due, zero if none is
+// pending
+func (q *MemoryQueue) due() (*Invocation, time.Duration) {
+	now := q.now()
+	for q.pending.Len() > 0 {
+		next := q.pending[0]
+		inv, ok := q.invocations[next.id]
+		if !ok || inv.Status != StatusPending || !inv.NextAttemptAt.Equal(next.at) {
+			// superseded by a later put
+			heap.Pop(&q.pending)
+			continue
+		}
+		if wait := next.at.Sub(now); wait > 0 {
+			return nil, wait
+		}
+
+		heap.Pop(&q.pending)
+		inv.Status = StatusRunning
+		inv.UpdatedAt = now
+		return inv.DeepCopy(), 0
+	}
+	return nil, 0
+}
+
+// Get bulabula
+func (q *MemoryQueue) Get(ctx context.Context, id string) (*Invocation, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	inv, ok := q.invocations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return inv.DeepCopy(), nil
+}
+
+type scheduled struct {
+	id string
+	at time.Time
+}
+
+// schedule is a heap of pending invocations ordered by their next attempt
+type schedule []scheduled
+
+func (s schedule) Len() int            { return len(s) }
+func (s schedule) Less(i, j int) bool  { return s[i].at.Before(s[j].at) }
+func (s schedule) Swap(i, j int)       { s[i], s[j] = s[j], s[i] }
+func (s *schedule) Push(x interface{}) { *s = append(*s, x.(scheduled)) }
+func (s *schedule) Pop() interface{} {
+	old := *s
+	item := old[len(old)-1]
+	*s = old[:len(old)-1]
+	return item
+}
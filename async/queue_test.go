@@ -0,0 +1,151 @@
+package async
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPolicyDelay(t *testing.T) {
+	policy := Policy{MaxAttempts: 5, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		attempt := int32(i + 1)
+		if delay := policy.Delay(attempt); delay != want {
+			t.Errorf("delay after attempt %d must be %s, got %s", attempt, want, delay)
+		}
+	}
+}
+
+func TestMemoryQueue(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	q := NewMemoryQueue()
+	later := NewInvocation("", "later", "", Request{}, Policy{}, now.Add(100*time.Millisecond))
+	first := NewInvocation("", "first", "", Request{}, Policy{}, now)
+	for _, inv := range []*Invocation{later, first} {
+		if err := q.Put(ctx, inv); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, want := range []*Invocation{first, later} {
+		inv, err := q.Next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if inv.ID != want.ID || inv.Status != StatusRunning {
+			t.Fatalf("unexpected next invocation %s %s, want %s", inv.Function, inv.Status, want.Function)
+		}
+		if time.Now().Before(want.NextAttemptAt) {
+			t.Errorf("invocation %s must not be handed out before its next attempt", inv.Function)
+		}
+	}
+
+	short, shortCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer shortCancel()
+	if inv, err := q.Next(short); err == nil {
+		t.Fatalf("running invocations must not be handed out again, got %s", inv.Function)
+	}
+
+	first.Attempts = 1
+	first.Finish(StatusSucceeded, now.Add(-2*DefaultRetention))
+	if err := q.Put(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Get(ctx, first.ID); err != ErrNotFound {
+		t.Errorf("finished invocations must be forgotten after retention, got %v", err)
+	}
+	if inv, err := q.Get(ctx, later.ID); err != nil || inv.Status != StatusRunning {
+		t.Errorf("unexpected state of running invocation %+v %v", inv, err)
+	}
+}
+
+func TestFileQueue(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "kess-async")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	q, err := NewFileQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	running := NewInvocation("kess-samples", "sample", "v1", Request{Method: "POST", Path: "/", Body: []byte("payload")}, Policy{MaxAttempts: 3}, time.Now())
+	running.Status = StatusRunning
+	finished := NewInvocation("kess-samples", "sample", "v1", Request{Method: "POST", Path: "/"}, Policy{MaxAttempts: 3}, time.Now())
+	finished.Finish(StatusDeadLettered, time.Now())
+	for _, inv := range []*Invocation{running, finished} {
+		if err := q.Put(ctx, inv); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := NewFileQueue(dir); err != ErrLocked {
+		t.Fatalf("directory must be locked while the queue is open, got %v", err)
+	}
+	q.Close()
+
+	reopened, err := NewFileQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if inv, err := reopened.Get(ctx, finished.ID); err != nil || inv.Status != StatusDeadLettered {
+		t.Errorf("finished invocations must be kept, got %+v %v", inv, err)
+	}
+	inv, err := reopened.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv.ID != running.ID || string(inv.Request.Body) != "payload" {
+		t.Errorf("invocations running when the queue stopped must be attempted again, got %+v", inv)
+	}
+}
+
+func TestMemoryQueueLimits(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	q := NewMemoryQueue()
+	q.MaxUnfinished, q.MaxUnfinishedBytes = 2, 10
+
+	small := NewInvocation("", "small", "", Request{Body: []byte("12345")}, Policy{MaxAttempts: 3}, now)
+	large := NewInvocation("", "large", "", Request{Body: []byte("123456")}, Policy{}, now)
+	if err := q.Put(ctx, small); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Put(ctx, large); err != ErrFull {
+		t.Fatalf("invocations over the bytes limit must be refused, got %v", err)
+	}
+
+	tiny := NewInvocation("", "tiny", "", Request{Body: []byte("1")}, Policy{}, now)
+	if err := q.Put(ctx, tiny); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Put(ctx, NewInvocation("", "empty", "", Request{}, Policy{}, now)); err != ErrFull {
+		t.Fatalf("invocations over the count limit must be refused, got %v", err)
+	}
+
+	small.Attempts = 1
+	if !small.Retry(now) {
+		t.Fatal("invocation must be retried")
+	}
+	if err := q.Put(ctx, small); err != nil {
+		t.Errorf("queued invocations must always be updated, got %v", err)
+	}
+
+	small.Finish(StatusSucceeded, now)
+	if err := q.Put(ctx, small); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Put(ctx, large); err != nil {
+		t.Errorf("finished invocations must not count against the limits, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/yamajik/kess/activator"
+)
+
+var setupLog = ctrl.Log.WithName("setup")
+
+func main() {
+	var (
+		addr    string
+		backend string
+		opts    activator.Options
+	)
+	flag.StringVar(&addr, "addr", ":8000", "The address the activator proxy binds to.")
+	flag.StringVar(&backend, "backend", "", "The URL of the private service of runtime.")
+	flag.StringVar(&opts.Runtime.Name, "runtime-name", "", "The name of runtime.")
+	flag.StringVar(&opts.Runtime.Namespace, "runtime-namespace", "", "The namespace of runtime.")
+	flag.StringVar(&opts.ActivityURL, "activity-url", "", "The activity endpoint of the controller manager.")
+	flag.StringVar(&opts.TokenFile, "token-file", "/var/run/secrets/kubernetes.io/serviceaccount/token",
+		"The service account token the activity is reported with.")
+	flag.DurationVar(&opts.ActivationTimeout, "activation-timeout", 2*time.Minute,
+		"How long a request is buffered while runtime is scaled up from zero.")
+	flag.DurationVar(&opts.ReportInterval, "report-interval", 10*time.Second,
+		"How often the last activity is reported while runtime receives requests.")
+	flag.DurationVar(&opts.RetryInterval, "retry-interval", 250*time.Millisecond,
+		"How often runtime is dialed again while it is scaled up from zero.")
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	backendURL, err := url.Parse(backend)
+	if err != nil || backendURL.Host == "" {
+		setupLog.Error(err, "invalid backend", "backend", backend)
+		os.Exit(1)
+	}
+	opts.Backend = backendURL
+	if opts.Runtime == (types.NamespacedName{}) || opts.ActivityURL == "" {
+		setupLog.Info("runtime-name, runtime-namespace and activity-url are required")
+		os.Exit(1)
+	}
+
+	a := activator.New(opts, ctrl.Log.WithName("activator").WithValues("runtime", opts.Runtime))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := ctrl.SetupSignalHandler()
+	go func() {
+		<-stop
+		cancel()
+	}()
+	go a.Run(ctx)
+
+	server := &http.Server{Addr: addr, Handler: a}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	setupLog.Info("starting activator", "addr", addr, "backend", backend)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		setupLog.Error(err, "problem running activator")
+		os.Exit(1)
+	}
+}
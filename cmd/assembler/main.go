@@ -0,0 +1,49 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"os"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/yamajik/kess/assembler"
+)
+
+var setupLog = ctrl.Log.WithName("setup")
+
+func main() {
+	var (
+		configMapsDir string
+		chunksDir     string
+		outDir        string
+	)
+	flag.StringVar(&configMapsDir, "configmaps", "/kess-chunks/configmaps", "The directory the config maps are mounted under.")
+	flag.StringVar(&chunksDir, "chunks", "/kess-chunks/chunks", "The directory the chunk config maps are mounted under.")
+	flag.StringVar(&outDir, "out", "/kess-chunks/assembled", "The directory the config maps are assembled into.")
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	if err := assembler.Assemble(configMapsDir, chunksDir, outDir); err != nil {
+		setupLog.Error(err, "unable to assemble config maps")
+		os.Exit(1)
+	}
+	setupLog.Info("assembled config maps", "out", outDir)
+}
@@ -0,0 +1,137 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"net/url"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/async"
+	"github.com/yamajik/kess/gateway"
+)
+
+var (
+	scheme   = runtime.NewScheme()
+	setupLog = ctrl.Log.WithName("setup")
+)
+
+func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	_ = corev1.AddToScheme(scheme)
+}
+
+func main() {
+	var addr string
+	var metricsAddr string
+	var namespace string
+	var defaultNamespace string
+	var asyncWorkers int
+	var asyncQueueDir string
+	var asyncURL string
+	var asyncMaxUnfinished int
+	var asyncMaxUnfinishedBytes int64
+	flag.StringVar(&addr, "addr", ":8000", "The address the gateway binds to.")
+	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&namespace, "namespace", "", "The namespace of functions to route, all namespaces when empty.")
+	flag.StringVar(&defaultNamespace, "default-namespace", "default",
+		"The namespace of functions requested at /fn/{function}/{version}, --namespace when it is set.")
+	flag.IntVar(&asyncWorkers, "async-workers", 4, "The number of workers attempting asynchronous invocations.")
+	flag.StringVar(&asyncQueueDir, "async-queue-dir", "",
+		"The directory asynchronous invocations are kept in, used by a single gateway at once, they are kept in memory when empty.")
+	flag.IntVar(&asyncMaxUnfinished, "async-max-unfinished", 10000,
+		"The number of pending and running asynchronous invocations over which new ones are refused, unbounded when 0.")
+	flag.Int64Var(&asyncMaxUnfinishedBytes, "async-max-unfinished-bytes", 512<<20,
+		"The size of the requests of pending and running asynchronous invocations over which new ones are refused, unbounded when 0.")
+	flag.StringVar(&asyncURL, "async-url", "",
+		"The URL of the async gateway requests under /async/ are forwarded to, this gateway queues and attempts them when empty.")
+	flag.Parse()
+	if namespace != "" {
+		defaultNamespace = namespace
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		Scheme:             scheme,
+		MetricsBindAddress: metricsAddr,
+		Namespace:          namespace,
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to start manager")
+		os.Exit(1)
+	}
+
+	table := gateway.NewTable()
+	if err = (&gateway.Reconciler{
+		Client:    mgr.GetClient(),
+		Log:       ctrl.Log.WithName("controllers").WithName("Gateway"),
+		Table:     table,
+		Namespace: namespace,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Gateway")
+		os.Exit(1)
+	}
+
+	gw := gateway.NewGateway(table, addr, ctrl.Log.WithName("gateway"))
+	gw.Namespace = defaultNamespace
+	if asyncURL != "" {
+		target, err := url.Parse(asyncURL)
+		if err != nil {
+			setupLog.Error(err, "unable to parse async url", "url", asyncURL)
+			os.Exit(1)
+		}
+		gw.Async = gateway.NewAsyncProxy(target, ctrl.Log.WithName("async"))
+	} else {
+		memoryQueue := async.NewMemoryQueue()
+		var queue async.Queue = memoryQueue
+		if asyncQueueDir != "" {
+			fileQueue, err := async.NewFileQueue(asyncQueueDir)
+			if err != nil {
+				setupLog.Error(err, "unable to open async queue", "dir", asyncQueueDir)
+				os.Exit(1)
+			}
+			memoryQueue, queue = fileQueue.MemoryQueue, fileQueue
+		}
+		memoryQueue.MaxUnfinished, memoryQueue.MaxUnfinishedBytes = asyncMaxUnfinished, asyncMaxUnfinishedBytes
+		asyncInvoker := gateway.NewAsync(table, queue, asyncWorkers, ctrl.Log.WithName("async"))
+		asyncInvoker.Namespace = defaultNamespace
+		if err = mgr.Add(asyncInvoker); err != nil {
+			setupLog.Error(err, "unable to create async workers")
+			os.Exit(1)
+		}
+		gw.Async = asyncInvoker
+	}
+	if err = mgr.Add(gw); err != nil {
+		setupLog.Error(err, "unable to create gateway")
+		os.Exit(1)
+	}
+
+	setupLog.Info("starting gateway manager")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		setupLog.Error(err, "problem running gateway manager")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/yamajik/kess/activator"
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/controllers/operations"
+)
+
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+
+// ActivityServer receives the activity of scale-to-zero runtimes reported by their activators,
+// and records it in the last-activity annotation of runtime which RuntimeReconciler scales on,
+// reports are authenticated with the service account token of the activator of runtime
+type ActivityServer struct {
+	client.Client
+	Log  logr.Logger
+	Addr string
+
+	// MinInterval is how far the last-activity annotation of a running runtime is moved forward at least
+	MinInterval time.Duration
+
+	ops operations.ResourceOperationsInterface
+
+	mu     sync.Mutex
+	tokens map[[sha256.Size]byte]authenticatedToken
+}
+
+// authenticatedToken caches a token review, so that an activator reporting every few seconds
+// is not reviewed every time
+type authenticatedToken struct {
+	username string
+	expires  time.Time
+}
+
+// tokenCacheTTL bounds how long a reviewed token is trusted without being reviewed again
+const tokenCacheTTL = time.Minute
+
+// Resource bulabula
+func (s *ActivityServer) Resource() operations.ResourceOperationsInterface {
+	if s.ops == nil {
+		s.ops = operations.NewResourceOperations(s.Client)
+	}
+	return s.ops
+}
+
+// SetupWithManager bulabula
+func (s *ActivityServer) SetupWithManager(mgr ctrl.Manager) error {
+	return mgr.Add(s)
+}
+
+// NeedLeaderElection is false, the activity endpoint is served by every manager replica behind its service
+func (s *ActivityServer) NeedLeaderElection() bool {
+	return false
+}
+
+// Start bulabula
+func (s *ActivityServer) Start(stop <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle("/activity", s)
+	server := &http.Server{Addr: s.Addr, Handler: mux}
+
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	s.Log.Info("starting activity server", "addr", s.Addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// ServeHTTP bulabula
+func (s *ActivityServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	username, err := s.authenticate(req)
+	if err != nil {
+		s.Log.Error(err, "unable to authenticate activity report")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var report activator.Report
+	if err := json.NewDecoder(io.LimitReader(req.Body, 1<<16)).Decode(&report); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	namespacedName := types.NamespacedName{Name: report.Name, Namespace: report.Namespace}
+	rt := corev1.Runtime{ObjectMeta: metav1.ObjectMeta{Name: report.Name, Namespace: report.Namespace}}
+	if username != rt.ActivatorUsername() {
+		http.Error(w, fmt.Sprintf("%s may not report the activity of runtime %s", username, namespacedName), http.StatusForbidden)
+		return
+	}
+	if err := s.applyActivity(req.Context(), namespacedName, report.LastActivity); err != nil {
+		s.Log.Error(err, "unable to apply runtime activity", "runtime", namespacedName)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *ActivityServer) applyActivity(ctx context.Context, namespacedName types.NamespacedName, lastActivity time.Time) error {
+	var rt corev1.Runtime
+	if _, err := s.Resource().Get(ctx, namespacedName, &rt); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if !rt.ActivityOutdated(lastActivity, s.MinInterval) {
+		return nil
+	}
+
+	patch := client.MergeFrom(rt.DeepCopy())
+	if rt.Annotations == nil {
+		rt.Annotations = make(map[string]string)
+	}
+	rt.Annotations[corev1.AnnotationLastActivity] = lastActivity.UTC().Format(time.RFC3339Nano)
+	_, err := s.Resource().Patch(ctx, &rt, patch)
+	return err
+}
+
+// authenticate reviews the bearer token of req and returns the user it belongs to
+func (s *ActivityServer) authenticate(req *http.Request) (string, error) {
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == req.Header.Get("Authorization") {
+		return "", fmt.Errorf("missing bearer token")
+	}
+
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+	s.mu.Lock()
+	cached, ok := s.tokens[key]
+	s.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.username, nil
+	}
+
+	review := authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+	if _, err := s.Resource().Create(req.Context(), &review); err != nil {
+		return "", err
+	}
+	if !review.Status.Authenticated {
+		return "", fmt.Errorf("token is not authenticated: %s", review.Status.Error)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tokens == nil {
+		s.tokens = make(map[[sha256.Size]byte]authenticatedToken)
+	}
+	for k, t := range s.tokens {
+		if now.After(t.expires) {
+			delete(s.tokens, k)
+		}
+	}
+	s.tokens[key] = authenticatedToken{username: review.Status.User.Username, expires: now.Add(tokenCacheTTL)}
+	return review.Status.User.Username, nil
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"context"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/controllers/operations"
+)
+
+// applyChunks creates the chunk config maps, which are named after their content so existing ones are kept as is
+func applyChunks(ctx context.Context, ops operations.ResourceOperationsInterface, chunks []apiv1.ConfigMap) error {
+	for i := range chunks {
+		if _, err := ops.Create(ctx, &chunks[i]); err != nil && !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneChunks deletes the chunk config maps of cm its chunk index no longer references
+func pruneChunks(ctx context.Context, ops operations.ResourceOperationsInterface, cm *apiv1.ConfigMap) error {
+	var (
+		chunks     apiv1.ConfigMapList
+		referenced = make(map[string]bool)
+	)
+	for _, name := range corev1.ChunkConfigMapNames(cm) {
+		referenced[name] = true
+	}
+
+	if _, err := ops.List(ctx, &chunks, client.InNamespace(cm.Namespace), client.MatchingLabels{
+		corev1.LabelType:      corev1.TypeChunk,
+		corev1.LabelConfigMap: cm.Name,
+	}); err != nil {
+		return err
+	}
+	for i := range chunks.Items {
+		if referenced[chunks.Items[i].Name] {
+			continue
+		}
+		if _, err := ops.Delete(ctx, &chunks.Items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// secretResyncPeriod is how often the objects depending on a secret read it again, secrets are not watched
+const secretResyncPeriod = time.Minute
+
+// NewClient creates the client of the manager, which reads secrets straight from the API server instead of the cache,
+// so that the manager only gets the secrets it reads rather than listing and watching every secret of the cluster
+func NewClient(cache cache.Cache, config *rest.Config, options client.Options) (client.Client, error) {
+	c, err := client.New(config, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &client.DelegatingClient{
+		Reader: &secretsReader{
+			Reader: &client.DelegatingReader{
+				CacheReader:  cache,
+				ClientReader: c,
+			},
+			direct: c,
+		},
+		Writer:       c,
+		StatusClient: c,
+	}, nil
+}
+
+// secretsReader reads secrets through direct and every other object through the embedded reader
+type secretsReader struct {
+	client.Reader
+	direct client.Reader
+}
+
+// Get bulabula
+func (r *secretsReader) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if _, ok := obj.(*apiv1.Secret); ok {
+		return r.direct.Get(ctx, key, obj)
+	}
+	return r.Reader.Get(ctx, key, obj)
+}
+
+// List bulabula
+func (r *secretsReader) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	if _, ok := list.(*apiv1.SecretList); ok {
+		return r.direct.List(ctx, list, opts...)
+	}
+	return r.Reader.List(ctx, list, opts...)
+}
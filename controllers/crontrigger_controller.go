@@ -0,0 +1,123 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/controllers/operations"
+	"github.com/yamajik/kess/invoker"
+)
+
+// CronTriggerReconciler reconciles a CronTrigger object, invoking its function whenever a schedule is due
+type CronTriggerReconciler struct {
+	client.Client
+	Log     logr.Logger
+	Scheme  *runtime.Scheme
+	Invoker *invoker.Invoker
+
+	ops operations.ResourceOperationsInterface
+}
+
+// Resource bulabula
+func (r *CronTriggerReconciler) Resource() operations.ResourceOperationsInterface {
+	if r.ops == nil {
+		r.ops = operations.NewResourceOperations(r.Client)
+	}
+	return r.ops
+}
+
+// +kubebuilder:rbac:groups=core.kess.io,resources=crontriggers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core.kess.io,resources=crontriggers/status,verbs=get;update;patch
+
+// Reconcile bulabula
+func (r *CronTriggerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("crontrigger", req.NamespacedName)
+
+	var trigger corev1.CronTrigger
+	if _, err := r.Resource().Get(ctx, req.NamespacedName, &trigger); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if _, err := r.Resource().ApplyDefaultAll(ctx, &trigger); err != nil {
+		log.Error(err, "unable to set default for cron trigger")
+		return ctrl.Result{}, err
+	}
+
+	if !trigger.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	schedule, loc, err := trigger.Schedule()
+	if err != nil {
+		return ctrl.Result{}, r.applyStatusUnscheduled(ctx, &trigger, corev1.ReasonInvalidSchedule, err.Error())
+	}
+	if trigger.Spec.Suspend {
+		return ctrl.Result{}, r.applyStatusUnscheduled(ctx, &trigger, corev1.ReasonSuspended, "cron trigger is suspended")
+	}
+
+	now := time.Now()
+	due, next := trigger.ScheduleTimes(schedule, loc, now)
+
+	var invocation *corev1.TriggerInvocation
+	if !due.IsZero() {
+		header := http.Header{}
+		header.Set(invoker.HeaderTrigger, trigger.Name)
+		header.Set(invoker.HeaderScheduleTime, due.UTC().Format(time.RFC3339))
+
+		result := r.Invoker.Invoke(ctx, trigger.Namespace, &trigger.Spec.TriggerTarget, []byte(trigger.Spec.Payload), header)
+		invocation = &result
+		log.Info("invoked function", "schedule", due, "statusCode", result.StatusCode, "error", result.Error)
+	}
+
+	if _, err := r.Resource().Status().Update(ctx, &trigger, func() error {
+		trigger.UpdateStatusSchedule(due, next, invocation)
+		return nil
+	}); err != nil {
+		log.Error(err, "unable to update cron trigger status")
+		return ctrl.Result{}, err
+	}
+
+	if next.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: next.Sub(time.Now())}, nil
+}
+
+func (r *CronTriggerReconciler) applyStatusUnscheduled(ctx context.Context, trigger *corev1.CronTrigger, reason, message string) error {
+	_, err := r.Resource().Status().Update(ctx, trigger, func() error {
+		trigger.UpdateStatusUnscheduled(reason, message)
+		return nil
+	})
+	return err
+}
+
+// SetupWithManager bulabula
+func (r *CronTriggerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.CronTrigger{}).
+		Complete(r)
+}
@@ -1,97 +0,0 @@
-/*
-
-
-Licensed under the Apache License, Version 2.0 (the "License");
-you may not use this file except in compliance with the License.
-You may obtain a copy of the License at
-
-    http://www.apache.org/licenses/LICENSE-2.0
-
-Unless required by applicable law or agreed to in writing, software
-distributed under the License is distributed on an "AS IS" BASIS,
-WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
-See the License for the specific language governing permissions and
-limitations under the License.
-*/
-
-package controllers
-
-import (
-	"context"
-
-	"github.com/go-logr/logr"
-	corev1 "github.com/yamajik/kess/api/v1"
-	"github.com/yamajik/kess/controllers/operations"
-	appsv1 "k8s.io/api/apps/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/runtime"
-	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/client"
-)
-
-// DeploymentReconciler reconciles a Deployment object
-type DeploymentReconciler struct {
-	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
-
-	ops operations.ResourceOperationsInterface
-}
-
-// Resource bulabula
-func (r *DeploymentReconciler) Resource() operations.ResourceOperationsInterface {
-	if r.ops == nil {
-		r.ops = operations.NewResourceOperations(r.Client)
-	}
-	return r.ops
-}
-
-// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=list;get;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=apps,resources=deployments/status,verbs=update;patch
-// +kubebuilder:rbac:groups=core.kess.io,resources=runtimes,verbs=list;get;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=core.kess.io,resources=runtimes/status,verbs=update;patch
-
-// Reconcile runtime
-func (r *DeploymentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
-	ctx := context.Background()
-	log := r.Log.WithValues("deployment", req.NamespacedName)
-
-	var deploy appsv1.Deployment
-	if err := r.Get(ctx, req.NamespacedName, &deploy); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
-	}
-
-	if err := r.applyRuntime(ctx, req, &deploy); err != nil {
-		log.Error(err, "unable to apply runtime")
-		return ctrl.Result{}, err
-	}
-
-	return ctrl.Result{}, nil
-}
-
-func (r *DeploymentReconciler) applyRuntime(ctx context.Context, req ctrl.Request, deploy *appsv1.Deployment) error {
-	var rt corev1.Runtime
-
-	if _, err := r.Resource().Get(ctx, req.NamespacedName, &rt); err != nil {
-		if !apierrors.IsNotFound(err) {
-			return err
-		}
-		return nil
-	}
-
-	if _, err := r.Resource().Status().Update(ctx, &rt, func() error {
-		rt.UpdateStatusReady(deploy)
-		return nil
-	}); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// SetupWithManager runtime
-func (r *DeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&appsv1.Deployment{}).
-		Complete(r)
-}
@@ -18,16 +18,25 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/go-logr/logr"
 	apiv1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/archive"
 	"github.com/yamajik/kess/controllers/operations"
+	"github.com/yamajik/kess/git"
 )
 
 // FunctionReconciler reconciles a Function object
@@ -36,6 +45,12 @@ type FunctionReconciler struct {
 	Log    logr.Logger
 	Scheme *runtime.Scheme
 
+	// Git fetches the functions sourced from git repositories
+	Git *git.Fetcher
+
+	// Archive fetches the functions sourced from archives
+	Archive *archive.Fetcher
+
 	ops operations.ResourceOperationsInterface
 }
 
@@ -53,6 +68,7 @@ func (r *FunctionReconciler) Resource() operations.ResourceOperationsInterface {
 // +kubebuilder:rbac:groups=core.kess.io,resources=runtimes/status,verbs=update;patch
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=list;get;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=configmaps/status,verbs=update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
 
 // Reconcile bulabula
 func (r *FunctionReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -100,6 +116,10 @@ func (r *FunctionReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
+	// Secrets are not watched, a function mounting one reads it again to pick up its changes
+	if fn.RuntimeSecret() != nil {
+		return ctrl.Result{RequeueAfter: secretResyncPeriod}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
@@ -113,23 +133,195 @@ func (r *FunctionReconciler) applyStatus(ctx context.Context, fn *corev1.Functio
 	return err
 }
 
+func (r *FunctionReconciler) applyCondition(ctx context.Context, fn *corev1.Function, condition corev1.Condition) error {
+	_, err := r.Resource().Status().Update(ctx, fn, func() error {
+		fn.SetCondition(condition)
+		return nil
+	})
+	return err
+}
+
 func (r *FunctionReconciler) applyExternalResources(ctx context.Context, fn *corev1.Function) error {
-	var cm = fn.ConfigMap()
+	src, err := r.applySource(ctx, fn)
+	if err != nil {
+		return err
+	}
+	var cm = src.ConfigMap()
 
+	// Content over the config map limit moves to chunk config maps, which must exist before they are referenced
 	if _, err := r.Resource().CreateOrUpdate(ctx, &cm, func() error {
-		fn.SetConfigMap(&cm)
-		return nil
+		src.SetConfigMap(&cm)
+		return applyChunks(ctx, r.Resource(), corev1.ChunkConfigMap(&cm))
 	}); err != nil {
+		r.applyCondition(ctx, fn, corev1.NewCondition(corev1.ConditionConfigMapSynced, false, corev1.ReasonConfigMapFailed, err.Error()))
+		return err
+	}
+	if err := pruneChunks(ctx, r.Resource(), &cm); err != nil {
+		return err
+	}
+	if err := r.applyCondition(ctx, fn, corev1.NewCondition(corev1.ConditionConfigMapSynced, true, corev1.ReasonReconciled, "")); err != nil {
 		return err
 	}
 
-	if err := r.applyRuntimeStatusFunctions(ctx, fn); err != nil {
+	if err := r.applyRuntimeReference(ctx, fn); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// applySource returns function with the data fetched from its source, a git repository is read at the commit
+// function is pinned to, the ref is resolved again once the repository, ref or path changed, a path naming
+// a directory of a repository or an archive makes function of the files under it
+func (r *FunctionReconciler) applySource(ctx context.Context, fn *corev1.Function) (*corev1.Function, error) {
+	var (
+		commit          string
+		resourceVersion string
+		data            []byte
+		files           map[string][]byte
+		err             error
+	)
+	switch {
+	case fn.GitSource() != nil:
+		commit, data, files, err = r.fetchGit(ctx, fn)
+	case fn.ArchiveSource() != nil:
+		data, files, err = r.fetchArchive(ctx, fn)
+	case fn.Reference() != nil:
+		resourceVersion, data, err = r.fetchReference(ctx, fn)
+	default:
+		if fn.Status.Source == nil && fn.Status.Reference == nil {
+			return fn, nil
+		}
+		_, err := r.Resource().Status().Update(ctx, fn, func() error {
+			fn.UpdateStatusSource("")
+			fn.UpdateStatusReference("")
+			return nil
+		})
+		return fn, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if files != nil {
+		if err := fn.ValidateSourceFiles(files); err != nil {
+			r.applyCondition(ctx, fn, sourceFetchFailedCondition(err))
+			return nil, err
+		}
+	}
+
+	if _, err := r.Resource().Status().Update(ctx, fn, func() error {
+		fn.UpdateStatusSource(commit)
+		fn.UpdateStatusReference(resourceVersion)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if files != nil {
+		return fn.WithSourceFiles(files), nil
+	}
+	return fn.WithSourceData(data), nil
+}
+
+// fetchGit returns the commit function is pinned to and either the file of function or the files under
+// the directory of function at that commit
+func (r *FunctionReconciler) fetchGit(ctx context.Context, fn *corev1.Function) (string, []byte, map[string][]byte, error) {
+	gitSource := fn.GitSource()
+	repo, err := r.gitRepository(ctx, fn)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			r.applyCondition(ctx, fn, corev1.NewCondition(corev1.ConditionSourceFetched, false, corev1.ReasonSecretNotFound,
+				fmt.Sprintf("secret %q is not found", gitSource.CredentialsSecret.Name)))
+		}
+		return "", nil, nil, err
+	}
+
+	commit := fn.PinnedCommit()
+	if commit == "" {
+		if commit, err = r.Git.Resolve(ctx, repo, gitSource.Ref); err != nil {
+			r.applyCondition(ctx, fn, sourceFetchFailedCondition(err))
+			return "", nil, nil, err
+		}
+	}
+	files, err := r.Git.ReadDir(ctx, repo, commit, gitSource.Path)
+	if err == nil {
+		return commit, nil, files, nil
+	}
+	if !errors.Is(err, git.ErrNotDir) {
+		r.applyCondition(ctx, fn, sourceFetchFailedCondition(err))
+		return "", nil, nil, err
+	}
+	data, err := r.Git.ReadFile(ctx, repo, commit, gitSource.Path)
+	if err != nil {
+		r.applyCondition(ctx, fn, sourceFetchFailedCondition(err))
+		return "", nil, nil, err
+	}
+	return commit, data, nil, nil
+}
+
+// fetchArchive returns either the file of function in its archive or the files under the directory its path
+// names, once the archive is verified
+func (r *FunctionReconciler) fetchArchive(ctx context.Context, fn *corev1.Function) ([]byte, map[string][]byte, error) {
+	files, err := r.Archive.Fetch(ctx, fn.ArchiveURL(), fn.ArchiveSource().SHA256)
+	if err != nil {
+		r.applyCondition(ctx, fn, sourceFetchFailedCondition(err))
+		return nil, nil, err
+	}
+	if archivePath := fn.ArchiveSource().Path; archivePath != "" {
+		if dir := files.Dir(archivePath); len(dir) > 0 {
+			return nil, dir, nil
+		}
+	}
+	data, err := fn.ArchiveFile(files)
+	if err != nil {
+		r.applyCondition(ctx, fn, sourceFetchFailedCondition(err))
+		return nil, nil, err
+	}
+	return data, nil, nil
+}
+
+// fetchReference returns the resource version of the referenced config map or secret and the file of function
+// in a config map, the file in a secret is mounted from the secret by runtime pods and never read
+func (r *FunctionReconciler) fetchReference(ctx context.Context, fn *corev1.Function) (string, []byte, error) {
+	obj, err := getReference(ctx, r.Resource(), fn.Reference().Kind, fn.ReferenceNamespacedName())
+	if err != nil {
+		if !apierrors.IsNotFound(err) || !fn.ReferenceOptional() {
+			r.applyCondition(ctx, fn, sourceFetchFailedCondition(err))
+			return "", nil, err
+		}
+		return "", nil, nil
+	}
+	var data []byte
+	switch obj := obj.(type) {
+	case *apiv1.ConfigMap:
+		data, err = fn.ReferenceFile(obj)
+	case *apiv1.Secret:
+		err = fn.CheckReferenceSecret(obj)
+	}
+	if err != nil {
+		r.applyCondition(ctx, fn, sourceFetchFailedCondition(err))
+		return "", nil, err
+	}
+	return obj.GetResourceVersion(), data, nil
+}
+
+// gitRepository returns the git repository of function with the credentials of its secret
+func (r *FunctionReconciler) gitRepository(ctx context.Context, fn *corev1.Function) (git.Repository, error) {
+	gitSource := fn.GitSource()
+	repo := git.Repository{URL: gitSource.URL}
+
+	name := fn.GitSecretNamespacedName()
+	if name == nil {
+		return repo, nil
+	}
+	var secret apiv1.Secret
+	if _, err := r.Resource().Get(ctx, *name, &secret); err != nil {
+		return repo, err
+	}
+	repo.Username = string(secret.Data[gitSource.CredentialsSecret.UsernameKey])
+	repo.Password = string(secret.Data[gitSource.CredentialsSecret.PasswordKey])
+	return repo, nil
+}
+
 func (r *FunctionReconciler) deleteExternalResources(ctx context.Context, fn *corev1.Function) error {
 	var cm apiv1.ConfigMap
 
@@ -142,9 +334,6 @@ func (r *FunctionReconciler) deleteExternalResources(ctx context.Context, fn *co
 
 	fn.UnsetConfigMap(&cm)
 	if len(cm.Data) == 0 && len(cm.BinaryData) == 0 {
-		if err := r.deleteRuntimeStatusFunctions(ctx, fn); err != nil {
-			return err
-		}
 		if _, err := r.Resource().Delete(ctx, &cm); err != nil {
 			return err
 		}
@@ -157,19 +346,21 @@ func (r *FunctionReconciler) deleteExternalResources(ctx context.Context, fn *co
 		}
 	}
 
-	return nil
+	return pruneChunks(ctx, r.Resource(), &cm)
 }
 
-func (r *FunctionReconciler) applyRuntimeStatusFunctions(ctx context.Context, fn *corev1.Function) error {
+func (r *FunctionReconciler) applyRuntimeReference(ctx context.Context, fn *corev1.Function) error {
 	var rt corev1.Runtime
 
 	if _, err := r.Resource().Get(ctx, fn.RuntimeNamespacedName(), &rt); err != nil {
-		if apierrors.IsNotFound(err) {
-			r.Resource().Status().Update(ctx, fn, func() error {
-				fn.UpdateStatusReady(&rt)
-				return nil
-			})
+		if !apierrors.IsNotFound(err) {
+			return err
 		}
+		// The runtime watch requeues this function once the runtime is created
+		return r.applyCondition(ctx, fn, corev1.NewCondition(corev1.ConditionRuntimeFound, false, corev1.ReasonWaitingForRuntime,
+			fmt.Sprintf("waiting for runtime %q to be created", fn.Spec.Runtime)))
+	}
+	if err := r.applyCondition(ctx, fn, corev1.NewCondition(corev1.ConditionRuntimeFound, true, corev1.ReasonReconciled, "")); err != nil {
 		return err
 	}
 
@@ -179,43 +370,82 @@ func (r *FunctionReconciler) applyRuntimeStatusFunctions(ctx context.Context, fn
 		return err
 	}
 
-	if _, err := r.Resource().Status().Update(ctx, &rt, func() error {
-		rt.UpdateStatusFunctions(fn)
-		return nil
+	return nil
+}
+
+// sourceFetchFailedCondition reports why a source could not be fetched
+func sourceFetchFailedCondition(err error) corev1.Condition {
+	reason := corev1.ReasonFetchFailed
+	if errors.Is(err, archive.ErrChecksumMismatch) {
+		reason = corev1.ReasonChecksumMismatch
+	}
+	return corev1.NewCondition(corev1.ConditionSourceFetched, false, reason, err.Error())
+}
+
+// SetupWithManager bulabula
+func (r *FunctionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Function{}, referenceField, func(obj runtime.Object) []string {
+		return referenceIndex(obj.(*corev1.Function).Reference())
 	}); err != nil {
 		return err
 	}
 
-	// TODO: hot upgrade runtime deployment
-
-	return nil
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Function{}).
+		Watches(&source.Kind{Type: &corev1.Runtime{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.mapRuntimeToFunctions),
+		}).
+		Watches(&source.Kind{Type: &apiv1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: r.mapReferenceToFunctions(corev1.ReferenceKindConfigMap),
+		}, builder.WithPredicates(unmanagedReferences)).
+		Complete(r)
 }
 
-func (r *FunctionReconciler) deleteRuntimeStatusFunctions(ctx context.Context, fn *corev1.Function) error {
-	var runtime corev1.Runtime
+// mapReferenceToFunctions enqueues the functions referencing an object of kind which changed since they read it,
+// the functions are listed from the index of their references
+func (r *FunctionReconciler) mapReferenceToFunctions(kind string) handler.ToRequestsFunc {
+	return func(obj handler.MapObject) []reconcile.Request {
+		var (
+			fns         corev1.FunctionList
+			inNamespace = client.InNamespace(obj.Meta.GetNamespace())
+			matchFields = client.MatchingFields{referenceField: referenceKey(kind, obj.Meta.GetName())}
+		)
+
+		if _, err := r.Resource().List(context.Background(), &fns, inNamespace, matchFields); err != nil {
+			r.Log.Error(err, "unable to list functions of reference", "kind", kind, "name", obj.Meta.GetName())
+			return nil
+		}
 
-	if _, err := r.Resource().Get(ctx, fn.RuntimeNamespacedName(), &runtime); err != nil {
-		if !apierrors.IsNotFound(err) {
-			return err
+		var requests []reconcile.Request
+		for _, fn := range fns.Items {
+			if fn.ReferenceChanged(kind, obj.Meta.GetName(), obj.Meta.GetResourceVersion()) {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: fn.Name, Namespace: fn.Namespace},
+				})
+			}
 		}
-		return nil
+		return requests
 	}
+}
+
+// mapRuntimeToFunctions enqueues the functions labeled with the name of runtime
+func (r *FunctionReconciler) mapRuntimeToFunctions(obj handler.MapObject) []reconcile.Request {
+	var (
+		fns         corev1.FunctionList
+		matchLabels = client.MatchingLabels{corev1.LabelRuntime: obj.Meta.GetName()}
+		inNamespace = client.InNamespace(obj.Meta.GetNamespace())
+	)
 
-	if _, err := r.Resource().Status().Update(ctx, &runtime, func() error {
-		runtime.DeleteStatusFunctions(fn)
+	if _, err := r.Resource().List(context.Background(), &fns, inNamespace, matchLabels); err != nil {
+		r.Log.Error(err, "unable to list functions of runtime", "runtime", obj.Meta.GetName())
 		return nil
-	}); err != nil {
-		return err
 	}
 
-	// TODO: hot upgrade runtime deployment
-
-	return nil
-}
-
-// SetupWithManager bulabula
-func (r *FunctionReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Function{}).
-		Complete(r)
+	requests := make([]reconcile.Request, 0, len(fns.Items))
+	for _, fn := range fns.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: fn.Name, Namespace: fn.Namespace},
+		})
+	}
+	return requests
 }
@@ -0,0 +1,136 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/controllers/operations"
+)
+
+// FunctionAliasReconciler reconciles a FunctionAlias object
+type FunctionAliasReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	ops operations.ResourceOperationsInterface
+}
+
+// Resource bulabula
+func (r *FunctionAliasReconciler) Resource() operations.ResourceOperationsInterface {
+	if r.ops == nil {
+		r.ops = operations.NewResourceOperations(r.Client)
+	}
+	return r.ops
+}
+
+// +kubebuilder:rbac:groups=core.kess.io,resources=functionaliases,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core.kess.io,resources=functionaliases/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core.kess.io,resources=functions,verbs=get;list;watch
+
+// Reconcile bulabula
+func (r *FunctionAliasReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("functionalias", req.NamespacedName)
+
+	var alias corev1.FunctionAlias
+	if _, err := r.Resource().Get(ctx, req.NamespacedName, &alias); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if _, err := r.Resource().ApplyDefaultAll(ctx, &alias); err != nil {
+		log.Error(err, "unable to set default for function alias")
+		return ctrl.Result{}, err
+	}
+
+	if !alias.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.applyStatus(ctx, &alias); err != nil {
+		log.Error(err, "unable to apply function alias status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (r *FunctionAliasReconciler) applyStatus(ctx context.Context, alias *corev1.FunctionAlias) error {
+	var (
+		fns         corev1.FunctionList
+		matchLabels = client.MatchingLabels{corev1.LabelFunction: alias.Spec.Function}
+		inNamespace = client.InNamespace(alias.Namespace)
+	)
+
+	if _, err := r.Resource().List(ctx, &fns, inNamespace, matchLabels); err != nil {
+		return err
+	}
+
+	_, err := r.Resource().Status().Update(ctx, alias, func() error {
+		alias.UpdateStatusVersions(fns.Items)
+		return nil
+	})
+	return err
+}
+
+// SetupWithManager bulabula
+func (r *FunctionAliasReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.FunctionAlias{}).
+		Watches(&source.Kind{Type: &corev1.Function{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.mapFunctionToAliases),
+		}).
+		Complete(r)
+}
+
+// mapFunctionToAliases enqueues the aliases labeled with the function name of function
+func (r *FunctionAliasReconciler) mapFunctionToAliases(obj handler.MapObject) []reconcile.Request {
+	var (
+		aliases     corev1.FunctionAliasList
+		inNamespace = client.InNamespace(obj.Meta.GetNamespace())
+	)
+
+	name, ok := obj.Meta.GetLabels()[corev1.LabelFunction]
+	if !ok || name == "" {
+		return nil
+	}
+	matchLabels := client.MatchingLabels{corev1.LabelFunction: name}
+
+	if _, err := r.Resource().List(context.Background(), &aliases, inNamespace, matchLabels); err != nil {
+		r.Log.Error(err, "unable to list aliases of function", "function", name)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(aliases.Items))
+	for _, alias := range aliases.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: alias.Name, Namespace: alias.Namespace},
+		})
+	}
+	return requests
+}
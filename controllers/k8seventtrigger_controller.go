@@ -0,0 +1,132 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/controllers/operations"
+	"github.com/yamajik/kess/dispatcher"
+	"github.com/yamajik/kess/invoker"
+)
+
+// K8sEventTriggerReconciler reconciles a K8sEventTrigger object, watching its resources through the dispatcher
+type K8sEventTriggerReconciler struct {
+	client.Client
+	Log        logr.Logger
+	Scheme     *runtime.Scheme
+	Invoker    *invoker.Invoker
+	Dispatcher *dispatcher.Dispatcher
+
+	// Dynamic and RESTMapper watch resources of any kind, default to the ones of the manager
+	Dynamic    dynamic.Interface
+	RESTMapper meta.RESTMapper
+
+	ops operations.ResourceOperationsInterface
+}
+
+// Resource bulabula
+func (r *K8sEventTriggerReconciler) Resource() operations.ResourceOperationsInterface {
+	if r.ops == nil {
+		r.ops = operations.NewResourceOperations(r.Client)
+	}
+	return r.ops
+}
+
+// +kubebuilder:rbac:groups=core.kess.io,resources=k8seventtriggers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core.kess.io,resources=k8seventtriggers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=configmaps;endpoints;events;persistentvolumeclaims;pods;services,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=daemonsets;deployments;replicasets;statefulsets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=cronjobs;jobs,verbs=get;list;watch
+
+// Reconcile bulabula
+func (r *K8sEventTriggerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("k8seventtrigger", req.NamespacedName)
+
+	var trigger corev1.K8sEventTrigger
+	if _, err := r.Resource().Get(ctx, req.NamespacedName, &trigger); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Dispatcher.Remove(req.NamespacedName)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if _, err := r.Resource().ApplyDefaultAll(ctx, &trigger); err != nil {
+		log.Error(err, "unable to set default for k8s event trigger")
+		return ctrl.Result{}, err
+	}
+
+	if !trigger.DeletionTimestamp.IsZero() {
+		r.Dispatcher.Remove(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
+	version := fmt.Sprintf("%d", trigger.Generation)
+	state := r.Dispatcher.Ensure(&trigger, version, dispatcher.K8sEvents(&trigger, r.Dynamic, r.RESTMapper, r.Invoker))
+	snapshot := state.Drain()
+
+	if _, err := r.Resource().Status().Update(ctx, &trigger, func() error {
+		var message string
+		if snapshot.Err != nil {
+			message = snapshot.Err.Error()
+		}
+		trigger.UpdateStatusWatch(snapshot.Connected, message, snapshot.LastEventTime, snapshot.Invocations)
+		return nil
+	}); err != nil {
+		log.Error(err, "unable to update k8s event trigger status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager bulabula
+func (r *K8sEventTriggerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Dynamic == nil {
+		dyn, err := dynamic.NewForConfig(mgr.GetConfig())
+		if err != nil {
+			return err
+		}
+		r.Dynamic = dyn
+	}
+	if r.RESTMapper == nil {
+		r.RESTMapper = mgr.GetRESTMapper()
+	}
+	if r.Dispatcher == nil {
+		r.Dispatcher = dispatcher.New(r.Log.WithName("dispatcher"))
+	}
+	if err := mgr.Add(r.Dispatcher); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.K8sEventTrigger{}).
+		Watches(r.Dispatcher.Source(), &handler.EnqueueRequestForObject{}).
+		Complete(r)
+}
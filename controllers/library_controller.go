@@ -18,15 +18,23 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/go-logr/logr"
 	apiv1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/archive"
 	"github.com/yamajik/kess/controllers/operations"
 )
 
@@ -36,6 +44,9 @@ type LibraryReconciler struct {
 	Log    logr.Logger
 	Scheme *runtime.Scheme
 
+	// Archive fetches the libraries sourced from archives
+	Archive *archive.Fetcher
+
 	ops operations.ResourceOperationsInterface
 }
 
@@ -53,6 +64,7 @@ func (r *LibraryReconciler) Resource() operations.ResourceOperationsInterface {
 // +kubebuilder:rbac:groups=core.kess.io,resources=runtimes/status,verbs=update;patch
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=list;get;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=configmaps/status,verbs=update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
 
 // Reconcile bulabula
 func (r *LibraryReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -100,6 +112,10 @@ func (r *LibraryReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
+	// Secrets are not watched, a library mounting one reads it again to pick up its changes
+	if lib.RuntimeSecret() != nil {
+		return ctrl.Result{RequeueAfter: secretResyncPeriod}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
@@ -113,53 +129,153 @@ func (r *LibraryReconciler) applyStatus(ctx context.Context, lib *corev1.Library
 	return err
 }
 
+func (r *LibraryReconciler) applyCondition(ctx context.Context, lib *corev1.Library, condition corev1.Condition) error {
+	_, err := r.Resource().Status().Update(ctx, lib, func() error {
+		lib.SetCondition(condition)
+		return nil
+	})
+	return err
+}
+
 func (r *LibraryReconciler) applyExternalResources(ctx context.Context, lib *corev1.Library) error {
+	src, err := r.applySource(ctx, lib)
+	if err != nil {
+		return err
+	}
 	var (
-		cm           = lib.ConfigMap()
+		cm           = src.ConfigMap()
 		patchOptions = client.PatchOptions{FieldManager: corev1.FieldManager}
 	)
 
+	// Content over the config map limit moves to chunk config maps, which must exist before they are referenced
+	chunks := corev1.ChunkConfigMap(&cm)
+	for i := range chunks {
+		ctrl.SetControllerReference(lib, &chunks[i], r.Scheme)
+	}
+	if err := applyChunks(ctx, r.Resource(), chunks); err != nil {
+		r.applyCondition(ctx, lib, corev1.NewCondition(corev1.ConditionConfigMapSynced, false, corev1.ReasonConfigMapFailed, err.Error()))
+		return err
+	}
+
 	ctrl.SetControllerReference(lib, &cm, r.Scheme)
 	if _, err := r.Resource().Patch(ctx, &cm, client.Apply, &patchOptions); err != nil {
+		r.applyCondition(ctx, lib, corev1.NewCondition(corev1.ConditionConfigMapSynced, false, corev1.ReasonConfigMapFailed, err.Error()))
+		return err
+	}
+	if err := pruneChunks(ctx, r.Resource(), &cm); err != nil {
+		return err
+	}
+	if err := r.applyCondition(ctx, lib, corev1.NewCondition(corev1.ConditionConfigMapSynced, true, corev1.ReasonReconciled, "")); err != nil {
 		return err
 	}
 
-	if err := r.applyRuntimeStatusLibraries(ctx, lib); err != nil {
+	if err := r.applyRuntimeReference(ctx, lib); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// applySource returns library with the files of its archive under path once the archive is verified,
+// or with the files of the config map or secret it references
+func (r *LibraryReconciler) applySource(ctx context.Context, lib *corev1.Library) (*corev1.Library, error) {
+	if lib.Reference() != nil {
+		return r.applyReference(ctx, lib)
+	}
+	if lib.Status.Reference != nil {
+		if _, err := r.Resource().Status().Update(ctx, lib, func() error {
+			lib.UpdateStatusReference("")
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	archiveSource := lib.ArchiveSource()
+	if archiveSource == nil {
+		return lib, nil
+	}
+
+	files, err := r.Archive.Fetch(ctx, lib.ArchiveURL(), archiveSource.SHA256)
+	if err != nil {
+		r.applyCondition(ctx, lib, sourceFetchFailedCondition(err))
+		return nil, err
+	}
+	src, err := lib.WithSourceFiles(files.Dir(archiveSource.Path))
+	if err != nil {
+		r.applyCondition(ctx, lib, sourceFetchFailedCondition(err))
+		return nil, err
+	}
+
+	if err := r.applyCondition(ctx, lib, corev1.NewCondition(corev1.ConditionSourceFetched, true, corev1.ReasonReconciled, "")); err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
+// applyReference returns library with the files of the config map it references, or without files when it
+// references a secret, which runtime pods mount from the secret, and records the resource version they were read at
+func (r *LibraryReconciler) applyReference(ctx context.Context, lib *corev1.Library) (*corev1.Library, error) {
+	obj, err := getReference(ctx, r.Resource(), lib.Reference().Kind, lib.ReferenceNamespacedName())
+	if err != nil {
+		r.applyCondition(ctx, lib, sourceFetchFailedCondition(err))
+		return nil, err
+	}
+	var files map[string][]byte
+	switch obj := obj.(type) {
+	case *apiv1.ConfigMap:
+		files, err = lib.ReferenceFiles(obj)
+	case *apiv1.Secret:
+		err = lib.CheckReferenceSecret(obj)
+	}
+	if err != nil {
+		r.applyCondition(ctx, lib, sourceFetchFailedCondition(err))
+		return nil, err
+	}
+	src, err := lib.WithSourceFiles(files)
+	if err != nil {
+		r.applyCondition(ctx, lib, sourceFetchFailedCondition(err))
+		return nil, err
+	}
+
+	if _, err := r.Resource().Status().Update(ctx, lib, func() error {
+		lib.UpdateStatusReference(obj.GetResourceVersion())
+		lib.SetCondition(corev1.NewCondition(corev1.ConditionSourceFetched, true, corev1.ReasonReconciled, ""))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
 func (r *LibraryReconciler) deleteExternalResources(ctx context.Context, lib *corev1.Library) error {
 	var (
 		cm            apiv1.ConfigMap
 		deleteOptions = client.DeleteOptions{}
 	)
 
-	if err := r.deleteRuntimeStatusLibraries(ctx, lib); err != nil {
-		if !apierrors.IsNotFound(err) {
-			return err
-		}
-		return nil
-	}
-
 	if _, err := r.Resource().GetAndDelete(ctx, lib.ConfigMapNamespacedName(), &cm, &deleteOptions); err != nil {
 		return err
 	}
 
-	return nil
+	// Without a chunk index every chunk of the config map is pruned
+	return pruneChunks(ctx, r.Resource(), &apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:      lib.ConfigMapNamespacedName().Name,
+		Namespace: lib.Namespace,
+	}})
 }
 
-func (r *LibraryReconciler) applyRuntimeStatusLibraries(ctx context.Context, lib *corev1.Library) error {
+func (r *LibraryReconciler) applyRuntimeReference(ctx context.Context, lib *corev1.Library) error {
 	var rt corev1.Runtime
 	if _, err := r.Resource().Get(ctx, lib.RuntimeNamespacedName(), &rt); err != nil {
-		if apierrors.IsNotFound(err) {
-			r.Resource().Status().Update(ctx, lib, func() error {
-				lib.UpdateStatusReady(&rt)
-				return nil
-			})
+		if !apierrors.IsNotFound(err) {
+			return err
 		}
+		// The runtime watch requeues this library once the runtime is created
+		return r.applyCondition(ctx, lib, corev1.NewCondition(corev1.ConditionRuntimeFound, false, corev1.ReasonWaitingForRuntime,
+			fmt.Sprintf("waiting for runtime %q to be created", lib.Spec.Runtime)))
+	}
+	if err := r.applyCondition(ctx, lib, corev1.NewCondition(corev1.ConditionRuntimeFound, true, corev1.ReasonReconciled, "")); err != nil {
 		return err
 	}
 
@@ -169,40 +285,73 @@ func (r *LibraryReconciler) applyRuntimeStatusLibraries(ctx context.Context, lib
 		return err
 	}
 
-	if _, err := r.Resource().Status().Update(ctx, &rt, func() error {
-		rt.UpdateStatusLibraries(lib)
-		return nil
+	return nil
+}
+
+// SetupWithManager bulabula
+func (r *LibraryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Library{}, referenceField, func(obj runtime.Object) []string {
+		return referenceIndex(obj.(*corev1.Library).Reference())
 	}); err != nil {
 		return err
 	}
 
-	// TODO: hot update runtime deployment
-
-	return nil
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Library{}).
+		Watches(&source.Kind{Type: &corev1.Runtime{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.mapRuntimeToLibraries),
+		}).
+		Watches(&source.Kind{Type: &apiv1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: r.mapReferenceToLibraries(corev1.ReferenceKindConfigMap),
+		}, builder.WithPredicates(unmanagedReferences)).
+		Complete(r)
 }
 
-func (r *LibraryReconciler) deleteRuntimeStatusLibraries(ctx context.Context, lib *corev1.Library) error {
-	var rt corev1.Runtime
+// mapReferenceToLibraries enqueues the libraries referencing an object of kind which changed since they read it,
+// the libraries are listed from the index of their references
+func (r *LibraryReconciler) mapReferenceToLibraries(kind string) handler.ToRequestsFunc {
+	return func(obj handler.MapObject) []reconcile.Request {
+		var (
+			libs        corev1.LibraryList
+			inNamespace = client.InNamespace(obj.Meta.GetNamespace())
+			matchFields = client.MatchingFields{referenceField: referenceKey(kind, obj.Meta.GetName())}
+		)
+
+		if _, err := r.Resource().List(context.Background(), &libs, inNamespace, matchFields); err != nil {
+			r.Log.Error(err, "unable to list libraries of reference", "kind", kind, "name", obj.Meta.GetName())
+			return nil
+		}
 
-	if _, err := r.Resource().Get(ctx, lib.RuntimeNamespacedName(), &rt); err != nil {
-		return err
+		var requests []reconcile.Request
+		for _, lib := range libs.Items {
+			if lib.ReferenceChanged(kind, obj.Meta.GetName(), obj.Meta.GetResourceVersion()) {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: lib.Name, Namespace: lib.Namespace},
+				})
+			}
+		}
+		return requests
 	}
+}
 
-	if _, err := r.Resource().Status().Update(ctx, &rt, func() error {
-		rt.DeleteStatusLibraries(lib)
+// mapRuntimeToLibraries enqueues the libraries labeled with the name of runtime
+func (r *LibraryReconciler) mapRuntimeToLibraries(obj handler.MapObject) []reconcile.Request {
+	var (
+		libs        corev1.LibraryList
+		matchLabels = client.MatchingLabels{corev1.LabelRuntime: obj.Meta.GetName()}
+		inNamespace = client.InNamespace(obj.Meta.GetNamespace())
+	)
+
+	if _, err := r.Resource().List(context.Background(), &libs, inNamespace, matchLabels); err != nil {
+		r.Log.Error(err, "unable to list libraries of runtime", "runtime", obj.Meta.GetName())
 		return nil
-	}); err != nil {
-		return err
 	}
 
-	// TODO: hot upgrade runtime deployment
-
-	return nil
-}
-
-// SetupWithManager bulabula
-func (r *LibraryReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Library{}).
-		Complete(r)
+	requests := make([]reconcile.Request, 0, len(libs.Items))
+	for _, lib := range libs.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: lib.Name, Namespace: lib.Namespace},
+		})
+	}
+	return requests
 }
@@ -0,0 +1,155 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/controllers/operations"
+	"github.com/yamajik/kess/dispatcher"
+	"github.com/yamajik/kess/invoker"
+)
+
+// MQTTTriggerReconciler reconciles a MQTTTrigger object, subscribing to its broker through the dispatcher
+type MQTTTriggerReconciler struct {
+	client.Client
+	Log        logr.Logger
+	Scheme     *runtime.Scheme
+	Invoker    *invoker.Invoker
+	Dispatcher *dispatcher.Dispatcher
+
+	ops operations.ResourceOperationsInterface
+}
+
+// Resource bulabula
+func (r *MQTTTriggerReconciler) Resource() operations.ResourceOperationsInterface {
+	if r.ops == nil {
+		r.ops = operations.NewResourceOperations(r.Client)
+	}
+	return r.ops
+}
+
+// +kubebuilder:rbac:groups=core.kess.io,resources=mqtttriggers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core.kess.io,resources=mqtttriggers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+// Reconcile bulabula
+func (r *MQTTTriggerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("mqtttrigger", req.NamespacedName)
+
+	var trigger corev1.MQTTTrigger
+	if _, err := r.Resource().Get(ctx, req.NamespacedName, &trigger); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Dispatcher.Remove(req.NamespacedName)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if _, err := r.Resource().ApplyDefaultAll(ctx, &trigger); err != nil {
+		log.Error(err, "unable to set default for mqtt trigger")
+		return ctrl.Result{}, err
+	}
+
+	if !trigger.DeletionTimestamp.IsZero() {
+		r.Dispatcher.Remove(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
+	credentials, secretVersion, err := r.getCredentials(ctx, &trigger)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "unable to get credentials of mqtt trigger")
+			return ctrl.Result{}, err
+		}
+		r.Dispatcher.Remove(req.NamespacedName)
+		message := fmt.Sprintf("secret %q is not found", trigger.Spec.CredentialsSecret.Name)
+		return ctrl.Result{RequeueAfter: secretResyncPeriod}, r.applyStatusUnsubscribed(ctx, &trigger, corev1.ReasonSecretNotFound, message)
+	}
+
+	version := fmt.Sprintf("%d/%s", trigger.Generation, secretVersion)
+	state := r.Dispatcher.Ensure(&trigger, version, dispatcher.MQTT(&trigger, credentials, r.Invoker))
+	snapshot := state.Drain()
+
+	if _, err := r.Resource().Status().Update(ctx, &trigger, func() error {
+		var message string
+		if snapshot.Err != nil {
+			message = snapshot.Err.Error()
+		}
+		trigger.UpdateStatusSubscription(snapshot.Connected, message, snapshot.LastEventTime, snapshot.Invocations)
+		return nil
+	}); err != nil {
+		log.Error(err, "unable to update mqtt trigger status")
+		return ctrl.Result{}, err
+	}
+
+	// Secrets are not watched, a trigger with credentials reads them again to pick up their changes
+	if trigger.Spec.CredentialsSecret != nil {
+		return ctrl.Result{RequeueAfter: secretResyncPeriod}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// getCredentials reads the credentials of broker and returns them with the resource version of their secret
+func (r *MQTTTriggerReconciler) getCredentials(ctx context.Context, trigger *corev1.MQTTTrigger) (dispatcher.MQTTCredentials, string, error) {
+	name := trigger.SecretNamespacedName()
+	if name == nil {
+		return dispatcher.MQTTCredentials{}, "", nil
+	}
+
+	var secret apiv1.Secret
+	if _, err := r.Resource().Get(ctx, *name, &secret); err != nil {
+		return dispatcher.MQTTCredentials{}, "", err
+	}
+	return dispatcher.MQTTCredentials{
+		Username: string(secret.Data[trigger.Spec.CredentialsSecret.UsernameKey]),
+		Password: string(secret.Data[trigger.Spec.CredentialsSecret.PasswordKey]),
+	}, secret.ResourceVersion, nil
+}
+
+func (r *MQTTTriggerReconciler) applyStatusUnsubscribed(ctx context.Context, trigger *corev1.MQTTTrigger, reason, message string) error {
+	_, err := r.Resource().Status().Update(ctx, trigger, func() error {
+		trigger.UpdateStatusUnsubscribed(reason, message)
+		return nil
+	})
+	return err
+}
+
+// SetupWithManager bulabula
+func (r *MQTTTriggerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Dispatcher == nil {
+		r.Dispatcher = dispatcher.New(r.Log.WithName("dispatcher"))
+	}
+	if err := mgr.Add(r.Dispatcher); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.MQTTTrigger{}).
+		Watches(r.Dispatcher.Source(), &handler.EnqueueRequestForObject{}).
+		Complete(r)
+}
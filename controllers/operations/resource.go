@@ -174,7 +174,7 @@ func (r *ResourceOperations) CreateOrUpdate(ctx context.Context, obj runtime.Obj
 // GetAndDelete bulabula
 func (r *ResourceOperations) GetAndDelete(ctx context.Context, key types.NamespacedName, obj runtime.Object, options ...client.DeleteOption) (Result, error) {
 	if err := r.Client.Get(ctx, key, obj); err != nil {
-		if !apierrors.IsNotFound(err) {
+		if apierrors.IsNotFound(err) {
 			return ResultNone, nil
 		}
 		return ResultNone, err
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/controllers/operations"
+)
+
+// referenceField indexes functions and libraries by the config map or secret they read their content from
+const referenceField = "spec.source.reference"
+
+// unmanagedReferences drops the config maps and secrets kess manages itself, which are never read as content
+var unmanagedReferences = predicate.NewPredicateFuncs(func(meta metav1.Object, _ runtime.Object) bool {
+	_, ok := meta.GetLabels()[corev1.LabelType]
+	return !ok
+})
+
+// referenceIndex returns the value referenceField indexes ref by
+func referenceIndex(ref *corev1.ReferenceStatus) []string {
+	if ref == nil {
+		return nil
+	}
+	return []string{referenceKey(ref.Kind, ref.Name)}
+}
+
+// referenceKey returns the value referenceField indexes the config map or secret name of kind by
+func referenceKey(kind, name string) string {
+	return kind + "/" + name
+}
+
+// referenceObject is a config map or secret read by a function or library
+type referenceObject interface {
+	metav1.Object
+	runtime.Object
+}
+
+// getReference returns the config map or secret of kind a function or library reads its content from
+func getReference(ctx context.Context, ops operations.ResourceOperationsInterface, kind string, name types.NamespacedName) (referenceObject, error) {
+	var obj referenceObject
+	switch kind {
+	case corev1.ReferenceKindConfigMap:
+		obj = &apiv1.ConfigMap{}
+	case corev1.ReferenceKindSecret:
+		obj = &apiv1.Secret{}
+	default:
+		return nil, fmt.Errorf("unknown reference kind %q", kind)
+	}
+	if _, err := ops.Get(ctx, name, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
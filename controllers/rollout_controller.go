@@ -0,0 +1,269 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/yamajik/kess/analysis"
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/controllers/operations"
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// RolloutReconciler reconciles a Rollout object
+type RolloutReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// Scraper collects the metrics of the new version from runtime pods
+	Scraper *analysis.Scraper
+
+	ops operations.ResourceOperationsInterface
+}
+
+// Resource bulabula
+func (r *RolloutReconciler) Resource() operations.ResourceOperationsInterface {
+	if r.ops == nil {
+		r.ops = operations.NewResourceOperations(r.Client)
+	}
+	return r.ops
+}
+
+// +kubebuilder:rbac:groups=core.kess.io,resources=rollouts,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core.kess.io,resources=rollouts/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core.kess.io,resources=functionaliases,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=core.kess.io,resources=functions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core.kess.io,resources=runtimes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+
+// Reconcile bulabula
+func (r *RolloutReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("rollout", req.NamespacedName)
+
+	var rollout corev1.Rollout
+	if _, err := r.Resource().Get(ctx, req.NamespacedName, &rollout); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if _, err := r.Resource().ApplyDefaultAll(ctx, &rollout); err != nil {
+		log.Error(err, "unable to set default for rollout")
+		return ctrl.Result{}, err
+	}
+
+	if !rollout.DeletionTimestamp.IsZero() || rollout.IsComplete() {
+		return ctrl.Result{}, nil
+	}
+	// Status updates, alias updates and function events requeue rollout at any time, metrics are only
+	// collected once every analysis interval
+	if wait := rollout.NextAnalysis(time.Now()); wait > 0 {
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	var alias corev1.FunctionAlias
+	if _, err := r.Resource().Get(ctx, rollout.AliasNamespacedName(), &alias); err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Error(err, "unable to get function alias")
+			return ctrl.Result{}, err
+		}
+		message := fmt.Sprintf("function alias %q is not found", rollout.Spec.Alias)
+		return ctrl.Result{}, r.applyStatusWaiting(ctx, &rollout, corev1.ReasonAliasNotFound, message)
+	}
+
+	fn, err := r.getFunction(ctx, &rollout, &alias)
+	if err != nil {
+		log.Error(err, "unable to get function of rollout")
+		return ctrl.Result{}, err
+	}
+	if fn == nil || !corev1.IsConditionTrue(fn.Status.Conditions, corev1.ConditionReady) {
+		message := fmt.Sprintf("version %s of function %q is not ready", rollout.Spec.Version, alias.Spec.Function)
+		return ctrl.Result{}, r.applyStatusWaiting(ctx, &rollout, corev1.ReasonVersionNotReady, message)
+	}
+
+	var (
+		current   *corev1.RolloutMetrics
+		scrapeErr error
+	)
+	if rollout.Spec.Analysis != nil {
+		urls, err := r.metricsURLs(ctx, &rollout, fn)
+		if err != nil {
+			log.Error(err, "unable to list metrics endpoints of rollout")
+			return ctrl.Result{}, err
+		}
+		metrics, err := r.Scraper.Scrape(ctx, urls, fn.Spec.Function, fn.Spec.Version, rollout.Spec.Analysis)
+		if err != nil {
+			log.Error(err, "unable to scrape metrics of rollout")
+			scrapeErr = err
+		} else {
+			current = &metrics
+		}
+	}
+
+	wait, err := r.applyStatusProgress(ctx, &rollout, &alias, current, scrapeErr)
+	if err != nil {
+		log.Error(err, "unable to apply rollout status")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.applyAlias(ctx, &rollout, &alias); err != nil {
+		log.Error(err, "unable to apply function alias of rollout")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: wait}, nil
+}
+
+// getFunction returns the function of the version rolled out, nil when not found
+func (r *RolloutReconciler) getFunction(ctx context.Context, rollout *corev1.Rollout, alias *corev1.FunctionAlias) (*corev1.Function, error) {
+	var (
+		fns         corev1.FunctionList
+		matchLabels = client.MatchingLabels{corev1.LabelFunction: alias.Spec.Function}
+		inNamespace = client.InNamespace(rollout.Namespace)
+	)
+
+	if _, err := r.Resource().List(ctx, &fns, inNamespace, matchLabels); err != nil {
+		return nil, err
+	}
+	for i, fn := range fns.Items {
+		if fn.Spec.Function == alias.Spec.Function && fn.Spec.Version == rollout.Spec.Version && fn.DeletionTimestamp.IsZero() {
+			return &fns.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// metricsURLs returns the metrics endpoints of the running pods of the runtime of the version rolled out
+func (r *RolloutReconciler) metricsURLs(ctx context.Context, rollout *corev1.Rollout, fn *corev1.Function) ([]string, error) {
+	var (
+		rt   corev1.Runtime
+		pods apiv1.PodList
+		urls []string
+	)
+
+	if _, err := r.Resource().Get(ctx, fn.RuntimeNamespacedName(), &rt); err != nil {
+		return nil, err
+	}
+	if _, err := r.Resource().List(ctx, &pods, client.InNamespace(rt.Namespace), client.MatchingLabels(rt.Labels())); err != nil {
+		return nil, err
+	}
+
+	port := rollout.Spec.Analysis.Port
+	if port == 0 {
+		port = rt.Spec.Port
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != apiv1.PodRunning || pod.Status.PodIP == "" || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		host := net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(port)))
+		urls = append(urls, "http://"+host+rollout.Spec.Analysis.Path)
+	}
+
+	return urls, nil
+}
+
+func (r *RolloutReconciler) applyStatusWaiting(ctx context.Context, rollout *corev1.Rollout, reason, message string) error {
+	_, err := r.Resource().Status().Update(ctx, rollout, func() error {
+		rollout.SetCondition(corev1.NewCondition(corev1.ConditionProgressing, false, reason, message))
+		rollout.SetCondition(corev1.NewCondition(corev1.ConditionReady, false, reason, message))
+		return nil
+	})
+	return err
+}
+
+func (r *RolloutReconciler) applyStatusProgress(ctx context.Context, rollout *corev1.Rollout, alias *corev1.FunctionAlias, current *corev1.RolloutMetrics, scrapeErr error) (time.Duration, error) {
+	var wait time.Duration
+
+	_, err := r.Resource().Status().Update(ctx, rollout, func() error {
+		if !rollout.IsStarted() {
+			rollout.Start(alias)
+		}
+		if scrapeErr != nil {
+			wait = rollout.FailAnalysis(time.Now(), scrapeErr)
+		} else {
+			wait = rollout.Progress(time.Now(), current)
+		}
+		return nil
+	})
+	return wait, err
+}
+
+func (r *RolloutReconciler) applyAlias(ctx context.Context, rollout *corev1.Rollout, alias *corev1.FunctionAlias) error {
+	_, err := r.Resource().Update(ctx, alias, func() error {
+		alias.Spec.Versions = rollout.AliasVersions(alias)
+		return nil
+	})
+	return err
+}
+
+// SetupWithManager bulabula
+func (r *RolloutReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Scraper == nil {
+		r.Scraper = analysis.NewScraper(5 * time.Second)
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Rollout{}).
+		Watches(&source.Kind{Type: &corev1.FunctionAlias{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.mapToRollouts),
+		}).
+		Watches(&source.Kind{Type: &corev1.Function{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.mapToRollouts),
+		}).
+		Complete(r)
+}
+
+// mapToRollouts enqueues the rollouts in progress in the namespace of obj, waiting rollouts pick up
+// their alias or function as soon as it shows up or becomes ready
+func (r *RolloutReconciler) mapToRollouts(obj handler.MapObject) []reconcile.Request {
+	var (
+		rollouts    corev1.RolloutList
+		inNamespace = client.InNamespace(obj.Meta.GetNamespace())
+	)
+
+	if _, err := r.Resource().List(context.Background(), &rollouts, inNamespace); err != nil {
+		r.Log.Error(err, "unable to list rollouts", "namespace", obj.Meta.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, rollout := range rollouts.Items {
+		if rollout.IsComplete() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: rollout.Name, Namespace: rollout.Namespace},
+		})
+	}
+	return requests
+}
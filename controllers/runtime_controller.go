@@ -17,25 +17,46 @@ limitations under the License.
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	corev1 "github.com/yamajik/kess/api/v1"
 	"github.com/yamajik/kess/controllers/operations"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	apiv1 "k8s.io/api/core/v1"
 )
 
+// signalClient is used by the Signal update strategy to post the reload endpoint of runtime pods
+var signalClient = &http.Client{Timeout: 5 * time.Second}
+
 // RuntimeReconciler reconciles a Runtime object
 type RuntimeReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
 
+	// ActivatorImage and ActivityURL configure the activator of scale-to-zero runtimes
+	ActivatorImage string
+	ActivityURL    string
+
 	ops operations.ResourceOperationsInterface
 }
 
@@ -49,10 +70,16 @@ func (r *RuntimeReconciler) Resource() operations.ResourceOperationsInterface {
 
 // +kubebuilder:rbac:groups=core.kess.io,resources=runtimes,verbs=list;get;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core.kess.io,resources=runtimes/status,verbs=update;patch
+// +kubebuilder:rbac:groups=core.kess.io,resources=functions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core.kess.io,resources=libraries,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=list;get;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=deployments/status,verbs=update;patch
 // +kubebuilder:rbac:groups="",resources=services,verbs=list;get;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=services/status,verbs=update;patch
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=list;get;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=list;get;watch;create;update;patch;delete
 
 // Reconcile runtime
 func (r *RuntimeReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -90,8 +117,26 @@ func (r *RuntimeReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, nil
 	}
 
+	if err := r.applyStatusConfigMaps(ctx, &rt); err != nil {
+		log.Error(err, "unable to apply runtime config maps status")
+		return ctrl.Result{}, err
+	}
+
+	idleAfter, err := r.applyStatusActivity(ctx, &rt)
+	if err != nil {
+		log.Error(err, "unable to apply runtime activity status")
+		return ctrl.Result{}, err
+	}
+
 	if err := r.applyExternalResources(ctx, &rt); err != nil {
 		log.Error(err, "unable to apply runtime external resources")
+		r.applyCondition(ctx, &rt, corev1.NewCondition(corev1.ConditionReady, false, corev1.ReasonDeploymentFailed, err.Error()))
+		return ctrl.Result{}, err
+	}
+
+	acknowledged, err := r.applySignal(ctx, &rt)
+	if err != nil {
+		log.Error(err, "unable to signal runtime pods")
 		return ctrl.Result{}, err
 	}
 
@@ -100,63 +145,205 @@ func (r *RuntimeReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	// Signal the pods again with backoff until kubelet updated their mounted content
+	if !acknowledged {
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// Come back once runtime becomes idle to scale it to zero
+	return ctrl.Result{RequeueAfter: idleAfter}, nil
 }
 
-func (r *RuntimeReconciler) applyStatus(ctx context.Context, rt *corev1.Runtime) error {
+func (r *RuntimeReconciler) listDependents(ctx context.Context, rt *corev1.Runtime) (*corev1.FunctionList, *corev1.LibraryList, error) {
 	var (
 		fns         corev1.FunctionList
 		libs        corev1.LibraryList
-		matchLabels = client.MatchingLabels{"kess-runtime": rt.Name}
+		matchLabels = client.MatchingLabels{corev1.LabelRuntime: rt.Name}
+		inNamespace = client.InNamespace(rt.Namespace)
 	)
 
-	if _, err := r.Resource().Status().Update(ctx, rt, func() error {
-		var deploy appsv1.Deployment
-		r.Get(ctx, rt.NamespacedName(), &deploy)
-		rt.UpdateStatusReady(&deploy)
+	if _, err := r.Resource().List(ctx, &fns, inNamespace, matchLabels); err != nil {
+		return nil, nil, err
+	}
+	if _, err := r.Resource().List(ctx, &libs, inNamespace, matchLabels); err != nil {
+		return nil, nil, err
+	}
+
+	return &fns, &libs, nil
+}
+
+func (r *RuntimeReconciler) applyStatusConfigMaps(ctx context.Context, rt *corev1.Runtime) error {
+	fns, libs, err := r.listDependents(ctx, rt)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Resource().Status().Update(ctx, rt, func() error {
+		rt.UpdateStatusConfigMaps(fns.Items, libs.Items)
+		cms, err := r.listConfigMaps(ctx, rt)
+		if err != nil {
+			return err
+		}
+		rt.UpdateStatusConfigMapChunks(cms)
+		rt.UpdateStatusConfigMapsHash(cms)
 		return nil
-	}); err != nil {
+	})
+	return err
+}
+
+func (r *RuntimeReconciler) listConfigMaps(ctx context.Context, rt *corev1.Runtime) ([]apiv1.ConfigMap, error) {
+	var cms []apiv1.ConfigMap
+
+	for _, namespacedName := range rt.ConfigMapNamespacedNames() {
+		var cm apiv1.ConfigMap
+		if _, err := r.Resource().Get(ctx, namespacedName, &cm); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			cm.Name = namespacedName.Name
+		}
+		cms = append(cms, cm)
+	}
+
+	return cms, nil
+}
+
+// applySignal posts the reload endpoint of every running pod once per config maps hash and tells whether every pod
+// confirmed it sees the signaled content, kubelet updates mounted config maps lazily so pods answering 409 Conflict
+// are signaled again later along with the others, which must not reload a config maps hash twice
+func (r *RuntimeReconciler) applySignal(ctx context.Context, rt *corev1.Runtime) (bool, error) {
+	var (
+		pods        apiv1.PodList
+		hash        = rt.Status.ConfigMapsHash
+		matchLabels = client.MatchingLabels(rt.Labels())
+		inNamespace = client.InNamespace(rt.Namespace)
+	)
+
+	if rt.Spec.UpdateStrategy.Type != corev1.SignalRuntimeUpdateStrategyType || rt.Status.SignaledHash == hash {
+		return true, nil
+	}
+
+	if _, err := r.Resource().List(ctx, &pods, inNamespace, matchLabels); err != nil {
+		return false, err
+	}
+
+	acknowledged := true
+	contentHashes := rt.ContentHashes()
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != apiv1.PodRunning || pod.Status.PodIP == "" || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		host := net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(rt.Spec.Port)))
+		err := signal(ctx, "http://"+host+rt.Spec.UpdateStrategy.Path, hash, contentHashes)
+		if err == errContentStale {
+			acknowledged = false
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("unable to signal pod %s: %v", pod.Name, err)
+		}
+	}
+	if !acknowledged {
+		return false, nil
+	}
+
+	_, err := r.Resource().Status().Update(ctx, rt, func() error {
+		rt.Status.SignaledHash = hash
+		return nil
+	})
+	return err == nil, err
+}
+
+// errContentStale tells the signaled pod does not see the content hashes in its mounted files yet
+var errContentStale = errors.New("mounted content is stale")
+
+// signalRequest is posted to the reload endpoint of runtime pods
+type signalRequest struct {
+	ConfigMapsHash string `json:"configMapsHash"`
+	// The content hash every file must hold by its path in pod
+	ContentHashes map[string]string `json:"contentHashes,omitempty"`
+}
+
+func signal(ctx context.Context, url, hash string, contentHashes map[string]string) error {
+	body, err := json.Marshal(signalRequest{ConfigMapsHash: hash, ContentHashes: contentHashes})
+	if err != nil {
 		return err
 	}
 
-	if _, err := r.Resource().List(ctx, &fns, matchLabels); err != nil {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
 		return err
 	}
-	if _, err := r.Resource().List(ctx, &libs, matchLabels); err != nil {
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := signalClient.Do(req)
+	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
-	var errors []error
-	for _, fn := range fns.Items {
-		if _, err := r.Resource().Status().Update(ctx, &fn, func() error {
-			fn.UpdateStatusReady(rt)
-			return nil
-		}); err != nil {
-			errors = append(errors, err)
-		}
+	if resp.StatusCode == http.StatusConflict {
+		return errContentStale
 	}
-	for _, lib := range libs.Items {
-		if _, err := r.Resource().Status().Update(ctx, &lib, func() error {
-			lib.UpdateStatusReady(rt)
-			return nil
-		}); err != nil {
-			errors = append(errors, err)
-		}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("reload endpoint responded %s", resp.Status)
 	}
-	if len(errors) > 0 {
-		return errors[0]
+	return nil
+}
+
+func (r *RuntimeReconciler) applyStatus(ctx context.Context, rt *corev1.Runtime) error {
+	if _, err := r.Resource().Status().Update(ctx, rt, func() error {
+		var (
+			deploy appsv1.Deployment
+			hpa    autoscalingv2beta2.HorizontalPodAutoscaler
+		)
+		r.Get(ctx, rt.NamespacedName(), &deploy)
+		rt.UpdateStatusReady(&deploy)
+		if rt.Spec.Autoscaling != nil {
+			r.Get(ctx, rt.NamespacedName(), &hpa)
+			rt.UpdateStatusAutoscaling(&hpa)
+		}
+		return nil
+	}); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+func (r *RuntimeReconciler) applyStatusActivity(ctx context.Context, rt *corev1.Runtime) (time.Duration, error) {
+	var idleAfter time.Duration
+	if _, err := r.Resource().Status().Update(ctx, rt, func() error {
+		idleAfter = rt.UpdateStatusActivity(time.Now())
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return idleAfter, nil
+}
+
+func (r *RuntimeReconciler) applyCondition(ctx context.Context, rt *corev1.Runtime, condition corev1.Condition) error {
+	_, err := r.Resource().Status().Update(ctx, rt, func() error {
+		rt.SetCondition(condition)
+		return nil
+	})
+	return err
+}
+
 func (r *RuntimeReconciler) applyExternalResources(ctx context.Context, rt *corev1.Runtime) error {
 	var (
-		deploy       = rt.Deployment()
 		svc          = rt.Service()
 		patchOptions = client.PatchOptions{FieldManager: corev1.FieldManager}
 	)
 
+	deploy, err := rt.Deployment()
+	if err != nil {
+		return err
+	}
+
+	// Force ownership so that drifted fields of the children are taken back on every reconcile
+	client.ForceOwnership.ApplyToPatch(&patchOptions)
+
 	ctrl.SetControllerReference(rt, &deploy, r.Scheme)
 	if _, err := r.Resource().Patch(ctx, &deploy, client.Apply, &patchOptions); err != nil {
 		return err
@@ -167,6 +354,66 @@ func (r *RuntimeReconciler) applyExternalResources(ctx context.Context, rt *core
 		return err
 	}
 
+	if err := r.applyActivator(ctx, rt, &patchOptions); err != nil {
+		return err
+	}
+
+	if rt.Spec.Autoscaling == nil {
+		var hpa autoscalingv2beta2.HorizontalPodAutoscaler
+		if _, err := r.Resource().GetAndDelete(ctx, rt.NamespacedName(), &hpa, &client.DeleteOptions{}); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	hpa := rt.HorizontalPodAutoscaler()
+	ctrl.SetControllerReference(rt, &hpa, r.Scheme)
+	if _, err := r.Resource().Patch(ctx, &hpa, client.Apply, &patchOptions); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// applyActivator puts the activator behind the runtime service when scale-to-zero is enabled, and removes it otherwise
+func (r *RuntimeReconciler) applyActivator(ctx context.Context, rt *corev1.Runtime, patchOptions *client.PatchOptions) error {
+	if rt.Spec.ScaleToZero == nil {
+		var (
+			activator     appsv1.Deployment
+			activatorSA   apiv1.ServiceAccount
+			privateSvc    apiv1.Service
+			deleteOptions = client.DeleteOptions{}
+		)
+		if _, err := r.Resource().GetAndDelete(ctx, rt.ActivatorNamespacedName(), &activator, &deleteOptions); err != nil {
+			return err
+		}
+		if _, err := r.Resource().GetAndDelete(ctx, rt.ActivatorNamespacedName(), &activatorSA, &deleteOptions); err != nil {
+			return err
+		}
+		if _, err := r.Resource().GetAndDelete(ctx, rt.PrivateServiceNamespacedName(), &privateSvc, &deleteOptions); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	privateSvc := rt.PrivateService()
+	ctrl.SetControllerReference(rt, &privateSvc, r.Scheme)
+	if _, err := r.Resource().Patch(ctx, &privateSvc, client.Apply, patchOptions); err != nil {
+		return err
+	}
+
+	activatorSA := rt.ActivatorServiceAccount()
+	ctrl.SetControllerReference(rt, &activatorSA, r.Scheme)
+	if _, err := r.Resource().Patch(ctx, &activatorSA, client.Apply, patchOptions); err != nil {
+		return err
+	}
+
+	activator := rt.ActivatorDeployment(r.ActivatorImage, r.ActivityURL)
+	ctrl.SetControllerReference(rt, &activator, r.Scheme)
+	if _, err := r.Resource().Patch(ctx, &activator, client.Apply, patchOptions); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -174,6 +421,10 @@ func (r *RuntimeReconciler) deleteExternalResources(ctx context.Context, rt *cor
 	var (
 		deploy         appsv1.Deployment
 		svc            apiv1.Service
+		hpa            autoscalingv2beta2.HorizontalPodAutoscaler
+		activator      appsv1.Deployment
+		activatorSA    apiv1.ServiceAccount
+		privateSvc     apiv1.Service
 		namespacedName = rt.NamespacedName()
 		deleteOptions  = client.DeleteOptions{}
 	)
@@ -186,12 +437,49 @@ func (r *RuntimeReconciler) deleteExternalResources(ctx context.Context, rt *cor
 		return err
 	}
 
+	if _, err := r.Resource().GetAndDelete(ctx, namespacedName, &hpa, &deleteOptions); err != nil {
+		return err
+	}
+
+	if _, err := r.Resource().GetAndDelete(ctx, rt.ActivatorNamespacedName(), &activator, &deleteOptions); err != nil {
+		return err
+	}
+
+	if _, err := r.Resource().GetAndDelete(ctx, rt.ActivatorNamespacedName(), &activatorSA, &deleteOptions); err != nil {
+		return err
+	}
+
+	if _, err := r.Resource().GetAndDelete(ctx, rt.PrivateServiceNamespacedName(), &privateSvc, &deleteOptions); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 // SetupWithManager runtime
 func (r *RuntimeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	dependents := &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(r.mapDependentToRuntime),
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.Runtime{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&apiv1.Service{}).
+		Owns(&autoscalingv2beta2.HorizontalPodAutoscaler{}).
+		Watches(&source.Kind{Type: &corev1.Function{}}, dependents).
+		Watches(&source.Kind{Type: &corev1.Library{}}, dependents).
+		Watches(&source.Kind{Type: &apiv1.ConfigMap{}}, dependents).
 		Complete(r)
 }
+
+// mapDependentToRuntime enqueues the runtime referenced by the kess-runtime label of a function, library or config map
+func (r *RuntimeReconciler) mapDependentToRuntime(obj handler.MapObject) []reconcile.Request {
+	name, ok := obj.Meta.GetLabels()[corev1.LabelRuntime]
+	if !ok || name == "" {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: name, Namespace: obj.Meta.GetNamespace()}},
+	}
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSignalStaleContent signals a pod whose mounted content kubelet has not updated yet
+func TestSignalStaleContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kess-signal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "kess/functions/kess-hash.fn-sample")
+	write := func(hash string) {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(hash), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var reloaded []string
+	pod := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body signalRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for name, hash := range body.ContentHashes {
+			if data, err := ioutil.ReadFile(filepath.Join(dir, name)); err != nil || string(data) != hash {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+		}
+		reloaded = append(reloaded, body.ConfigMapsHash)
+	}))
+	defer pod.Close()
+
+	hashes := map[string]string{"/kess/functions/kess-hash.fn-sample": "new"}
+	write("old")
+	if err := signal(context.Background(), pod.URL, "v2", hashes); err != errContentStale {
+		t.Fatalf("a pod reading stale content must not acknowledge the signal, got %v", err)
+	}
+	if len(reloaded) != 0 {
+		t.Fatalf("a pod must not reload stale content, reloaded %v", reloaded)
+	}
+
+	write("new")
+	if err := signal(context.Background(), pod.URL, "v2", hashes); err != nil {
+		t.Fatalf("a pod reading the signaled content must acknowledge it, got %v", err)
+	}
+	if len(reloaded) != 1 || reloaded[0] != "v2" {
+		t.Errorf("pod must reload the signaled hash once, reloaded %v", reloaded)
+	}
+}
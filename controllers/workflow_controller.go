@@ -0,0 +1,87 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/controllers/operations"
+)
+
+// WorkflowReconciler reconciles a Workflow object, validating its steps
+type WorkflowReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	ops operations.ResourceOperationsInterface
+}
+
+// Resource bulabula
+func (r *WorkflowReconciler) Resource() operations.ResourceOperationsInterface {
+	if r.ops == nil {
+		r.ops = operations.NewResourceOperations(r.Client)
+	}
+	return r.ops
+}
+
+// +kubebuilder:rbac:groups=core.kess.io,resources=workflows,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core.kess.io,resources=workflows/status,verbs=get;update;patch
+
+// Reconcile bulabula
+func (r *WorkflowReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("workflow", req.NamespacedName)
+
+	var wf corev1.Workflow
+	if _, err := r.Resource().Get(ctx, req.NamespacedName, &wf); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if _, err := r.Resource().ApplyDefaultAll(ctx, &wf); err != nil {
+		log.Error(err, "unable to set default for workflow")
+		return ctrl.Result{}, err
+	}
+
+	if !wf.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	if _, err := r.Resource().Status().Update(ctx, &wf, func() error {
+		wf.UpdateStatusValidated(wf.Spec.Validate(field.NewPath("spec")))
+		return nil
+	}); err != nil {
+		log.Error(err, "unable to update workflow status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager bulabula
+func (r *WorkflowReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Workflow{}).
+		Complete(r)
+}
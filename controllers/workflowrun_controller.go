@@ -0,0 +1,210 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/controllers/operations"
+	"github.com/yamajik/kess/workflow"
+)
+
+// defaultMaxConcurrentRuns bounds the workflow runs progressing at once
+const defaultMaxConcurrentRuns = 4
+
+// WorkflowRunReconciler reconciles a WorkflowRun object, every reconcile dispatches the function steps which are
+// ready to the executor, which records the output of every step in the status of run as soon as it finished and
+// then enqueues run, from which the next reconcile carries on
+type WorkflowRunReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Executor *workflow.Executor
+
+	// MaxConcurrentRuns bounds the runs progressing at once
+	MaxConcurrentRuns int
+
+	ops operations.ResourceOperationsInterface
+}
+
+// Resource bulabula
+func (r *WorkflowRunReconciler) Resource() operations.ResourceOperationsInterface {
+	if r.ops == nil {
+		r.ops = operations.NewResourceOperations(r.Client)
+	}
+	return r.ops
+}
+
+// +kubebuilder:rbac:groups=core.kess.io,resources=workflowruns,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core.kess.io,resources=workflowruns/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core.kess.io,resources=workflows,verbs=get;list;watch
+
+// Reconcile bulabula
+func (r *WorkflowRunReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("workflowrun", req.NamespacedName)
+
+	var run corev1.WorkflowRun
+	if _, err := r.Resource().Get(ctx, req.NamespacedName, &run); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Executor.Forget(req.NamespacedName)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if _, err := r.Resource().ApplyDefaultAll(ctx, &run); err != nil {
+		log.Error(err, "unable to set default for workflow run")
+		return ctrl.Result{}, err
+	}
+
+	if !run.DeletionTimestamp.IsZero() || run.IsFinished() {
+		r.Executor.Forget(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
+	if run.Status.Workflow == nil {
+		return r.start(ctx, &run)
+	}
+
+	result := workflow.Evaluate(run.Status.Workflow, run.Input(), run.Status.Nodes)
+	if result.Finished {
+		if _, err := r.Resource().Status().Update(ctx, &run, func() error {
+			if result.Error != "" {
+				run.UpdateStatusFailed(corev1.ReasonStepFailed, result.Error, time.Now())
+			} else {
+				run.UpdateStatusSucceeded(result.Output, time.Now())
+			}
+			return nil
+		}); err != nil {
+			log.Error(err, "unable to update workflow run status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	r.Executor.Dispatch(&run, result.Invocations, r.recordNode(req.NamespacedName))
+	return ctrl.Result{}, nil
+}
+
+// recordNode returns how the nodes of the run key are recorded in its status once they finished
+func (r *WorkflowRunReconciler) recordNode(key types.NamespacedName) func(corev1.WorkflowNodeStatus) error {
+	log := r.Log.WithValues("workflowrun", key)
+
+	return func(node corev1.WorkflowNodeStatus) error {
+		run := corev1.WorkflowRun{}
+		run.Name, run.Namespace = key.Name, key.Namespace
+		_, err := r.Resource().Status().Update(context.Background(), &run, func() error {
+			if !run.IsFinished() {
+				run.UpdateStatusNode(node)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Error(err, "unable to record workflow node", "node", node.ID)
+		}
+		return err
+	}
+}
+
+// start runs the steps of workflow as they are now, later changes of workflow do not affect run
+func (r *WorkflowRunReconciler) start(ctx context.Context, run *corev1.WorkflowRun) (ctrl.Result, error) {
+	var wf corev1.Workflow
+	if _, err := r.Resource().Get(ctx, run.WorkflowNamespacedName(), &wf); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		_, err := r.Resource().Status().Update(ctx, run, func() error {
+			run.UpdateStatusPending(corev1.ReasonWorkflowNotFound, fmt.Sprintf("workflow %q is not found", run.Spec.Workflow))
+			return nil
+		})
+		return ctrl.Result{}, err
+	}
+
+	wf.Default()
+	errs := wf.Spec.Validate(field.NewPath("spec"))
+	if _, err := r.Resource().Status().Update(ctx, run, func() error {
+		if len(errs) > 0 {
+			run.UpdateStatusFailed(corev1.ReasonInvalidWorkflow, errs.ToAggregate().Error(), time.Now())
+		} else {
+			run.UpdateStatusStarted(&wf.Spec, time.Now())
+		}
+		return nil
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{Requeue: len(errs) == 0}, nil
+}
+
+// SetupWithManager bulabula
+func (r *WorkflowRunReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.MaxConcurrentRuns == 0 {
+		r.MaxConcurrentRuns = defaultMaxConcurrentRuns
+	}
+
+	if err := mgr.Add(r.Executor); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.WorkflowRun{}).
+		Watches(r.Executor.Source(), &handler.EnqueueRequestForObject{}).
+		Watches(&source.Kind{Type: &corev1.Workflow{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.mapToWorkflowRuns),
+		}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentRuns}).
+		Complete(r)
+}
+
+// mapToWorkflowRuns enqueues the runs of the workflow obj waiting for it to exist
+func (r *WorkflowRunReconciler) mapToWorkflowRuns(obj handler.MapObject) []reconcile.Request {
+	var (
+		runs        corev1.WorkflowRunList
+		inNamespace = client.InNamespace(obj.Meta.GetNamespace())
+		labels      = client.MatchingLabels{corev1.LabelWorkflow: obj.Meta.GetName()}
+	)
+
+	if _, err := r.Resource().List(context.Background(), &runs, inNamespace, labels); err != nil {
+		r.Log.Error(err, "unable to list workflow runs", "namespace", obj.Meta.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, run := range runs.Items {
+		if run.Status.Workflow != nil || run.Spec.Workflow != obj.Meta.GetName() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: run.Name, Namespace: run.Namespace},
+		})
+	}
+	return requests
+}
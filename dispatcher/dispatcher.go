@@ -0,0 +1,226 @@
+package dispatcher
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+)
+
+// DefaultBackoff is the delay before a failed subscription is run again
+const DefaultBackoff = 5 * time.Second
+
+// maxPendingInvocations bounds the invocations kept until the trigger controller drains them
+const maxPendingInvocations = 100
+
+// Object is a trigger whose subscription is run by the dispatcher
+type Object interface {
+	metav1.Object
+	runtime.Object
+}
+
+// RunFunc runs the subscription of a trigger until ctx is done or it fails, reporting to state
+type RunFunc func(ctx context.Context, state *State) error
+
+// Dispatcher runs the subscriptions of triggers in the background, restarting them when they fail and notifying
+// the trigger controller whenever their state changes
+type Dispatcher struct {
+	Log     logr.Logger
+	Backoff time.Duration
+
+	events chan event.GenericEvent
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu            sync.Mutex
+	subscriptions map[types.NamespacedName]*subscription
+}
+
+type subscription struct {
+	version string
+	state   *State
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// New bulabula
+func New(log logr.Logger) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		Log:           log,
+		Backoff:       DefaultBackoff,
+		events:        make(chan event.GenericEvent, 128),
+		ctx:           ctx,
+		cancel:        cancel,
+		subscriptions: make(map[types.NamespacedName]*subscription),
+	}
+}
+
+// Source emits an event for a trigger whenever the state of its subscription changes
+func (d *Dispatcher) Source() source.Source {
+	return &source.Channel{Source: d.events}
+}
+
+// NeedLeaderElection is true, triggers are only subscribed by the leader so that functions are invoked once
+func (d *Dispatcher) NeedLeaderElection() bool {
+	return true
+}
+
+// Start waits for stop and then stops every subscription
+func (d *Dispatcher) Start(stop <-chan struct{}) error {
+	<-stop
+	d.cancel()
+
+	d.mu.Lock()
+	subscriptions := d.subscriptions
+	d.subscriptions = make(map[types.NamespacedName]*subscription)
+	d.mu.Unlock()
+	for _, sub := range subscriptions {
+		<-sub.done
+	}
+	return nil
+}
+
+// Ensure runs the subscription of obj, a running subscription is restarted only when version changes
+func (d *Dispatcher) Ensure(obj Object, version string, run RunFunc) *State {
+	key := types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	prev, ok := d.subscriptions[key]
+	if ok {
+		if prev.version == version {
+			return prev.state
+		}
+		prev.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	sub := &subscription{
+		version: version,
+		state:   &State{notify: d.notifier(obj.DeepCopyObject().(Object))},
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	d.subscriptions[key] = sub
+	go d.run(ctx, key, sub, prev, run)
+	return sub.state
+}
+
+// Remove stops the subscription of the trigger named key
+func (d *Dispatcher) Remove(key types.NamespacedName) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if sub, ok := d.subscriptions[key]; ok {
+		sub.cancel()
+		delete(d.subscriptions, key)
+	}
+}
+
+// run runs a subscription once the one it replaces, if any, has stopped
+func (d *Dispatcher) run(ctx context.Context, key types.NamespacedName, sub, prev *subscription, run RunFunc) {
+	defer close(sub.done)
+	log := d.Log.WithValues("trigger", key)
+
+	if prev != nil {
+		<-prev.done
+	}
+
+	for {
+		err := run(ctx, sub.state)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Error(err, "subscription failed, retrying", "backoff", d.Backoff)
+		sub.state.SetConnected(false, err)
+
+		select {
+		case <-time.After(d.Backoff):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) notifier(obj Object) func() {
+	return func() {
+		select {
+		case d.events <- event.GenericEvent{Meta: obj, Object: obj}:
+		case <-d.ctx.Done():
+		}
+	}
+}
+
+// State is the state of a subscription shared with the trigger controller
+type State struct {
+	notify func()
+
+	mu          sync.Mutex
+	connected   bool
+	err         error
+	lastEvent   time.Time
+	invocations []corev1.TriggerInvocation
+}
+
+// Snapshot is the state of a subscription since it was last drained
+type Snapshot struct {
+	Connected bool
+
+	// Why the subscription is not connected, nil while connecting
+	Err error
+
+	LastEventTime time.Time
+
+	// Invocations oldest first
+	Invocations []corev1.TriggerInvocation
+}
+
+// SetConnected reports whether the subscription is connected, err is why it is not
+func (s *State) SetConnected(connected bool, err error) {
+	s.mu.Lock()
+	changed := s.connected != connected || s.err != err
+	s.connected, s.err = connected, err
+	s.mu.Unlock()
+
+	if changed {
+		s.notify()
+	}
+}
+
+// Record reports the invocation of function for a message or event received at received
+func (s *State) Record(received time.Time, invocation corev1.TriggerInvocation) {
+	s.mu.Lock()
+	s.lastEvent = received
+	s.invocations = append(s.invocations, invocation)
+	if len(s.invocations) > maxPendingInvocations {
+		s.invocations = s.invocations[len(s.invocations)-maxPendingInvocations:]
+	}
+	s.mu.Unlock()
+
+	s.notify()
+}
+
+// Drain returns the state of the subscription and forgets the invocations recorded so far
+func (s *State) Drain() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := Snapshot{
+		Connected:     s.connected,
+		Err:           s.err,
+		LastEventTime: s.lastEvent,
+		Invocations:   s.invocations,
+	}
+	s.invocations = nil
+	return snapshot
+}
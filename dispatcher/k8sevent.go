@@ -0,0 +1,129 @@
+package dispatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/invoker"
+)
+
+// k8sEventQueueSize bounds the events waiting to be delivered, informers block once it is full
+const k8sEventQueueSize = 256
+
+// K8sEvent is the body of invocations of k8s event triggers, shaped like the events of a watch
+type K8sEvent struct {
+	Type      corev1.K8sEventType        `json:"type"`
+	Object    *unstructured.Unstructured `json:"object"`
+	OldObject *unstructured.Unstructured `json:"oldObject,omitempty"`
+}
+
+// K8sEvents returns the watch of the resources of trigger in its namespace with a dynamic informer, its function
+// in the same namespace is invoked for every event, resources existing when the watch starts are not delivered as added
+func K8sEvents(trigger *corev1.K8sEventTrigger, client dynamic.Interface, mapper meta.RESTMapper, inv *invoker.Invoker) RunFunc {
+	trigger = trigger.DeepCopy()
+
+	return func(ctx context.Context, state *State) error {
+		gvk := trigger.GroupVersionKind()
+		if !trigger.Watchable() {
+			return fmt.Errorf("%s may not be watched", gvk.GroupKind())
+		}
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return err
+		}
+		if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			return fmt.Errorf("%s is not namespaced", gvk.Kind)
+		}
+		selector := labels.Everything()
+		if trigger.Spec.Selector != nil {
+			if selector, err = metav1.LabelSelectorAsSelector(trigger.Spec.Selector); err != nil {
+				return err
+			}
+		}
+
+		var (
+			synced int32
+			events = make(chan K8sEvent, k8sEventQueueSize)
+		)
+		enqueue := func(eventType corev1.K8sEventType, obj, old interface{}) {
+			if !trigger.Delivers(eventType) {
+				return
+			}
+			event := K8sEvent{Type: eventType, Object: unstructuredObject(obj), OldObject: unstructuredObject(old)}
+			if event.Object == nil || !selector.Matches(labels.Set(event.Object.GetLabels())) {
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		}
+
+		factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, 0, trigger.Namespace, func(opts *metav1.ListOptions) {
+			opts.LabelSelector = selector.String()
+		})
+		informer := factory.ForResource(mapping.Resource).Informer()
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				if atomic.LoadInt32(&synced) == 1 {
+					enqueue(corev1.AddedK8sEventType, obj, nil)
+				}
+			},
+			UpdateFunc: func(old, obj interface{}) {
+				if unstructuredObject(old).GetResourceVersion() != unstructuredObject(obj).GetResourceVersion() {
+					enqueue(corev1.UpdatedK8sEventType, obj, old)
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				enqueue(corev1.DeletedK8sEventType, obj, nil)
+			},
+		})
+
+		factory.Start(ctx.Done())
+		if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+			return ctx.Err()
+		}
+		atomic.StoreInt32(&synced, 1)
+		state.SetConnected(true, nil)
+
+		for {
+			select {
+			case event := <-events:
+				body, err := json.Marshal(event)
+				if err != nil {
+					return err
+				}
+				header := http.Header{}
+				header.Set(invoker.HeaderTrigger, trigger.Name)
+				header.Set(invoker.HeaderEventType, string(event.Type))
+				header.Set("Content-Type", "application/json")
+
+				received := time.Now()
+				state.Record(received, inv.Invoke(ctx, trigger.Namespace, &trigger.Spec.TriggerTarget, body, header))
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func unstructuredObject(obj interface{}) *unstructured.Unstructured {
+	u, _ := obj.(*unstructured.Unstructured)
+	return u
+}
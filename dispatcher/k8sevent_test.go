@@ -0,0 +1,131 @@
+package dispatcher
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/invoker"
+)
+
+func testConfigMap(name string, labels map[string]string, data string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace("kess-samples")
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	unstructured.SetNestedField(obj.Object, data, "data", "value")
+	return obj
+}
+
+func TestK8sEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	events := make(chan K8sEvent, 8)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var event K8sEvent
+		body, _ := ioutil.ReadAll(req.Body)
+		if err := json.Unmarshal(body, &event); err != nil || req.Header.Get(invoker.HeaderEventType) != string(event.Type) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		events <- event
+	}))
+	defer gateway.Close()
+
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme(), testConfigMap("existing", map[string]string{"app": "sample"}, "0"))
+
+	trigger := &corev1.K8sEventTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "configs", Namespace: "kess-samples"},
+		Spec: corev1.K8sEventTriggerSpec{
+			TriggerTarget: corev1.TriggerTarget{Function: "sample"},
+			Resource:      corev1.K8sEventResource{Version: "v1", Kind: "ConfigMap"},
+			Selector:      &metav1.LabelSelector{MatchLabels: map[string]string{"app": "sample"}},
+			Events:        []corev1.K8sEventType{corev1.AddedK8sEventType, corev1.UpdatedK8sEventType},
+		},
+	}
+	trigger.Default()
+
+	d := New(ctrl.Log)
+	stop := make(chan struct{})
+	defer func() {
+		close(stop)
+		d.Start(stop)
+	}()
+	go func() {
+		for range d.events {
+		}
+	}()
+
+	state := d.Ensure(trigger, "1", K8sEvents(trigger, client, mapper, invoker.New(gateway.URL)))
+	for !state.Drain().Connected || len(client.Actions()) < 2 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("resources are not watched")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	resource := client.Resource(gvr).Namespace("kess-samples")
+	if _, err := resource.Create(ctx, testConfigMap("other", map[string]string{"app": "other"}, "1"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resource.Create(ctx, testConfigMap("created", map[string]string{"app": "sample"}, "1"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	updated := testConfigMap("created", map[string]string{"app": "sample"}, "2")
+	updated.SetResourceVersion("2")
+	if _, err := resource.Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := resource.Delete(ctx, "created", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []struct {
+		eventType corev1.K8sEventType
+		value     string
+		old       string
+	}{
+		{corev1.AddedK8sEventType, "1", ""},
+		{corev1.UpdatedK8sEventType, "2", "1"},
+	} {
+		select {
+		case event := <-events:
+			value, _, _ := unstructured.NestedString(event.Object.Object, "data", "value")
+			var old string
+			if event.OldObject != nil {
+				old, _, _ = unstructured.NestedString(event.OldObject.Object, "data", "value")
+			}
+			if event.Type != want.eventType || event.Object.GetName() != "created" || value != want.value || old != want.old {
+				t.Errorf("unexpected %s event of %s with %q and %q, want %+v", event.Type, event.Object.GetName(), value, old, want)
+			}
+		case <-ctx.Done():
+			t.Fatalf("%s event was not delivered", want.eventType)
+		}
+	}
+
+	select {
+	case event := <-events:
+		t.Errorf("only added and updated events of selected resources must be delivered, got %s of %s", event.Type, event.Object.GetName())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,84 @@
+package dispatcher
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/invoker"
+	"github.com/yamajik/kess/mqtt"
+)
+
+// MQTTConcurrency bounds the invocations of an mqtt trigger at once, further messages are acknowledged once
+// an invocation finished
+const MQTTConcurrency = 16
+
+// MQTTCredentials bulabula
+type MQTTCredentials struct {
+	Username string
+	Password string
+}
+
+// MQTT returns the subscription of trigger to its broker, its function is invoked for every message received
+// with the topic and QoS of the message in headers, up to MQTTConcurrency messages are handled at once
+func MQTT(trigger *corev1.MQTTTrigger, credentials MQTTCredentials, inv *invoker.Invoker) RunFunc {
+	trigger = trigger.DeepCopy()
+
+	return func(ctx context.Context, state *State) error {
+		var (
+			slots   = make(chan struct{}, MQTTConcurrency)
+			stopped = make(chan struct{})
+		)
+		defer func() {
+			// wait for the invocations in flight
+			close(stopped)
+			for i := 0; i < cap(slots); i++ {
+				slots <- struct{}{}
+			}
+		}()
+
+		handler := func(msg mqtt.Message) {
+			select {
+			case slots <- struct{}{}:
+			case <-stopped:
+				return
+			}
+
+			received := time.Now()
+			go func() {
+				defer func() { <-slots }()
+
+				header := http.Header{}
+				header.Set(invoker.HeaderTrigger, trigger.Name)
+				header.Set(invoker.HeaderMQTTTopic, msg.Topic)
+				header.Set(invoker.HeaderMQTTQoS, strconv.Itoa(int(msg.QoS)))
+				state.Record(received, inv.Invoke(ctx, trigger.Namespace, &trigger.Spec.TriggerTarget, msg.Payload, header))
+			}()
+		}
+
+		client, err := mqtt.Dial(ctx, trigger.Spec.Broker, mqtt.Options{
+			ClientID: trigger.ClientID(),
+			Username: credentials.Username,
+			Password: credentials.Password,
+			Handler:  handler,
+		})
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		if _, err := client.Subscribe(ctx, trigger.Spec.Topic, byte(trigger.Spec.QoS)); err != nil {
+			return err
+		}
+		state.SetConnected(true, nil)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-client.Done():
+			return client.Err()
+		}
+	}
+}
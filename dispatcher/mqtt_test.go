@@ -0,0 +1,131 @@
+package dispatcher
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/invoker"
+	"github.com/yamajik/kess/mqtt"
+	"github.com/yamajik/kess/mqtt/mqtttest"
+)
+
+type request struct {
+	path, topic, qos, trigger, body string
+}
+
+func TestMQTT(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	clientIDs := make(chan string, 16)
+	broker := &mqtttest.Broker{Authenticate: func(clientID, username, password string) bool {
+		select {
+		case clientIDs <- clientID:
+		default:
+		}
+		return username == "kess" && password == "secret"
+	}}
+	defer broker.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go broker.Serve(l)
+
+	requests := make(chan request, 4)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		requests <- request{
+			path:    req.URL.Path,
+			topic:   req.Header.Get(invoker.HeaderMQTTTopic),
+			qos:     req.Header.Get(invoker.HeaderMQTTQoS),
+			trigger: req.Header.Get(invoker.HeaderTrigger),
+			body:    string(body),
+		}
+	}))
+	defer gateway.Close()
+
+	trigger := &corev1.MQTTTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "sensors", Namespace: "kess-samples"},
+		Spec: corev1.MQTTTriggerSpec{
+			TriggerTarget: corev1.TriggerTarget{Function: "sample", Path: "/readings"},
+			Broker:        "tcp://" + l.Addr().String(),
+			Topic:         "sensors/+/temperature",
+			QoS:           1,
+		},
+	}
+	trigger.Default()
+
+	d := New(ctrl.Log)
+	d.Backoff = 50 * time.Millisecond
+	stop := make(chan struct{})
+	defer func() {
+		close(stop)
+		d.Start(stop)
+	}()
+
+	state := d.Ensure(trigger, "1", MQTT(trigger, MQTTCredentials{Username: "kess", Password: "wrong"}, invoker.New(gateway.URL)))
+	if event := <-d.events; event.Meta.GetName() != "sensors" {
+		t.Fatalf("state changes must be notified for the trigger, got %s", event.Meta.GetName())
+	}
+	if snapshot := state.Drain(); snapshot.Connected || snapshot.Err == nil {
+		t.Fatalf("subscription with wrong credentials must fail, got %+v", snapshot)
+	}
+	if clientID := <-clientIDs; clientID != "kess-kess-samples-sensors" {
+		t.Errorf("client identifier must default to the trigger, got %s", clientID)
+	}
+
+	state = d.Ensure(trigger, "2", MQTT(trigger, MQTTCredentials{Username: "kess", Password: "secret"}, invoker.New(gateway.URL)))
+	go func() {
+		for range d.events {
+		}
+	}()
+	for !state.Drain().Connected {
+		select {
+		case <-ctx.Done():
+			t.Fatal("subscription did not connect")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	publisher, err := mqtt.Dial(ctx, trigger.Spec.Broker, mqtt.Options{ClientID: "publisher", Username: "kess", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Close()
+	if err := publisher.Publish(ctx, mqtt.Message{Topic: "sensors/kitchen/temperature", Payload: []byte("21.5"), QoS: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-requests:
+		want := request{path: "/fn/kess-samples/sample/latest/readings", topic: "sensors/kitchen/temperature", qos: "1", trigger: "sensors", body: "21.5"}
+		if got != want {
+			t.Errorf("unexpected invocation %+v, want %+v", got, want)
+		}
+	case <-ctx.Done():
+		t.Fatal("function was not invoked")
+	}
+
+	var snapshot Snapshot
+	for len(snapshot.Invocations) == 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("invocation was not recorded")
+		case <-time.After(10 * time.Millisecond):
+			snapshot = state.Drain()
+		}
+	}
+	if !snapshot.Invocations[0].Succeeded() || snapshot.LastEventTime.IsZero() {
+		t.Errorf("unexpected snapshot %+v", snapshot)
+	}
+}
@@ -0,0 +1,369 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/yamajik/kess/async"
+)
+
+// Paths of asynchronous invocations
+const (
+	AsyncPrefix            = "/async/"
+	AsyncFunctionPrefix    = AsyncPrefix + "fn/"
+	AsyncInvocationsPrefix = AsyncPrefix + "invocations/"
+)
+
+// Headers of asynchronous invocations, forwarded to the function and to dead-letter sinks
+const (
+	HeaderInvocationID       = "X-Kess-Invocation-Id"
+	HeaderAttempt            = "X-Kess-Attempt"
+	HeaderDeadLetterFunction = "X-Kess-Dead-Letter-Function"
+	HeaderDeadLetterVersion  = "X-Kess-Dead-Letter-Version"
+	HeaderDeadLetterReason   = "X-Kess-Dead-Letter-Reason"
+)
+
+// DefaultAsyncTimeout bounds every attempt of asynchronous invocations
+const DefaultAsyncTimeout = time.Minute
+
+// credentialHeaders are dropped from the requests of asynchronous invocations, which are kept until they finish
+var credentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+const (
+	// maxAsyncBody bounds the body of asynchronous invocations, which is kept until they finish
+	maxAsyncBody = 8 << 20
+	// maxAsyncResponse bounds the beginning of responses recorded in the invocation state
+	maxAsyncResponse = 4 << 10
+)
+
+// Async accepts requests to /async/fn/{namespace}/{function}/{version}/{path}, the namespace may be left out for
+// functions in Namespace as for the gateway, answering 202 with the invocation identifier, and invokes the function in the background with workers, retrying 5xx codes and connection errors
+// with backoff as its version sets, invocations failing every attempt are delivered to the dead-letter function
+// in the same namespace or url, the state of invocations is served at /async/invocations/{id}, requests over
+// the limits of the queue are answered 503 and credential headers are never kept
+type Async struct {
+	Log     logr.Logger
+	Table   *Table
+	Queue   async.Queue
+	Workers int
+	Client  *http.Client
+	// Optional namespace of the functions requested without one
+	Namespace string
+}
+
+// NewAsync bulabula
+func NewAsync(table *Table, queue async.Queue, workers int, log logr.Logger) *Async {
+	return &Async{
+		Log:     log,
+		Table:   table,
+		Queue:   queue,
+		Workers: workers,
+		Client:  &http.Client{Timeout: DefaultAsyncTimeout},
+	}
+}
+
+// NewAsyncProxy returns a handler forwarding the requests under /async/ to the async gateway at target, which holds
+// the queue of asynchronous invocations, so that every other gateway replica accepts them without a queue of its own
+func NewAsyncProxy(target *url.URL, log logr.Logger) http.Handler {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		log.Error(err, "unable to proxy async request", "host", target.Host, "path", req.URL.Path)
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	return proxy
+}
+
+// NeedLeaderElection is false, the async gateway runs as a single replica attempting the invocations it queued
+func (a *Async) NeedLeaderElection() bool {
+	return false
+}
+
+// Start runs the workers until stop is closed
+func (a *Async) Start(stop <-chan struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	a.Log.Info("starting async workers", "workers", a.Workers)
+	var wg sync.WaitGroup
+	for i := 0; i < a.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.work(ctx)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func (a *Async) work(ctx context.Context) {
+	for {
+		inv, err := a.Queue.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			a.Log.Error(err, "unable to get next invocation")
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		a.attempt(ctx, inv)
+	}
+}
+
+// attempt invokes the function of inv once and queues it again with the outcome
+func (a *Async) attempt(ctx context.Context, inv *async.Invocation) {
+	log := a.Log.WithValues("invocation", inv.ID, "namespace", inv.Namespace, "function", inv.Function, "version", inv.Version)
+
+	inv.Attempts++
+	header := inv.Request.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set(HeaderInvocationID, inv.ID)
+	header.Set(HeaderAttempt, strconv.Itoa(int(inv.Attempts)))
+
+	status, response, err := a.invoke(ctx, inv.Namespace, inv.Function, inv.Version, inv.Request.Method, inv.Request.Path, header, inv.Request.Body)
+	if ctx.Err() != nil {
+		// interrupted by shutdown, the attempt does not count
+		inv.Attempts--
+		inv.Status = async.StatusPending
+		inv.NextAttemptAt = time.Now()
+		if err := a.Queue.Put(context.Background(), inv); err != nil {
+			log.Error(err, "unable to requeue invocation")
+		}
+		return
+	}
+
+	now := time.Now()
+	inv.StatusCode, inv.Response, inv.Error = status, response, ""
+	if err != nil {
+		inv.Error = err.Error()
+	}
+	switch {
+	case err == nil && status < http.StatusBadRequest:
+		inv.Finish(async.StatusSucceeded, now)
+	case err == nil && status < http.StatusInternalServerError:
+		// client errors are not retried
+		a.deadLetter(ctx, inv)
+	case inv.Retry(now):
+		log.V(1).Info("invocation failed, retrying", "attempts", inv.Attempts, "status", status, "error", inv.Error)
+	default:
+		a.deadLetter(ctx, inv)
+	}
+
+	if err := a.Queue.Put(ctx, inv); err != nil {
+		log.Error(err, "unable to update invocation")
+	}
+}
+
+// deadLetter delivers the request of the failed inv to its dead-letter function or url
+func (a *Async) deadLetter(ctx context.Context, inv *async.Invocation) {
+	deadLetter := inv.Policy.DeadLetter
+	if deadLetter == nil {
+		inv.Finish(async.StatusFailed, time.Now())
+		return
+	}
+
+	reason := inv.Error
+	if reason == "" {
+		reason = fmt.Sprintf("function answered %d", inv.StatusCode)
+	}
+	header := inv.Request.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set(HeaderInvocationID, inv.ID)
+	header.Set(HeaderAttempt, strconv.Itoa(int(inv.Attempts)))
+	header.Set(HeaderDeadLetterFunction, inv.Function)
+	header.Set(HeaderDeadLetterVersion, inv.Version)
+	header.Set(HeaderDeadLetterReason, reason)
+
+	var (
+		status int
+		err    error
+	)
+	if deadLetter.URL != "" {
+		status, _, err = a.send(ctx, http.MethodPost, deadLetter.URL, header, inv.Request.Body, nil)
+	} else {
+		status, _, err = a.invoke(ctx, inv.Namespace, deadLetter.Function, deadLetter.Version, http.MethodPost, "/", header, inv.Request.Body)
+	}
+	if err == nil && (status < http.StatusOK || status >= http.StatusMultipleChoices) {
+		err = fmt.Errorf("dead letter answered %d", status)
+	}
+	if err != nil {
+		a.Log.Error(err, "unable to deliver invocation to dead letter", "invocation", inv.ID)
+		inv.Error = fmt.Sprintf("%s, unable to deliver to dead letter: %s", reason, err)
+		inv.Finish(async.StatusFailed, time.Now())
+		return
+	}
+	inv.Finish(async.StatusDeadLettered, time.Now())
+}
+
+// invoke sends a request to the function version in namespace routed by the table, path is under the function
+func (a *Async) invoke(ctx context.Context, namespace, function, version, method, path string, header http.Header, body []byte) (int, []byte, error) {
+	route, ok := a.Table.Lookup(namespace, function, version, header)
+	if !ok {
+		return 0, nil, fmt.Errorf("function %s/%s %s is not found", namespace, function, version)
+	}
+	setRouteHeaders(header, route)
+	return a.send(ctx, method, "http://"+route.Host+path, header, body, &route)
+}
+
+// send returns the status code and the beginning of the response of a request, observed as routed by route if set
+func (a *Async) send(ctx context.Context, method, url string, header http.Header, body []byte, route *Route) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		if route != nil {
+			observe(*route, http.StatusBadGateway)
+		}
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	if route != nil {
+		observe(*route, resp.StatusCode)
+	}
+
+	response, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxAsyncResponse))
+	io.Copy(ioutil.Discard, resp.Body)
+	return resp.StatusCode, response, err
+}
+
+// ServeHTTP bulabula
+func (a *Async) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case strings.HasPrefix(req.URL.Path, AsyncFunctionPrefix):
+		a.accept(w, req)
+	case strings.HasPrefix(req.URL.Path, AsyncInvocationsPrefix) && req.Method == http.MethodGet:
+		a.get(w, req, strings.TrimPrefix(req.URL.Path, AsyncInvocationsPrefix))
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// accept queues the request as an invocation with the retry policy of the function version it is routed to
+func (a *Async) accept(w http.ResponseWriter, req *http.Request) {
+	t, route, ok := lookupPath(a.Table, strings.TrimPrefix(req.URL.Path, strings.TrimSuffix(AsyncPrefix, "/")), a.Namespace, req.Header)
+	if !ok {
+		http.Error(w, "no function version is found at "+req.URL.Path, http.StatusNotFound)
+		return
+	}
+	rest := t.rest
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxAsyncBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if req.URL.RawQuery != "" {
+		rest += "?" + req.URL.RawQuery
+	}
+
+	header := req.Header.Clone()
+	for _, key := range credentialHeaders {
+		header.Del(key)
+	}
+
+	inv := async.NewInvocation(route.Namespace, t.function, t.version, async.Request{
+		Method: req.Method,
+		Path:   rest,
+		Header: header,
+		Body:   body,
+	}, async.PolicyFor(route.Async), time.Now())
+	err = a.Queue.Put(req.Context(), inv)
+	if err == async.ErrFull {
+		http.Error(w, "too many unfinished invocations", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		a.Log.Error(err, "unable to queue invocation", "namespace", t.namespace, "function", t.function, "version", t.version)
+		http.Error(w, "unable to queue invocation", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", AsyncInvocationsPrefix+inv.ID)
+	writeInvocation(w, http.StatusAccepted, inv)
+}
+
+func (a *Async) get(w http.ResponseWriter, req *http.Request, id string) {
+	inv, err := a.Queue.Get(req.Context(), id)
+	if err == async.ErrNotFound {
+		http.Error(w, "invocation "+id+" is not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeInvocation(w, http.StatusOK, inv)
+}
+
+// InvocationState is the state of an asynchronous invocation served by the gateway
+type InvocationState struct {
+	ID            string       `json:"id"`
+	Namespace     string       `json:"namespace"`
+	Function      string       `json:"function"`
+	Version       string       `json:"version,omitempty"`
+	Status        async.Status `json:"status"`
+	Attempts      int32        `json:"attempts"`
+	MaxAttempts   int32        `json:"maxAttempts"`
+	StatusCode    int          `json:"statusCode,omitempty"`
+	Response      string       `json:"response,omitempty"`
+	Error         string       `json:"error,omitempty"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	UpdatedAt     time.Time    `json:"updatedAt"`
+	NextAttemptAt *time.Time   `json:"nextAttemptAt,omitempty"`
+}
+
+func writeInvocation(w http.ResponseWriter, status int, inv *async.Invocation) {
+	state := InvocationState{
+		ID:          inv.ID,
+		Namespace:   inv.Namespace,
+		Function:    inv.Function,
+		Version:     inv.Version,
+		Status:      inv.Status,
+		Attempts:    inv.Attempts,
+		MaxAttempts: inv.Policy.MaxAttempts,
+		StatusCode:  inv.StatusCode,
+		Response:    string(inv.Response),
+		Error:       inv.Error,
+		CreatedAt:   inv.CreatedAt,
+		UpdatedAt:   inv.UpdatedAt,
+	}
+	if inv.Status == async.StatusPending {
+		state.NextAttemptAt = &inv.NextAttemptAt
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(state)
+}
@@ -0,0 +1,206 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/async"
+)
+
+type deadLetter struct {
+	function, reason, body string
+}
+
+func TestAsync(t *testing.T) {
+	var flaky int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Header.Get(HeaderFunction) {
+		case "flaky":
+			if atomic.AddInt32(&flaky, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			body, _ := ioutil.ReadAll(req.Body)
+			w.Write([]byte(req.URL.RequestURI() + " " + req.Header.Get(HeaderAttempt) + " " + string(body)))
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer backend.Close()
+
+	deadLetters := make(chan deadLetter, 4)
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		deadLetters <- deadLetter{
+			function: req.Header.Get(HeaderDeadLetterFunction),
+			reason:   req.Header.Get(HeaderDeadLetterReason),
+			body:     string(body),
+		}
+	}))
+	defer sink.Close()
+
+	maxAttempts := int32(3)
+	policy := &corev1.FunctionAsync{
+		MaxAttempts: &maxAttempts,
+		Backoff:     metav1.Duration{Duration: 10 * time.Millisecond},
+		MaxBackoff:  metav1.Duration{Duration: 20 * time.Millisecond},
+	}
+	deadLettered := policy.DeepCopy()
+	deadLettered.DeadLetter = &corev1.FunctionDeadLetter{URL: sink.URL}
+	host := strings.TrimPrefix(backend.URL, "http://")
+
+	table := NewTable()
+	table.Set([]Route{
+		{Namespace: "kess-samples", Function: "flaky", Version: "v1", Host: host, Async: policy},
+		{Namespace: "kess-samples", Function: "broken", Version: "v1", Host: host, Async: deadLettered},
+	}, nil)
+	asyncInvoker := NewAsync(table, async.NewMemoryQueue(), 2, ctrl.Log)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		asyncInvoker.Start(stop)
+		close(done)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+	g := NewGateway(table, "", ctrl.Log)
+	g.Async = asyncInvoker
+	asyncGateway := httptest.NewServer(g)
+	defer asyncGateway.Close()
+
+	// Requests reach the async gateway through another replica
+	target, err := url.Parse(asyncGateway.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica := NewGateway(table, "", ctrl.Log)
+	replica.Async = NewAsyncProxy(target, ctrl.Log)
+	gateway := httptest.NewServer(replica)
+	defer gateway.Close()
+
+	invoke := func(path, body string) InvocationState {
+		resp, err := http.Post(gateway.URL+path, "text/plain", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var state InvocationState
+		if resp.StatusCode != http.StatusAccepted || json.NewDecoder(resp.Body).Decode(&state) != nil {
+			t.Fatalf("%s: unexpected status %d", path, resp.StatusCode)
+		}
+		if location := resp.Header.Get("Location"); location != AsyncInvocationsPrefix+state.ID {
+			t.Errorf("%s: unexpected location %s", path, location)
+		}
+		return state
+	}
+	wait := func(id string) InvocationState {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			resp, err := http.Get(gateway.URL + AsyncInvocationsPrefix + id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var state InvocationState
+			json.NewDecoder(resp.Body).Decode(&state)
+			resp.Body.Close()
+			if state.Status.IsFinished() {
+				return state
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("invocation %s did not finish, got %+v", id, state)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if state := wait(invoke("/async/fn/kess-samples/flaky/v1/items?page=2", "payload").ID); state.Status != async.StatusSucceeded ||
+		state.Attempts != 3 || state.StatusCode != http.StatusOK || state.Response != "/items?page=2 3 payload" {
+		t.Errorf("5xx codes must be retried until the function succeeds, got %+v", state)
+	}
+
+	state := wait(invoke("/async/fn/kess-samples/broken", "lost").ID)
+	if state.Status != async.StatusDeadLettered || state.Attempts != 3 || state.StatusCode != http.StatusInternalServerError {
+		t.Errorf("invocations failing every attempt must be dead-lettered, got %+v", state)
+	}
+	select {
+	case got := <-deadLetters:
+		want := deadLetter{function: "broken", reason: "function answered 500", body: "lost"}
+		if got != want {
+			t.Errorf("unexpected dead letter %+v, want %+v", got, want)
+		}
+	default:
+		t.Error("payload must be delivered to the dead-letter sink")
+	}
+
+	for _, c := range []struct {
+		method, path string
+	}{
+		{http.MethodPost, "/async/fn/kess-samples/missing"},
+		{http.MethodGet, AsyncInvocationsPrefix + "missing"},
+	} {
+		req, _ := http.NewRequest(c.method, gateway.URL+c.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s %s: unexpected status %d", c.method, c.path, resp.StatusCode)
+		}
+	}
+}
+
+func TestAsyncLimits(t *testing.T) {
+	table := NewTable()
+	table.Set([]Route{{Namespace: "kess-samples", Function: "sample", Version: "v1", Host: "sample"}}, nil)
+	queue := async.NewMemoryQueue()
+	queue.MaxUnfinished = 1
+	g := NewGateway(table, "", ctrl.Log)
+	g.Async = NewAsync(table, queue, 0, ctrl.Log)
+	gateway := httptest.NewServer(g)
+	defer gateway.Close()
+
+	invoke := func() *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, gateway.URL+"/async/fn/kess-samples/sample/v1", strings.NewReader("payload"))
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set("Cookie", "session=secret")
+		req.Header.Set("X-Request-Id", "42")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := invoke()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+	id := strings.TrimPrefix(resp.Header.Get("Location"), AsyncInvocationsPrefix)
+	inv, err := queue.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header := inv.Request.Header; header.Get("Authorization") != "" || header.Get("Cookie") != "" || header.Get("X-Request-Id") != "42" {
+		t.Errorf("credential headers must be dropped before the invocation is kept, got %v", header)
+	}
+
+	if resp := invoke(); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("invocations over the limit of the queue must be refused, got status %d", resp.StatusCode)
+	}
+}
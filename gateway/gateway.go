@@ -0,0 +1,165 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// Headers forwarded to the runtime along with the request
+const (
+	HeaderNamespace = "X-Kess-Namespace"
+	HeaderFunction  = "X-Kess-Function"
+	HeaderVersion   = "X-Kess-Version"
+	HeaderMount     = "X-Kess-Mount"
+	HeaderFile      = "X-Kess-File"
+	HeaderHandler   = "X-Kess-Handler"
+	HeaderAlias     = "X-Kess-Alias"
+)
+
+// PathPrefix bulabula
+const PathPrefix = "/fn/"
+
+// Gateway routes /fn/{namespace}/{function}/{version}/{path} to the runtime service of the function version,
+// /fn/{namespace}/{function} and /fn/{namespace}/{function}/latest/{path} are routed to the latest version, and
+// a function alias may be used in place of the version, which splits requests between its versions by weight and
+// header rules, the namespace may be left out for functions in Namespace, a path naming a function in another
+// namespace takes precedence, requests under /async/ are served by Async when it is set, which is either the Async
+// of this gateway or a proxy to the async gateway
+type Gateway struct {
+	Log   logr.Logger
+	Table *Table
+	Addr  string
+	Async http.Handler
+	// Optional namespace of the functions requested without one
+	Namespace string
+
+	proxy *httputil.ReverseProxy
+}
+
+// NewGateway bulabula
+func NewGateway(table *Table, addr string, log logr.Logger) *Gateway {
+	g := &Gateway{Log: log, Table: table, Addr: addr}
+	g.proxy = &httputil.ReverseProxy{
+		Director: func(req *http.Request) {},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			g.Log.Error(err, "unable to proxy request", "host", req.URL.Host, "path", req.URL.Path)
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+	return g
+}
+
+// NeedLeaderElection is false, every gateway replica serves requests
+func (g *Gateway) NeedLeaderElection() bool {
+	return false
+}
+
+// Start bulabula
+func (g *Gateway) Start(stop <-chan struct{}) error {
+	server := &http.Server{Addr: g.Addr, Handler: g}
+
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	g.Log.Info("starting gateway", "addr", g.Addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// ServeHTTP bulabula
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if g.Async != nil && strings.HasPrefix(req.URL.Path, AsyncPrefix) {
+		g.Async.ServeHTTP(w, req)
+		return
+	}
+
+	t, route, ok := lookupPath(g.Table, req.URL.Path, g.Namespace, req.Header)
+	if !ok {
+		http.Error(w, "no function version is found at "+req.URL.Path, http.StatusNotFound)
+		return
+	}
+
+	out := req.Clone(req.Context())
+	out.URL.Scheme = "http"
+	out.URL.Host = route.Host
+	out.URL.Path = t.rest
+	out.URL.RawPath = ""
+	out.Host = route.Host
+	setRouteHeaders(out.Header, route)
+
+	recorder := &statusRecorder{ResponseWriter: w}
+	g.proxy.ServeHTTP(recorder, out)
+	observe(route, recorder.status)
+}
+
+// setRouteHeaders sets the headers telling the runtime which function version serves the request
+func setRouteHeaders(header http.Header, route Route) {
+	header.Set(HeaderNamespace, route.Namespace)
+	header.Set(HeaderFunction, route.Function)
+	header.Set(HeaderVersion, route.Version)
+	header.Set(HeaderMount, route.Mount)
+	header.Set(HeaderFile, route.File)
+	if route.Handler != "" {
+		header.Set(HeaderHandler, route.Handler)
+	}
+	if route.Alias != "" {
+		header.Set(HeaderAlias, route.Alias)
+	}
+}
+
+// target is a function version a request path may name, rest always starts with a slash
+type target struct {
+	namespace, function, version, rest string
+}
+
+// parsePaths returns what p may name, /fn/{namespace}/{function}[/{version}[/{rest}]] first, then
+// /fn/{function}[/{version}[/{rest}]] in namespace unless namespace is empty
+func parsePaths(p, namespace string) []target {
+	if !strings.HasPrefix(p, PathPrefix) {
+		return nil
+	}
+
+	var targets []target
+	segments := strings.SplitN(strings.TrimPrefix(p, PathPrefix), "/", 4)
+	if len(segments) >= 2 && segments[0] != "" && segments[1] != "" {
+		targets = append(targets, newTarget(segments[0], segments[1:]))
+	}
+	if namespace != "" && segments[0] != "" {
+		segments = strings.SplitN(strings.TrimPrefix(p, PathPrefix), "/", 3)
+		targets = append(targets, newTarget(namespace, segments))
+	}
+	return targets
+}
+
+// newTarget returns the target of function, version and rest segments in namespace
+func newTarget(namespace string, segments []string) target {
+	t := target{namespace: namespace, function: segments[0], rest: "/"}
+	if len(segments) > 1 {
+		t.version = segments[1]
+	}
+	if len(segments) > 2 {
+		t.rest += segments[2]
+	}
+	return t
+}
+
+// lookupPath returns the first target of p table has a route of, and the route
+func lookupPath(table *Table, p, namespace string, header http.Header) (target, Route, bool) {
+	for _, t := range parsePaths(p, namespace) {
+		if route, ok := table.Lookup(t.namespace, t.function, t.version, header); ok {
+			return t, route, true
+		}
+	}
+	return target{}, Route{}, false
+}
@@ -0,0 +1,291 @@
+package gateway
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+)
+
+func testFunction(name, runtime string) corev1.Function {
+	fn := corev1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kess-samples"},
+		Spec: corev1.FunctionSpec{
+			Runtime:   runtime,
+			File:      corev1.FunctionFile{Name: "{Version}.py"},
+			ConfigMap: corev1.FunctionConfigMap{Name: "fn-{Name}", Mount: "/kess/fn/{Name}"},
+		},
+	}
+	fn.Default()
+	return fn
+}
+
+func TestRoutes(t *testing.T) {
+	var (
+		fns = []corev1.Function{
+			testFunction("sample-v1", "sample"),
+			testFunction("sample-v2", "sample"),
+			testFunction("other-v1", "missing"),
+			testFunction("unmounted-v1", "sample"),
+		}
+		rt = corev1.Runtime{
+			ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "kess-samples"},
+			Spec:       corev1.RuntimeSpec{Port: 8000},
+		}
+	)
+	rt.UpdateStatusConfigMaps(fns[:2], nil)
+
+	routes := Routes(fns, []corev1.Runtime{rt})
+	if len(routes) != 2 {
+		t.Fatalf("only functions mounted by their runtime must be routed, got %+v", routes)
+	}
+
+	table := NewTable()
+	table.Set(routes, nil)
+	route, ok := table.Lookup("kess-samples", "sample", "v1", nil)
+	if !ok {
+		t.Fatal("sample v1 is not routed")
+	}
+	want := Route{
+		Namespace: "kess-samples",
+		Function:  "sample",
+		Version:   "v1",
+		Runtime:   "sample",
+		Host:      "sample.kess-samples.svc:8000",
+		Mount:     "/kess/fn/sample",
+		File:      "/kess/fn/sample/v1.py",
+	}
+	if route != want {
+		t.Errorf("unexpected route %+v, want %+v", route, want)
+	}
+	if _, ok := table.Lookup("kess-samples", "unmounted", "", nil); ok {
+		t.Error("unmounted function must not be routed")
+	}
+}
+
+func TestLookupLatest(t *testing.T) {
+	table := NewTable()
+	table.Set([]Route{
+		{Namespace: "kess-samples", Function: "sample", Version: "v2"},
+		{Namespace: "kess-samples", Function: "sample", Version: "v10"},
+		{Namespace: "kess-samples", Function: "sample", Version: "v9"},
+		{Namespace: "kess-samples", Function: "pinned", Version: "v2"},
+		{Namespace: "kess-samples", Function: "pinned", Version: corev1.LatestVersion},
+	}, nil)
+
+	if route, _ := table.Lookup("kess-samples", "sample", "", nil); route.Version != "v10" {
+		t.Errorf("latest version of sample must be v10, got %s", route.Version)
+	}
+	if route, _ := table.Lookup("kess-samples", "pinned", corev1.LatestVersion, nil); route.Version != corev1.LatestVersion {
+		t.Errorf("an explicit latest version must win, got %s", route.Version)
+	}
+	if _, ok := table.Lookup("kess-samples", "sample", "v3", nil); ok {
+		t.Error("unknown version must not be routed")
+	}
+}
+
+func TestLookupAlias(t *testing.T) {
+	table := NewTable()
+	table.Set([]Route{
+		{Namespace: "kess-samples", Function: "sample", Version: "v1"},
+		{Namespace: "kess-samples", Function: "sample", Version: "v2"},
+		{Namespace: "kess-samples", Function: "sample", Version: "prod"},
+	}, Aliases([]corev1.FunctionAlias{
+		testAlias("sample-prod", "v2", "v1"),
+		testAlias("sample-staging", "v3"),
+	}))
+
+	if route, ok := table.Lookup("kess-samples", "sample", "canary", nil); ok {
+		t.Fatalf("unknown alias must not be routed, got %+v", route)
+	}
+	if route, ok := table.Lookup("kess-samples", "sample", "staging", nil); ok {
+		t.Fatalf("alias without routed versions must not be routed, got %+v", route)
+	}
+	if route, _ := table.Lookup("kess-samples", "sample", "prod", nil); route.Version != "prod" || route.Alias != "" {
+		t.Fatalf("versions must take precedence over aliases, got %+v", route)
+	}
+
+	table.Set([]Route{
+		{Namespace: "kess-samples", Function: "sample", Version: "v1"},
+		{Namespace: "kess-samples", Function: "sample", Version: "v2"},
+	}, Aliases([]corev1.FunctionAlias{testAlias("sample-prod", "v2", "v1")}))
+	picked := make(map[string]bool)
+	for _, n := range []int{0, 99} {
+		n := n
+		table.pick = func(int) int { return n }
+		route, ok := table.Lookup("kess-samples", "sample", "prod", nil)
+		if !ok || route.Alias != "prod" {
+			t.Fatalf("alias prod must be routed, got %+v", route)
+		}
+		picked[route.Version] = true
+	}
+	if !picked["v1"] || !picked["v2"] {
+		t.Errorf("requests must be split between the versions of alias, got %v", picked)
+	}
+}
+
+func TestLookupAliasWeights(t *testing.T) {
+	table := NewTable()
+	table.Set([]Route{
+		{Namespace: "kess-samples", Function: "sample", Version: "v1"},
+		{Namespace: "kess-samples", Function: "sample", Version: "v2"},
+	}, []Alias{{
+		Namespace: "kess-samples",
+		Function:  "sample",
+		Alias:     "prod",
+		Versions:  []AliasVersion{{Version: "v1", Weight: 95}, {Version: "v2", Weight: 5}, {Version: "v3", Weight: 0}},
+		Rules:     []AliasRule{{Headers: map[string]string{"X-Canary": "true"}, Version: "v2"}},
+	}})
+
+	counts := make(map[string]int)
+	for n := 0; n < 100; n++ {
+		n := n
+		table.pick = func(total int) int {
+			if total != 100 {
+				t.Fatalf("weights of routed versions must sum to 100, got %d", total)
+			}
+			return n
+		}
+		route, _ := table.Lookup("kess-samples", "sample", "prod", nil)
+		counts[route.Version]++
+	}
+	if counts["v1"] != 95 || counts["v2"] != 5 {
+		t.Errorf("requests must be split by weight, got %v", counts)
+	}
+
+	table.pick = func(int) int { return 0 }
+	header := http.Header{}
+	header.Set("x-canary", "true")
+	if route, _ := table.Lookup("kess-samples", "sample", "prod", header); route.Version != "v2" {
+		t.Errorf("requests matching a rule must be routed to its version, got %+v", route)
+	}
+	header.Set("x-canary", "false")
+	if route, _ := table.Lookup("kess-samples", "sample", "prod", header); route.Version != "v1" {
+		t.Errorf("requests not matching any rule must be split by weight, got %+v", route)
+	}
+}
+
+func TestLookupNamespaces(t *testing.T) {
+	table := NewTable()
+	table.Set([]Route{
+		{Namespace: "team-a", Function: "sample", Version: "v1", Host: "a"},
+		{Namespace: "team-b", Function: "sample", Version: "v1", Host: "b"},
+		{Namespace: "team-b", Function: "sample", Version: "v2", Host: "b"},
+	}, []Alias{
+		{Namespace: "team-a", Function: "sample", Alias: "prod", Versions: []AliasVersion{{Version: "v1", Weight: 100}}},
+		{Namespace: "team-a", Function: "sample", Alias: "next", Versions: []AliasVersion{{Version: "v2", Weight: 100}}},
+	})
+
+	if route, _ := table.Lookup("team-a", "sample", "v1", nil); route.Host != "a" {
+		t.Errorf("a function version must be routed in its own namespace, got %+v", route)
+	}
+	if route, _ := table.Lookup("team-a", "sample", "", nil); route.Namespace != "team-a" || route.Version != "v1" {
+		t.Errorf("latest version must not be taken from another namespace, got %+v", route)
+	}
+	if route, ok := table.Lookup("team-a", "sample", "prod", nil); !ok || route.Host != "a" {
+		t.Errorf("alias must be routed to the versions of its namespace, got %+v", route)
+	}
+	if route, ok := table.Lookup("team-a", "sample", "next", nil); ok {
+		t.Errorf("alias must not be routed to a version of another namespace, got %+v", route)
+	}
+	if route, ok := table.Lookup("team-b", "sample", "prod", nil); ok {
+		t.Errorf("alias of another namespace must not be routed, got %+v", route)
+	}
+}
+
+func testAlias(name string, versions ...string) corev1.FunctionAlias {
+	alias := corev1.FunctionAlias{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kess-samples"}}
+	alias.Default()
+	for _, version := range versions {
+		alias.Spec.Versions = append(alias.Spec.Versions, corev1.FunctionAliasVersion{Version: version})
+	}
+	alias.UpdateStatusVersions([]corev1.Function{
+		testFunction("sample-v1", "sample"),
+		testFunction("sample-v2", "sample"),
+	})
+	return alias
+}
+
+func TestGateway(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(strings.Join([]string{
+			req.URL.Path,
+			req.Header.Get(HeaderFunction),
+			req.Header.Get(HeaderVersion),
+			req.Header.Get(HeaderMount),
+			req.Header.Get(HeaderFile),
+			req.Header.Get(HeaderHandler),
+		}, " ")))
+	}))
+	defer backend.Close()
+
+	table := NewTable()
+	table.Set([]Route{
+		{Namespace: "kess-samples", Function: "sample", Version: "v1", Host: strings.TrimPrefix(backend.URL, "http://"), Mount: "/kess/fn/sample", File: "/kess/fn/sample/v1.py"},
+		{Namespace: "kess-samples", Function: "sample", Version: "v2", Host: strings.TrimPrefix(backend.URL, "http://"), Mount: "/kess/fn/sample", File: "/kess/fn/sample/v2.py", Handler: "main"},
+	}, []Alias{
+		{
+			Namespace: "kess-samples",
+			Function:  "sample",
+			Alias:     "prod",
+			Versions:  []AliasVersion{{Version: "v1", Weight: 100}},
+			Rules:     []AliasRule{{Headers: map[string]string{"X-Canary": "true"}, Version: "v2"}},
+		},
+	})
+	g := NewGateway(table, "", ctrl.Log)
+	g.Namespace = "kess-samples"
+	gateway := httptest.NewServer(g)
+	defer gateway.Close()
+
+	cases := []struct {
+		path   string
+		header http.Header
+		status int
+		body   string
+	}{
+		{"/fn/kess-samples/sample/v1", nil, http.StatusOK, "/ sample v1 /kess/fn/sample /kess/fn/sample/v1.py "},
+		{"/fn/kess-samples/sample", nil, http.StatusOK, "/ sample v2 /kess/fn/sample /kess/fn/sample/v2.py main"},
+		{"/fn/kess-samples/sample/latest/items/1", nil, http.StatusOK, "/items/1 sample v2 /kess/fn/sample /kess/fn/sample/v2.py main"},
+		{"/fn/kess-samples/sample/prod/items", nil, http.StatusOK, "/items sample v1 /kess/fn/sample /kess/fn/sample/v1.py "},
+		{"/fn/kess-samples/sample/prod/items", http.Header{"X-Canary": {"true"}}, http.StatusOK, "/items sample v2 /kess/fn/sample /kess/fn/sample/v2.py main"},
+		{"/fn/sample/v1", nil, http.StatusOK, "/ sample v1 /kess/fn/sample /kess/fn/sample/v1.py "},
+		{"/fn/sample", nil, http.StatusOK, "/ sample v2 /kess/fn/sample /kess/fn/sample/v2.py main"},
+		{"/fn/sample/prod/items", nil, http.StatusOK, "/items sample v1 /kess/fn/sample /kess/fn/sample/v1.py "},
+		{"/fn/kess-samples/sample/v3", nil, http.StatusNotFound, ""},
+		{"/fn/sample/v3", nil, http.StatusNotFound, ""},
+		{"/fn/other/sample/v1", nil, http.StatusNotFound, ""},
+		{"/fn/kess-samples", nil, http.StatusNotFound, ""},
+		{"/fn/", nil, http.StatusNotFound, ""},
+		{"/other", nil, http.StatusNotFound, ""},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest(http.MethodGet, gateway.URL+c.path, nil)
+		for key, values := range c.header {
+			req.Header[key] = values
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Errorf("%s: unexpected status %d", c.path, resp.StatusCode)
+			continue
+		}
+		if c.status == http.StatusOK && string(body) != c.body {
+			t.Errorf("%s: unexpected body %q, want %q", c.path, body, c.body)
+		}
+	}
+
+	if count := testutil.ToFloat64(requestsTotal.WithLabelValues("kess-samples", "sample", "v2", "prod", "200")); count != 1 {
+		t.Errorf("requests routed by alias prod to v2 must be counted once, got %v", count)
+	}
+}
@@ -0,0 +1,54 @@
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// requestsTotal counts the requests routed to each function version, with the alias they were looked up by
+var requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "kess_gateway_requests_total",
+	Help: "Total number of requests routed by the gateway per function version.",
+}, []string{"namespace", "function", "version", "alias", "code"})
+
+func init() {
+	metrics.Registry.MustRegister(requestsTotal)
+}
+
+// statusRecorder records the status code written to the response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader bulabula
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write bulabula
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush supports streaming responses through the reverse proxy
+func (w *statusRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// observe counts a request routed by route
+func observe(route Route, status int) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	requestsTotal.WithLabelValues(route.Namespace, route.Function, route.Version, route.Alias, strconv.Itoa(status)).Inc()
+}
@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+)
+
+// Reconciler rebuilds the route table whenever a function, runtime or function alias changes
+type Reconciler struct {
+	client.Client
+	Log   logr.Logger
+	Table *Table
+
+	// Namespace limits the routes to a single namespace, all namespaces when empty
+	Namespace string
+}
+
+// +kubebuilder:rbac:groups=core.kess.io,resources=functions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core.kess.io,resources=runtimes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core.kess.io,resources=functionaliases,verbs=get;list;watch
+
+// Reconcile bulabula
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	var (
+		ctx     = context.Background()
+		fns     corev1.FunctionList
+		rts     corev1.RuntimeList
+		aliases corev1.FunctionAliasList
+		opts    []client.ListOption
+	)
+
+	if r.Namespace != "" {
+		opts = append(opts, client.InNamespace(r.Namespace))
+	}
+	if err := r.List(ctx, &fns, opts...); err != nil {
+		r.Log.Error(err, "unable to list functions")
+		return ctrl.Result{}, err
+	}
+	if err := r.List(ctx, &rts, opts...); err != nil {
+		r.Log.Error(err, "unable to list runtimes")
+		return ctrl.Result{}, err
+	}
+	if err := r.List(ctx, &aliases, opts...); err != nil {
+		r.Log.Error(err, "unable to list function aliases")
+		return ctrl.Result{}, err
+	}
+
+	r.Table.Update(fns.Items, rts.Items, aliases.Items)
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager bulabula
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Every change rebuilds the whole table, so all events share a single request
+	all := &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(handler.MapObject) []reconcile.Request {
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "routes"}}}
+		}),
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("gateway").
+		Watches(&source.Kind{Type: &corev1.Function{}}, all).
+		Watches(&source.Kind{Type: &corev1.Runtime{}}, all).
+		Watches(&source.Kind{Type: &corev1.FunctionAlias{}}, all).
+		Complete(r)
+}
@@ -0,0 +1,270 @@
+package gateway
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"path"
+	"sync"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+)
+
+// Route is where requests to a function version are forwarded to
+type Route struct {
+	Namespace string
+	Function  string
+	Version   string
+	Runtime   string
+
+	// Host is the address of the runtime service
+	Host string
+
+	// Mount is the mount path of the function config map in the runtime pods, File is the function file under it,
+	// or the directory of function when it has many files
+	Mount string
+	File  string
+
+	// Handler is the entrypoint of function, if any
+	Handler string
+
+	// Alias is the function alias the route is looked up by, if any
+	Alias string
+
+	// Async is the retry policy of asynchronous invocations of the function version, if any
+	Async *corev1.FunctionAsync
+}
+
+// Alias is a function alias resolved to concrete versions
+type Alias struct {
+	Namespace string
+	Function  string
+	Alias     string
+	Versions  []AliasVersion
+	Rules     []AliasRule
+}
+
+// AliasVersion is a version of alias and the weight of requests it receives
+type AliasVersion struct {
+	Version string
+	Weight  int32
+}
+
+// AliasRule routes requests carrying all of the headers to a version of alias
+type AliasRule struct {
+	Headers map[string]string
+	Version string
+}
+
+// Match reports whether header carries all of the headers of rule
+func (r AliasRule) Match(header http.Header) bool {
+	for key, value := range r.Headers {
+		if header.Get(key) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// functionKey identifies a function, the same function name in two namespaces is two functions
+type functionKey struct {
+	namespace string
+	function  string
+}
+
+// Table holds the routes of all function versions and aliases, keyed by namespace and function, then by version or alias
+type Table struct {
+	mu      sync.RWMutex
+	routes  map[functionKey]map[string]Route
+	aliases map[functionKey]map[string]Alias
+
+	// pick chooses a number in [0, n) to split requests between the versions of an alias by weight
+	pick func(n int) int
+}
+
+// NewTable bulabula
+func NewTable() *Table {
+	return &Table{
+		routes:  make(map[functionKey]map[string]Route),
+		aliases: make(map[functionKey]map[string]Alias),
+		pick:    rand.Intn,
+	}
+}
+
+// Lookup returns the route of a function version or alias in namespace, versions take precedence over aliases of
+// the same name, the latest version is looked up when version is empty or latest, header is matched against the
+// rules of aliases
+func (t *Table) Lookup(namespace, function, version string, header http.Header) (Route, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	key := functionKey{namespace: namespace, function: function}
+	versions, ok := t.routes[key]
+	if !ok {
+		return Route{}, false
+	}
+	if version == "" {
+		version = corev1.LatestVersion
+	}
+	if route, ok := versions[version]; ok {
+		return route, true
+	}
+	if alias, ok := t.aliases[key][version]; ok {
+		return t.lookupAlias(versions, alias, header)
+	}
+	if version != corev1.LatestVersion {
+		return Route{}, false
+	}
+
+	var latest *Route
+	for _, route := range versions {
+		route := route
+		if latest == nil || corev1.CompareVersions(route.Version, latest.Version) > 0 {
+			latest = &route
+		}
+	}
+	return *latest, true
+}
+
+// lookupAlias routes requests by the first rule of alias matching header, or else splits requests between
+// the versions of alias which are routed by their weights
+func (t *Table) lookupAlias(versions map[string]Route, alias Alias, header http.Header) (Route, bool) {
+	routed := func(version string) (Route, bool) {
+		route, ok := versions[version]
+		if !ok {
+			return Route{}, false
+		}
+		route.Alias = alias.Alias
+		return route, true
+	}
+
+	for _, rule := range alias.Rules {
+		if rule.Match(header) {
+			return routed(rule.Version)
+		}
+	}
+
+	var (
+		routes  []Route
+		weights []int32
+		total   int32
+	)
+	for _, version := range alias.Versions {
+		if version.Weight <= 0 {
+			continue
+		}
+		if route, ok := routed(version.Version); ok {
+			routes = append(routes, route)
+			weights = append(weights, version.Weight)
+			total += version.Weight
+		}
+	}
+	if total == 0 {
+		return Route{}, false
+	}
+
+	n := int32(t.pick(int(total)))
+	for i, weight := range weights {
+		if n < weight {
+			return routes[i], true
+		}
+		n -= weight
+	}
+	return routes[len(routes)-1], true
+}
+
+// Set replaces all routes and aliases
+func (t *Table) Set(routes []Route, aliases []Alias) {
+	table := make(map[functionKey]map[string]Route)
+	for _, route := range routes {
+		key := functionKey{namespace: route.Namespace, function: route.Function}
+		versions, ok := table[key]
+		if !ok {
+			versions = make(map[string]Route)
+			table[key] = versions
+		}
+		versions[route.Version] = route
+	}
+
+	aliasTable := make(map[functionKey]map[string]Alias)
+	for _, alias := range aliases {
+		key := functionKey{namespace: alias.Namespace, function: alias.Function}
+		names, ok := aliasTable[key]
+		if !ok {
+			names = make(map[string]Alias)
+			aliasTable[key] = names
+		}
+		names[alias.Alias] = alias
+	}
+
+	t.mu.Lock()
+	t.routes = table
+	t.aliases = aliasTable
+	t.mu.Unlock()
+}
+
+// Update replaces all routes with the functions mounted by their runtime and the resolved function aliases
+func (t *Table) Update(fns []corev1.Function, rts []corev1.Runtime, aliases []corev1.FunctionAlias) {
+	t.Set(Routes(fns, rts), Aliases(aliases))
+}
+
+// Aliases returns the function aliases with the versions and rules resolved in their status
+func Aliases(aliases []corev1.FunctionAlias) []Alias {
+	var result []Alias
+	for _, alias := range aliases {
+		if !alias.DeletionTimestamp.IsZero() || len(alias.Status.Versions) == 0 && len(alias.Status.Rules) == 0 {
+			continue
+		}
+		versions := make([]AliasVersion, 0, len(alias.Status.Versions))
+		for _, target := range alias.Status.Versions {
+			versions = append(versions, AliasVersion{Version: target.Version, Weight: target.Weight})
+		}
+		rules := make([]AliasRule, 0, len(alias.Status.Rules))
+		for _, rule := range alias.Status.Rules {
+			rules = append(rules, AliasRule{Headers: rule.Headers, Version: rule.Version})
+		}
+		result = append(result, Alias{
+			Namespace: alias.Namespace,
+			Function:  alias.Spec.Function,
+			Alias:     alias.Spec.Alias,
+			Versions:  versions,
+			Rules:     rules,
+		})
+	}
+	return result
+}
+
+// Routes returns the routes of the functions which are mounted by their runtime
+func Routes(fns []corev1.Function, rts []corev1.Runtime) []Route {
+	runtimes := make(map[string]*corev1.Runtime, len(rts))
+	for i := range rts {
+		runtimes[rts[i].NamespacedName().String()] = &rts[i]
+	}
+
+	var routes []Route
+	for _, fn := range fns {
+		if !fn.DeletionTimestamp.IsZero() {
+			continue
+		}
+		rt, ok := runtimes[fn.RuntimeNamespacedName().String()]
+		if !ok {
+			continue
+		}
+		mounted, ok := rt.Status.Functions[fn.RuntimeConfigMap().Name]
+		if !ok {
+			continue
+		}
+		routes = append(routes, Route{
+			Namespace: fn.Namespace,
+			Function:  fn.Spec.Function,
+			Version:   fn.Spec.Version,
+			Runtime:   rt.Name,
+			Host:      fmt.Sprintf("%s.%s.svc:%d", rt.Name, rt.Namespace, rt.Spec.Port),
+			Mount:     mounted.Mount,
+			File:      path.Join(mounted.Mount, fn.FileKey()),
+			Handler:   fn.Spec.Handler,
+			Async:     fn.Spec.Async.DeepCopy(),
+		})
+	}
+	return routes
+}
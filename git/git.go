@@ -0,0 +1,282 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRef is fetched when no ref is given, the default branch of repository
+const DefaultRef = "HEAD"
+
+// ErrNotFound is returned when a file is not in a commit
+var ErrNotFound = errors.New("file is not found")
+
+// ErrNotDir is returned when a directory is read but the path is a file
+var ErrNotDir = errors.New("path is not a directory")
+
+// symlinkMode is the mode of symbolic links in trees
+const symlinkMode = "120000"
+
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// MinVersion is the oldest git passing configuration through the environment with GIT_CONFIG_COUNT
+var MinVersion = [2]int{2, 31}
+
+var versionPattern = regexp.MustCompile(`^git version (\d+)\.(\d+)`)
+
+// Repository is a remote git repository, Username and Password are sent with basic authentication when set
+type Repository struct {
+	URL      string
+	Username string
+	Password string
+}
+
+// Fetcher reads the files of remote repositories through the git command, every repository is fetched into
+// a bare mirror of Dir, so a commit is fetched once and read locally afterwards, credentials are passed
+// through the environment of git, which requires git 2.31 or later
+type Fetcher struct {
+	// Command is the git binary, git from PATH when empty
+	Command string
+
+	// Dir keeps the mirrors of repositories
+	Dir string
+
+	// Timeout bounds every git command, unbounded when zero
+	Timeout time.Duration
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// NewFetcher bulabula
+func NewFetcher(dir string) *Fetcher {
+	return &Fetcher{Command: "git", Dir: dir, Timeout: time.Minute}
+}
+
+// CheckVersion fails unless git is MinVersion or later
+func (f *Fetcher) CheckVersion(ctx context.Context) error {
+	out, err := f.run(ctx, "", Repository{}, "--version")
+	if err != nil {
+		return err
+	}
+	version := strings.TrimSpace(string(out))
+	if !supportedVersion(version) {
+		return fmt.Errorf("git %d.%d or later is required, found %q", MinVersion[0], MinVersion[1], version)
+	}
+	return nil
+}
+
+// supportedVersion reports whether the output of git --version is MinVersion or later
+func supportedVersion(version string) bool {
+	match := versionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return false
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	return major > MinVersion[0] || major == MinVersion[0] && minor >= MinVersion[1]
+}
+
+// Resolve fetches ref from repo and returns the sha of its commit, ref is a branch, a tag, a full commit sha
+// or HEAD when empty
+func (f *Fetcher) Resolve(ctx context.Context, repo Repository, ref string) (string, error) {
+	if ref == "" {
+		ref = DefaultRef
+	}
+
+	unlock := f.lock(repo.URL)
+	defer unlock()
+
+	dir, err := f.mirror(ctx, repo)
+	if err != nil {
+		return "", err
+	}
+	if commitPattern.MatchString(ref) && f.hasCommit(ctx, dir, ref) {
+		return ref, nil
+	}
+	if _, err := f.run(ctx, dir, repo, "fetch", "--no-tags", "--force", "--", repo.URL, ref); err != nil {
+		return "", err
+	}
+	out, err := f.run(ctx, dir, Repository{}, "rev-parse", "--verify", "FETCH_HEAD^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ReadFile returns the content of file at commit of repo, the commit is fetched when the mirror misses it
+func (f *Fetcher) ReadFile(ctx context.Context, repo Repository, commit, file string) ([]byte, error) {
+	if !commitPattern.MatchString(commit) {
+		return nil, fmt.Errorf("invalid commit %q", commit)
+	}
+	file = path.Clean(strings.TrimPrefix(file, "/"))
+
+	unlock := f.lock(repo.URL)
+	defer unlock()
+
+	dir, err := f.mirrorCommit(ctx, repo, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	object := fmt.Sprintf("%s:%s", commit, file)
+	if _, err := f.run(ctx, dir, Repository{}, "cat-file", "-e", object); err != nil {
+		return nil, fmt.Errorf("%w: %s at %s", ErrNotFound, file, commit)
+	}
+	return f.run(ctx, dir, Repository{}, "cat-file", "blob", object)
+}
+
+// ReadDir returns the files under dir at commit of repo by their slash separated paths relative to dir,
+// ErrNotDir is returned when dir is a file, submodules and symbolic links are skipped
+func (f *Fetcher) ReadDir(ctx context.Context, repo Repository, commit, dir string) (map[string][]byte, error) {
+	if !commitPattern.MatchString(commit) {
+		return nil, fmt.Errorf("invalid commit %q", commit)
+	}
+	dir = path.Clean(strings.TrimPrefix(dir, "/"))
+
+	unlock := f.lock(repo.URL)
+	defer unlock()
+
+	mirror, err := f.mirrorCommit(ctx, repo, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	object := commit + ":"
+	if dir != "." {
+		object += dir
+	}
+	out, err := f.run(ctx, mirror, Repository{}, "cat-file", "-t", object)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s at %s", ErrNotFound, dir, commit)
+	}
+	if objectType := strings.TrimSpace(string(out)); objectType != "tree" {
+		return nil, fmt.Errorf("%w: %s is a %s at %s", ErrNotDir, dir, objectType, commit)
+	}
+
+	out, err = f.run(ctx, mirror, Repository{}, "ls-tree", "-r", "-z", object)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	for _, entry := range bytes.Split(out, []byte{0}) {
+		// every entry is "<mode> <type> <object>\t<path>"
+		tab := bytes.IndexByte(entry, '\t')
+		if tab < 0 {
+			continue
+		}
+		fields := strings.Fields(string(entry[:tab]))
+		if len(fields) != 3 || fields[1] != "blob" || fields[0] == symlinkMode {
+			continue
+		}
+		data, err := f.run(ctx, mirror, Repository{}, "cat-file", "blob", fields[2])
+		if err != nil {
+			return nil, err
+		}
+		files[string(entry[tab+1:])] = data
+	}
+	return files, nil
+}
+
+// lock serializes the commands run on the mirror of url
+func (f *Fetcher) lock(url string) func() {
+	f.mu.Lock()
+	if f.locks == nil {
+		f.locks = make(map[string]*sync.Mutex)
+	}
+	l, ok := f.locks[url]
+	if !ok {
+		l = &sync.Mutex{}
+		f.locks[url] = l
+	}
+	f.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// mirror returns the bare mirror of repo, which is initialized when missing
+func (f *Fetcher) mirror(ctx context.Context, repo Repository) (string, error) {
+	sum := sha256.Sum256([]byte(repo.URL))
+	dir := filepath.Join(f.Dir, hex.EncodeToString(sum[:8]))
+	if _, err := os.Stat(filepath.Join(dir, "HEAD")); err == nil {
+		return dir, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if _, err := f.run(ctx, dir, Repository{}, "init", "--bare", "--quiet", "."); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// mirrorCommit returns the bare mirror of repo once commit is fetched into it
+func (f *Fetcher) mirrorCommit(ctx context.Context, repo Repository, commit string) (string, error) {
+	dir, err := f.mirror(ctx, repo)
+	if err != nil {
+		return "", err
+	}
+	if !f.hasCommit(ctx, dir, commit) {
+		if _, err := f.run(ctx, dir, repo, "fetch", "--no-tags", "--", repo.URL, commit); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+func (f *Fetcher) hasCommit(ctx context.Context, dir, commit string) bool {
+	_, err := f.run(ctx, dir, Repository{}, "cat-file", "-e", commit+"^{commit}")
+	return err == nil
+}
+
+// run runs git in dir and returns its output, the credentials of repo are sent as an http header
+func (f *Fetcher) run(ctx context.Context, dir string, repo Repository, args ...string) ([]byte, error) {
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+
+	command := f.Command
+	if command == "" {
+		command = "git"
+	}
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_CONFIG_NOSYSTEM=1")
+	if repo.Username != "" || repo.Password != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(repo.Username + ":" + repo.Password))
+		cmd.Env = append(cmd.Env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+credentials,
+		)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
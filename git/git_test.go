@@ -0,0 +1,205 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testRepository is a bare repository pushed to from a work tree
+type testRepository struct {
+	t    *testing.T
+	bare string
+	work string
+}
+
+func newTestRepository(t *testing.T, dir string) *testRepository {
+	r := &testRepository{t: t, bare: filepath.Join(dir, "remote.git"), work: filepath.Join(dir, "work")}
+	r.git(dir, "init", "--bare", "--quiet", "--initial-branch=main", r.bare)
+	r.git(dir, "clone", "--quiet", r.bare, r.work)
+	return r
+}
+
+func (r *testRepository) git(dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=kess", "-c", "user.email=kess@kess.io"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s: %s: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commit commits files and pushes them to the bare repository, returning the commit
+func (r *testRepository) commit(files map[string]string) string {
+	for name, content := range files {
+		file := filepath.Join(r.work, name)
+		os.MkdirAll(filepath.Dir(file), 0755)
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+	r.git(r.work, "add", "--all")
+	r.git(r.work, "commit", "--quiet", "-m", "update")
+	r.git(r.work, "push", "--quiet", "origin", "HEAD:main")
+	return r.git(r.work, "rev-parse", "HEAD")
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "kess-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestFetcher(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRepository(t, dir)
+	first := remote.commit(map[string]string{"functions/hello.py": "def hello(): return 'v1'\n"})
+	remote.git(remote.work, "tag", "v1")
+	remote.git(remote.work, "push", "--quiet", "origin", "v1")
+	second := remote.commit(map[string]string{"functions/hello.py": "def hello(): return 'v2'\n"})
+
+	ctx := context.Background()
+	repo := Repository{URL: "file://" + remote.bare}
+	fetcher := NewFetcher(filepath.Join(dir, "cache"))
+
+	for ref, want := range map[string]string{"": second, "main": second, "v1": first, first: first} {
+		commit, err := fetcher.Resolve(ctx, repo, ref)
+		if err != nil || commit != want {
+			t.Errorf("ref %q must resolve to %s, got %s, %v", ref, want, commit, err)
+		}
+	}
+	if _, err := fetcher.Resolve(ctx, repo, "missing"); err == nil {
+		t.Errorf("missing ref must fail")
+	}
+
+	data, err := fetcher.ReadFile(ctx, repo, first, "/functions/hello.py")
+	if err != nil || string(data) != "def hello(): return 'v1'\n" {
+		t.Errorf("unexpected file %q, %v", data, err)
+	}
+	if _, err := fetcher.ReadFile(ctx, repo, first, "functions/missing.py"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing file must not be found, got %v", err)
+	}
+
+	// A pinned commit is still read once the branch moved, by a fetcher which never fetched it
+	remote.commit(map[string]string{"functions/hello.py": "def hello(): return 'v3'\n"})
+	data, err = NewFetcher(filepath.Join(dir, "restarted")).ReadFile(ctx, repo, second, "functions/hello.py")
+	if err != nil || string(data) != "def hello(): return 'v2'\n" {
+		t.Errorf("unexpected pinned file %q, %v", data, err)
+	}
+}
+
+func TestFetcherReadDir(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRepository(t, dir)
+	commit := remote.commit(map[string]string{
+		"functions/hello/main.py":      "from lib.greet import greet\n",
+		"functions/hello/lib/greet.py": "def greet(): return 'hello'\n",
+		"README.md":                    "# samples\n",
+	})
+
+	ctx := context.Background()
+	repo := Repository{URL: "file://" + remote.bare}
+	fetcher := NewFetcher(filepath.Join(dir, "cache"))
+
+	files, err := fetcher.ReadDir(ctx, repo, commit, "/functions/hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"main.py": "from lib.greet import greet\n", "lib/greet.py": "def greet(): return 'hello'\n"}
+	if len(files) != len(want) {
+		t.Errorf("unexpected files %v", files)
+	}
+	for file, data := range want {
+		if string(files[file]) != data {
+			t.Errorf("unexpected file %s %q", file, files[file])
+		}
+	}
+	if files, err := fetcher.ReadDir(ctx, repo, commit, ""); err != nil || len(files) != 3 {
+		t.Errorf("root must hold every file, got %v, %v", files, err)
+	}
+	if _, err := fetcher.ReadDir(ctx, repo, commit, "README.md"); !errors.Is(err, ErrNotDir) {
+		t.Errorf("file must not be read as a directory, got %v", err)
+	}
+	if _, err := fetcher.ReadDir(ctx, repo, commit, "functions/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing directory must not be found, got %v", err)
+	}
+}
+
+func TestFetcherCredentials(t *testing.T) {
+	backend, err := exec.Command("git", "--exec-path").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	remote := newTestRepository(t, dir)
+	commit := remote.commit(map[string]string{"hello.js": "module.exports = () => 'hello'\n"})
+
+	cgiHandler := &cgi.Handler{
+		Path: filepath.Join(strings.TrimSpace(string(backend)), "git-http-backend"),
+		Env:  []string{"GIT_PROJECT_ROOT=" + dir, "GIT_HTTP_EXPORT_ALL=1"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if username, password, ok := req.BasicAuth(); !ok || username != "ci" || password != "token" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="kess"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		cgiHandler.ServeHTTP(w, req)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	fetcher := NewFetcher(filepath.Join(dir, "cache"))
+
+	repo := Repository{URL: server.URL + "/remote.git"}
+	if _, err := fetcher.Resolve(ctx, repo, "main"); err == nil {
+		t.Errorf("fetching without credentials must fail")
+	}
+
+	repo.Username, repo.Password = "ci", "token"
+	resolved, err := fetcher.Resolve(ctx, repo, "main")
+	if err != nil || resolved != commit {
+		t.Fatalf("main must resolve to %s, got %s, %v", commit, resolved, err)
+	}
+	data, err := fetcher.ReadFile(ctx, repo, commit, "hello.js")
+	if err != nil || string(data) != "module.exports = () => 'hello'\n" {
+		t.Errorf("unexpected file %q, %v", data, err)
+	}
+}
+
+func TestSupportedVersion(t *testing.T) {
+	cases := map[string]bool{
+		"git version 2.31.0":                 true,
+		"git version 2.39.2 (Apple Git-143)": true,
+		"git version 3.0.0":                  true,
+		"git version 2.30.2":                 false,
+		"git version 1.8.3.1":                false,
+		"not git":                            false,
+	}
+	for version, supported := range cases {
+		if supportedVersion(version) != supported {
+			t.Errorf("supportedVersion(%q) must be %v", version, supported)
+		}
+	}
+
+	if err := NewFetcher("").CheckVersion(context.Background()); err != nil {
+		t.Errorf("git of tests must be supported, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package invoker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+)
+
+// Headers sent along with the invocations of triggers
+const (
+	HeaderTrigger      = "X-Kess-Trigger"
+	HeaderScheduleTime = "X-Kess-Schedule-Time"
+	HeaderMQTTTopic    = "X-Kess-Mqtt-Topic"
+	HeaderMQTTQoS      = "X-Kess-Mqtt-Qos"
+	HeaderEventType    = "X-Kess-Event-Type"
+)
+
+// Invoker invokes functions through the gateway on behalf of triggers
+type Invoker struct {
+	Client     *http.Client
+	GatewayURL string
+}
+
+// New bulabula
+func New(gatewayURL string) *Invoker {
+	return &Invoker{
+		Client:     &http.Client{},
+		GatewayURL: strings.TrimSuffix(gatewayURL, "/"),
+	}
+}
+
+// ErrResponseTooLarge is returned for responses longer than the limit of Call
+var ErrResponseTooLarge = errors.New("response is too large")
+
+// Invoke sends body to target in namespace and records the outcome, the response body is discarded
+func (i *Invoker) Invoke(ctx context.Context, namespace string, target *corev1.TriggerTarget, body []byte, header http.Header) corev1.TriggerInvocation {
+	invocation, _ := i.call(ctx, namespace, target, body, header, func(r io.Reader) ([]byte, error) {
+		_, err := io.Copy(ioutil.Discard, r)
+		return nil, err
+	})
+	return invocation
+}
+
+// Call sends body to target in namespace and records the outcome along with the response body, which fails the invocation
+// when it is longer than limit
+func (i *Invoker) Call(ctx context.Context, namespace string, target *corev1.TriggerTarget, body []byte, header http.Header,
+	limit int64) (corev1.TriggerInvocation, []byte) {
+	return i.call(ctx, namespace, target, body, header, func(r io.Reader) ([]byte, error) {
+		data, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+		if err == nil && int64(len(data)) > limit {
+			return nil, ErrResponseTooLarge
+		}
+		return data, err
+	})
+}
+
+func (i *Invoker) call(ctx context.Context, namespace string, target *corev1.TriggerTarget, body []byte, header http.Header,
+	read func(io.Reader) ([]byte, error)) (corev1.TriggerInvocation, []byte) {
+	var (
+		start      = time.Now()
+		invocation = corev1.TriggerInvocation{Time: metav1.Time{Time: start}}
+		response   []byte
+	)
+
+	if target.Timeout.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, target.Timeout.Duration)
+		defer cancel()
+	}
+
+	req, err := http.NewRequest(target.Method, i.GatewayURL+target.URLPath(namespace), bytes.NewReader(body))
+	if err != nil {
+		invocation.Error = err.Error()
+		return invocation, nil
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	for key, value := range target.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := i.Client.Do(req.WithContext(ctx))
+	if err == nil {
+		response, err = read(resp.Body)
+		resp.Body.Close()
+		invocation.StatusCode = int32(resp.StatusCode)
+	}
+	if err != nil {
+		invocation.Error = err.Error()
+	}
+	invocation.Duration.Duration = time.Since(start)
+	return invocation, response
+}
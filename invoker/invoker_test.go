@@ -0,0 +1,49 @@
+package invoker
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+)
+
+func TestInvoke(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req
+		body, _ = ioutil.ReadAll(req.Body)
+		if req.URL.Path == "/fn/default/slow/latest" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	invoker := New(server.URL + "/")
+	target := &corev1.TriggerTarget{Function: "sample", Version: "prod", Path: "/jobs", Headers: map[string]string{"X-Source": "cron"}}
+	target.Default()
+
+	invocation := invoker.Invoke(context.Background(), "default", target, []byte(`{"ok":true}`), http.Header{"X-Trigger": {"nightly"}})
+	if !invocation.Succeeded() || invocation.StatusCode != http.StatusAccepted {
+		t.Fatalf("unexpected invocation %+v", invocation)
+	}
+	if got.Method != http.MethodPost || got.URL.Path != "/fn/default/sample/prod/jobs" || string(body) != `{"ok":true}` {
+		t.Errorf("unexpected request %s %s %q", got.Method, got.URL.Path, body)
+	}
+	if got.Header.Get("X-Source") != "cron" || got.Header.Get("X-Trigger") != "nightly" {
+		t.Errorf("headers must be forwarded, got %v", got.Header)
+	}
+
+	slow := &corev1.TriggerTarget{Function: "slow", Timeout: metav1.Duration{Duration: 50 * time.Millisecond}}
+	slow.Default()
+	if invocation := invoker.Invoke(context.Background(), "default", slow, nil, nil); invocation.Succeeded() || invocation.Error == "" {
+		t.Errorf("invocation must time out, got %+v", invocation)
+	}
+}
@@ -17,8 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"path/filepath"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -27,7 +30,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/archive"
 	"github.com/yamajik/kess/controllers"
+	"github.com/yamajik/kess/git"
+	"github.com/yamajik/kess/invoker"
+	"github.com/yamajik/kess/workflow"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -45,8 +52,26 @@ func init() {
 
 func main() {
 	var metricsAddr string
+	var activityAddr string
+	var activityURL string
+	var activityInterval time.Duration
+	var activatorImage string
+	var gatewayURL string
+	var gitCacheDir string
+	var archiveCacheDir string
 	var enableLeaderElection bool
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&activityAddr, "activity-addr", ":8082", "The address the activity endpoint of activators binds to.")
+	flag.StringVar(&activityURL, "activity-url", "http://kess-controller-manager-activity.kess-system.svc:8082/activity",
+		"The URL activators report the activity of scale-to-zero runtimes to.")
+	flag.DurationVar(&activityInterval, "activity-min-interval", 30*time.Second,
+		"How far the last activity of a running runtime is moved forward at least, which bounds how often it is patched.")
+	flag.StringVar(&activatorImage, "activator-image", "yamajik/kess:latest", "The image of activators, providing /activator.")
+	flag.StringVar(&gatewayURL, "gateway-url", "http://kess-gateway.kess-system.svc", "The URL triggers invoke functions through.")
+	flag.StringVar(&gitCacheDir, "git-cache-dir", filepath.Join(os.TempDir(), "kess-git"),
+		"The directory keeping the git repositories functions are fetched from.")
+	flag.StringVar(&archiveCacheDir, "archive-cache-dir", filepath.Join(os.TempDir(), "kess-archives"),
+		"The directory keeping the archives functions and libraries are fetched from.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
@@ -60,6 +85,7 @@ func main() {
 		Port:               9443,
 		LeaderElection:     enableLeaderElection,
 		LeaderElectionID:   "abff1558.kess.io",
+		NewClient:          controllers.NewClient,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -67,37 +93,115 @@ func main() {
 	}
 
 	if err = (&controllers.RuntimeReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Runtime"),
-		Scheme: mgr.GetScheme(),
+		Client:         mgr.GetClient(),
+		Log:            ctrl.Log.WithName("controllers").WithName("Runtime"),
+		Scheme:         mgr.GetScheme(),
+		ActivatorImage: activatorImage,
+		ActivityURL:    activityURL,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Runtime")
 		os.Exit(1)
 	}
+	if err = (&controllers.ActivityServer{
+		Client:      mgr.GetClient(),
+		Log:         ctrl.Log.WithName("activity"),
+		Addr:        activityAddr,
+		MinInterval: activityInterval,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create activity server")
+		os.Exit(1)
+	}
+	archiveFetcher := archive.NewFetcher(archiveCacheDir)
+	gitFetcher := git.NewFetcher(gitCacheDir)
+	if err = gitFetcher.CheckVersion(context.Background()); err != nil {
+		setupLog.Error(err, "unable to fetch functions with git")
+		os.Exit(1)
+	}
 	if err = (&controllers.FunctionReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Function"),
-		Scheme: mgr.GetScheme(),
+		Client:  mgr.GetClient(),
+		Log:     ctrl.Log.WithName("controllers").WithName("Function"),
+		Scheme:  mgr.GetScheme(),
+		Git:     gitFetcher,
+		Archive: archiveFetcher,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Function")
 		os.Exit(1)
 	}
 	if err = (&controllers.LibraryReconciler{
+		Client:  mgr.GetClient(),
+		Log:     ctrl.Log.WithName("controllers").WithName("Library"),
+		Scheme:  mgr.GetScheme(),
+		Archive: archiveFetcher,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Library")
+		os.Exit(1)
+	}
+	if err = (&controllers.FunctionAliasReconciler{
 		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Library"),
+		Log:    ctrl.Log.WithName("controllers").WithName("FunctionAlias"),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Library")
+		setupLog.Error(err, "unable to create controller", "controller", "FunctionAlias")
+		os.Exit(1)
+	}
+	if err = (&controllers.RolloutReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("Rollout"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Rollout")
+		os.Exit(1)
+	}
+	if err = (&controllers.CronTriggerReconciler{
+		Client:  mgr.GetClient(),
+		Log:     ctrl.Log.WithName("controllers").WithName("CronTrigger"),
+		Scheme:  mgr.GetScheme(),
+		Invoker: invoker.New(gatewayURL),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "CronTrigger")
+		os.Exit(1)
+	}
+	if err = (&controllers.MQTTTriggerReconciler{
+		Client:  mgr.GetClient(),
+		Log:     ctrl.Log.WithName("controllers").WithName("MQTTTrigger"),
+		Scheme:  mgr.GetScheme(),
+		Invoker: invoker.New(gatewayURL),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "MQTTTrigger")
+		os.Exit(1)
+	}
+	if err = (&controllers.K8sEventTriggerReconciler{
+		Client:  mgr.GetClient(),
+		Log:     ctrl.Log.WithName("controllers").WithName("K8sEventTrigger"),
+		Scheme:  mgr.GetScheme(),
+		Invoker: invoker.New(gatewayURL),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "K8sEventTrigger")
 		os.Exit(1)
 	}
-	if err = (&controllers.DeploymentReconciler{
+	if err = (&controllers.WorkflowReconciler{
 		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Deployment"),
+		Log:    ctrl.Log.WithName("controllers").WithName("Workflow"),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Deployment")
+		setupLog.Error(err, "unable to create controller", "controller", "Workflow")
 		os.Exit(1)
 	}
+	if err = (&controllers.WorkflowRunReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("WorkflowRun"),
+		Scheme:   mgr.GetScheme(),
+		Executor: workflow.NewExecutor(invoker.New(gatewayURL)),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "WorkflowRun")
+		os.Exit(1)
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = corev1.SetupWebhooksWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhooks")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
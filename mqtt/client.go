@@ -0,0 +1,422 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/yamajik/kess/mqtt/internal/packet"
+)
+
+// DefaultKeepAlive is the keep alive interval of clients when not set
+const DefaultKeepAlive = 30 * time.Second
+
+// writeTimeout bounds every write to the broker
+const writeTimeout = 10 * time.Second
+
+// ErrClosed is returned by operations on a closed client
+var ErrClosed = errors.New("mqtt: client closed")
+
+// connackErrors are the reasons of refused connections by return code
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Message is an application message published to or received from a broker
+type Message struct {
+	Topic     string
+	Payload   []byte
+	QoS       byte
+	Retain    bool
+	Duplicate bool
+}
+
+// Handler handles the messages of subscriptions, QoS 1 and 2 messages are acknowledged once it returns
+type Handler func(Message)
+
+// Options bulabula
+type Options struct {
+	// Optional client identifier, the broker assigns one when empty
+	ClientID string
+
+	// Optional credentials, a password is only sent along with a user name
+	Username string
+	Password string
+
+	// Optional keep alive interval, defaults to 30s
+	KeepAlive time.Duration
+
+	// Optional TLS configuration of ssl:// and tls:// brokers
+	TLSConfig *tls.Config
+
+	// Optional handler of messages
+	Handler Handler
+}
+
+// Client is a minimal MQTT 3.1.1 client, sessions are clean and messages are handled one at a time in order
+type Client struct {
+	opts   Options
+	conn   net.Conn
+	reader *bufio.Reader
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	nextID   uint16
+	pending  map[uint16]chan packet.Packet
+	received map[uint16]bool
+
+	deliveries chan packet.PublishPacket
+	done       chan struct{}
+	closeOnce  sync.Once
+	err        error
+}
+
+// Dial connects to broker, a tcp://, mqtt://, ssl://, tls:// or mqtts:// URL
+func Dial(ctx context.Context, broker string, opts Options) (*Client, error) {
+	if opts.Password != "" && opts.Username == "" {
+		return nil, errors.New("mqtt: password requires a user name")
+	}
+
+	u, err := url.Parse(broker)
+	if err != nil {
+		return nil, err
+	}
+
+	var secure bool
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		secure = true
+	default:
+		return nil, fmt.Errorf("mqtt: unsupported broker scheme %q", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		port := "1883"
+		if secure {
+			port = "8883"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if secure {
+		config := &tls.Config{}
+		if opts.TLSConfig != nil {
+			config = opts.TLSConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = u.Hostname()
+		}
+		conn = tls.Client(conn, config)
+	}
+
+	if opts.KeepAlive == 0 {
+		opts.KeepAlive = DefaultKeepAlive
+	}
+	c := &Client{
+		opts:       opts,
+		conn:       conn,
+		reader:     bufio.NewReader(conn),
+		pending:    make(map[uint16]chan packet.Packet),
+		received:   make(map[uint16]bool),
+		deliveries: make(chan packet.PublishPacket, 16),
+		done:       make(chan struct{}),
+	}
+	if err := c.connect(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go c.readLoop()
+	go c.deliverLoop()
+	go c.pingLoop()
+	return c, nil
+}
+
+func (c *Client) connect(ctx context.Context) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(writeTimeout)
+	}
+	c.conn.SetDeadline(deadline)
+	defer c.conn.SetDeadline(time.Time{})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	flags := byte(0x02)
+	if c.opts.Username != "" {
+		flags |= 0x80
+	}
+	if c.opts.Password != "" {
+		flags |= 0x40
+	}
+	e := &packet.Encoder{}
+	e.String("MQTT").Byte(4).Byte(flags).Uint16(uint16(c.opts.KeepAlive / time.Second))
+	e.String(c.opts.ClientID)
+	if c.opts.Username != "" {
+		e.String(c.opts.Username)
+	}
+	if c.opts.Password != "" {
+		e.String(c.opts.Password)
+	}
+	if err := packet.Write(c.conn, packet.Packet{Type: packet.Connect, Body: e.Buf}); err != nil {
+		return err
+	}
+
+	p, err := packet.Read(c.reader)
+	if err != nil {
+		return err
+	}
+	if p.Type != packet.Connack || len(p.Body) != 2 {
+		return packet.ErrMalformed
+	}
+	if code := p.Body[1]; code != 0 {
+		reason, ok := connackErrors[code]
+		if !ok {
+			reason = fmt.Sprintf("return code %d", code)
+		}
+		return fmt.Errorf("mqtt: connection refused: %s", reason)
+	}
+	return nil
+}
+
+// Subscribe subscribes to filter at qos and returns the QoS granted by the broker
+func (c *Client) Subscribe(ctx context.Context, filter string, qos byte) (byte, error) {
+	if err := ValidateFilter(filter); err != nil {
+		return 0, err
+	}
+	if qos > 2 {
+		return 0, fmt.Errorf("mqtt: invalid qos %d", qos)
+	}
+
+	resp, err := c.request(ctx, func(id uint16) packet.Packet {
+		e := (&packet.Encoder{}).Uint16(id).String(filter).Byte(qos)
+		return packet.Packet{Type: packet.Subscribe, Flags: 0x02, Body: e.Buf}
+	})
+	if err != nil {
+		return 0, err
+	}
+	d := &packet.Decoder{Buf: resp.Body}
+	d.Uint16()
+	granted := d.Byte()
+	if d.Err != nil {
+		return 0, d.Err
+	}
+	if granted == 0x80 {
+		return 0, fmt.Errorf("mqtt: subscription to %q was refused", filter)
+	}
+	return granted, nil
+}
+
+// Publish publishes msg, QoS 1 messages return once acknowledged, QoS 2 is not supported
+func (c *Client) Publish(ctx context.Context, msg Message) error {
+	if err := ValidateTopic(msg.Topic); err != nil {
+		return err
+	}
+	switch msg.QoS {
+	case 0:
+		return c.write(packet.PublishPacket{Message: packet.Message(msg)}.Packet())
+	case 1:
+		_, err := c.request(ctx, func(id uint16) packet.Packet {
+			return packet.PublishPacket{Message: packet.Message(msg), PacketID: id}.Packet()
+		})
+		return err
+	}
+	return fmt.Errorf("mqtt: publishing at qos %d is not supported", msg.QoS)
+}
+
+// Close disconnects from the broker
+func (c *Client) Close() error {
+	select {
+	case <-c.done:
+	default:
+		c.write(packet.Packet{Type: packet.Disconnect})
+	}
+	c.close(ErrClosed)
+	return nil
+}
+
+// Done is closed once the connection is lost or closed
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection was lost, ErrClosed once closed
+func (c *Client) Err() error {
+	<-c.done
+	return c.err
+}
+
+func (c *Client) close(err error) {
+	c.closeOnce.Do(func() {
+		c.err = err
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+func (c *Client) write(p packet.Packet) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := packet.Write(c.conn, p); err != nil {
+		c.close(err)
+		return err
+	}
+	return nil
+}
+
+// request writes the packet built with a new packet identifier and waits for the acknowledgement
+func (c *Client) request(ctx context.Context, build func(id uint16) packet.Packet) (packet.Packet, error) {
+	ack := make(chan packet.Packet, 1)
+
+	c.mu.Lock()
+	for {
+		c.nextID++
+		if _, ok := c.pending[c.nextID]; c.nextID != 0 && !ok {
+			break
+		}
+	}
+	id := c.nextID
+	c.pending[id] = ack
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(build(id)); err != nil {
+		return packet.Packet{}, err
+	}
+	select {
+	case p := <-ack:
+		return p, nil
+	case <-ctx.Done():
+		return packet.Packet{}, ctx.Err()
+	case <-c.done:
+		return packet.Packet{}, c.err
+	}
+}
+
+func (c *Client) readLoop() {
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(c.opts.KeepAlive * 3 / 2))
+		p, err := packet.Read(c.reader)
+		if err != nil {
+			c.close(err)
+			return
+		}
+
+		switch p.Type {
+		case packet.Publish:
+			pub, err := packet.DecodePublish(p)
+			if err != nil {
+				c.close(err)
+				return
+			}
+			select {
+			case c.deliveries <- pub:
+			case <-c.done:
+				return
+			}
+		case packet.Pubrel:
+			id, err := packet.ID(p)
+			if err != nil {
+				c.close(err)
+				return
+			}
+			c.mu.Lock()
+			delete(c.received, id)
+			c.mu.Unlock()
+			c.write(packet.Ack(packet.Pubcomp, id))
+		case packet.Puback, packet.Pubrec, packet.Pubcomp, packet.Suback, packet.Unsuback:
+			id, err := packet.ID(p)
+			if err != nil {
+				c.close(err)
+				return
+			}
+			c.mu.Lock()
+			if ack, ok := c.pending[id]; ok {
+				select {
+				case ack <- p:
+				default:
+				}
+			}
+			c.mu.Unlock()
+		case packet.Pingresp:
+		default:
+			c.close(packet.ErrMalformed)
+			return
+		}
+	}
+}
+
+// deliverLoop hands messages to the handler and acknowledges them afterwards, a QoS 2 message redelivered
+// before its release is acknowledged without being handled again
+func (c *Client) deliverLoop() {
+	for {
+		select {
+		case pub := <-c.deliveries:
+			handle := true
+			if pub.QoS == 2 {
+				c.mu.Lock()
+				handle = !c.received[pub.PacketID]
+				c.received[pub.PacketID] = true
+				c.mu.Unlock()
+			}
+			if handle && c.opts.Handler != nil {
+				c.opts.Handler(Message(pub.Message))
+			}
+
+			switch pub.QoS {
+			case 1:
+				c.write(packet.Ack(packet.Puback, pub.PacketID))
+			case 2:
+				c.write(packet.Ack(packet.Pubrec, pub.PacketID))
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Client) pingLoop() {
+	ticker := time.NewTicker(c.opts.KeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.write(packet.Packet{Type: packet.Pingreq})
+		case <-c.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Package packet encodes and decodes the control packets of MQTT 3.1.1 for the client and the test broker
+package packet
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Packet types of MQTT 3.1.1
+const (
+	Connect     byte = 1
+	Connack     byte = 2
+	Publish     byte = 3
+	Puback      byte = 4
+	Pubrec      byte = 5
+	Pubrel      byte = 6
+	Pubcomp     byte = 7
+	Subscribe   byte = 8
+	Suback      byte = 9
+	Unsubscribe byte = 10
+	Unsuback    byte = 11
+	Pingreq     byte = 12
+	Pingresp    byte = 13
+	Disconnect  byte = 14
+)
+
+// maxRemainingLength is the largest remaining length encodable in four bytes
+const maxRemainingLength = 268435455
+
+// ErrMalformed is returned for packets not following the protocol
+var ErrMalformed = errors.New("mqtt: malformed packet")
+
+// Packet is a control packet, body is everything after the fixed header
+type Packet struct {
+	Type  byte
+	Flags byte
+	Body  []byte
+}
+
+// Read reads a packet from r
+func Read(r *bufio.Reader) (Packet, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return Packet{}, err
+	}
+
+	var length int
+	for i := 0; ; i++ {
+		if i == 4 {
+			return Packet{}, ErrMalformed
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return Packet{}, err
+		}
+		length += int(b&0x7f) << uint(7*i)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	p := Packet{Type: first >> 4, Flags: first & 0x0f, Body: make([]byte, length)}
+	if _, err := io.ReadFull(r, p.Body); err != nil {
+		return Packet{}, err
+	}
+	return p, nil
+}
+
+// Write writes p to w at once
+func Write(w io.Writer, p Packet) error {
+	length := len(p.Body)
+	if length > maxRemainingLength {
+		return fmt.Errorf("mqtt: packet of %d bytes is too large", length)
+	}
+
+	buf := make([]byte, 0, 5+length)
+	buf = append(buf, p.Type<<4|p.Flags)
+	for {
+		b := byte(length & 0x7f)
+		length >>= 7
+		if length > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if length == 0 {
+			break
+		}
+	}
+	buf = append(buf, p.Body...)
+	_, err := w.Write(buf)
+	return err
+}
+
+// Encoder builds the body of a packet in Buf
+type Encoder struct {
+	Buf []byte
+}
+
+// Byte appends a byte
+func (e *Encoder) Byte(b byte) *Encoder {
+	e.Buf = append(e.Buf, b)
+	return e
+}
+
+// Uint16 appends a big endian two byte integer
+func (e *Encoder) Uint16(n uint16) *Encoder {
+	e.Buf = append(e.Buf, byte(n>>8), byte(n))
+	return e
+}
+
+// String appends a length prefixed string
+func (e *Encoder) String(s string) *Encoder {
+	return e.Bytes([]byte(s))
+}
+
+// Bytes appends length prefixed bytes
+func (e *Encoder) Bytes(b []byte) *Encoder {
+	e.Uint16(uint16(len(b)))
+	e.Buf = append(e.Buf, b...)
+	return e
+}
+
+// Raw appends bytes as they are
+func (e *Encoder) Raw(b []byte) *Encoder {
+	e.Buf = append(e.Buf, b...)
+	return e
+}
+
+// Decoder reads the body of a packet from Buf, the first error sticks in Err
+type Decoder struct {
+	Buf []byte
+	Err error
+}
+
+// Byte reads a byte
+func (d *Decoder) Byte() byte {
+	if d.Err != nil || len(d.Buf) < 1 {
+		d.Err = ErrMalformed
+		return 0
+	}
+	b := d.Buf[0]
+	d.Buf = d.Buf[1:]
+	return b
+}
+
+// Uint16 reads a big endian two byte integer
+func (d *Decoder) Uint16() uint16 {
+	if d.Err != nil || len(d.Buf) < 2 {
+		d.Err = ErrMalformed
+		return 0
+	}
+	n := binary.BigEndian.Uint16(d.Buf)
+	d.Buf = d.Buf[2:]
+	return n
+}
+
+// Bytes reads length prefixed bytes
+func (d *Decoder) Bytes() []byte {
+	n := int(d.Uint16())
+	if d.Err != nil || len(d.Buf) < n {
+		d.Err = ErrMalformed
+		return nil
+	}
+	b := d.Buf[:n]
+	d.Buf = d.Buf[n:]
+	return b
+}
+
+// Text reads a length prefixed string, it is not named String so that printing a decoder does not consume it
+func (d *Decoder) Text() string {
+	return string(d.Bytes())
+}
+
+// Rest returns the unread bytes
+func (d *Decoder) Rest() []byte {
+	b := d.Buf
+	d.Buf = nil
+	return b
+}
+
+// Message is an application message, the client converts it from and to its own message of the same fields
+type Message struct {
+	Topic     string
+	Payload   []byte
+	QoS       byte
+	Retain    bool
+	Duplicate bool
+}
+
+// PublishPacket is the content of a PUBLISH packet
+type PublishPacket struct {
+	Message
+	PacketID uint16
+}
+
+// DecodePublish decodes the content of a PUBLISH packet
+func DecodePublish(p Packet) (PublishPacket, error) {
+	d := &Decoder{Buf: p.Body}
+	pub := PublishPacket{Message: Message{
+		Duplicate: p.Flags&0x08 != 0,
+		QoS:       (p.Flags >> 1) & 0x03,
+		Retain:    p.Flags&0x01 != 0,
+	}}
+	pub.Topic = d.Text()
+	if pub.QoS > 0 {
+		pub.PacketID = d.Uint16()
+	}
+	pub.Payload = append([]byte(nil), d.Rest()...)
+	if d.Err == nil && pub.QoS > 2 {
+		d.Err = ErrMalformed
+	}
+	return pub, d.Err
+}
+
+// Packet encodes pub
+func (pub PublishPacket) Packet() Packet {
+	e := &Encoder{}
+	e.String(pub.Topic)
+	if pub.QoS > 0 {
+		e.Uint16(pub.PacketID)
+	}
+	e.Raw(pub.Payload)
+
+	flags := pub.QoS << 1
+	if pub.Duplicate {
+		flags |= 0x08
+	}
+	if pub.Retain {
+		flags |= 0x01
+	}
+	return Packet{Type: Publish, Flags: flags, Body: e.Buf}
+}
+
+// Ack builds a packet carrying only a packet identifier
+func Ack(typ byte, id uint16) Packet {
+	var flags byte
+	if typ == Pubrel || typ == Subscribe || typ == Unsubscribe {
+		flags = 0x02
+	}
+	return Packet{Type: typ, Flags: flags, Body: (&Encoder{}).Uint16(id).Buf}
+}
+
+// ID decodes the packet identifier p starts with
+func ID(p Packet) (uint16, error) {
+	d := &Decoder{Buf: p.Body}
+	id := d.Uint16()
+	return id, d.Err
+}
@@ -0,0 +1,116 @@
+package mqtt_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yamajik/kess/mqtt"
+	"github.com/yamajik/kess/mqtt/mqtttest"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		filter, topic string
+		match         bool
+	}{
+		{"sensors/+/temperature", "sensors/kitchen/temperature", true},
+		{"sensors/+/temperature", "sensors/kitchen/humidity", false},
+		{"sensors/#", "sensors", true},
+		{"sensors/#", "sensors/kitchen/temperature", true},
+		{"sensors/kitchen", "sensors/kitchen/temperature", false},
+		{"sensors/kitchen/temperature", "sensors/kitchen", false},
+		{"#", "$SYS/uptime", false},
+		{"$SYS/#", "$SYS/uptime", true},
+	}
+	for _, c := range cases {
+		if match := mqtt.Match(c.filter, c.topic); match != c.match {
+			t.Errorf("Match(%q, %q) = %v, want %v", c.filter, c.topic, match, c.match)
+		}
+	}
+
+	for _, filter := range []string{"", "sensors/#/temperature", "sensors/kitchen#", "sensors/+kitchen"} {
+		if err := mqtt.ValidateFilter(filter); err == nil {
+			t.Errorf("filter %q must be invalid", filter)
+		}
+	}
+}
+
+// testBroker serves broker on a random local port and returns its URL
+func testBroker(t *testing.T, broker *mqtttest.Broker) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go broker.Serve(l)
+	return "tcp://" + l.Addr().String()
+}
+
+func TestClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	broker := &mqtttest.Broker{Authenticate: func(_, username, password string) bool {
+		return username == "device" && password == "secret"
+	}}
+	defer broker.Close()
+	url := testBroker(t, broker)
+
+	if _, err := mqtt.Dial(ctx, url, mqtt.Options{Password: "secret"}); err == nil {
+		t.Fatal("password without a user name must be rejected")
+	}
+	if _, err := mqtt.Dial(ctx, url, mqtt.Options{Username: "device", Password: "wrong"}); err == nil || !strings.Contains(err.Error(), "bad user name or password") {
+		t.Fatalf("connection with wrong credentials must be refused, got %v", err)
+	}
+
+	messages := make(chan mqtt.Message, 4)
+	subscriber, err := mqtt.Dial(ctx, url, mqtt.Options{
+		ClientID: "subscriber",
+		Username: "device",
+		Password: "secret",
+		Handler:  func(msg mqtt.Message) { messages <- msg },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscriber.Close()
+	if granted, err := subscriber.Subscribe(ctx, "sensors/+/temperature", 2); err != nil || granted != 1 {
+		t.Fatalf("subscription must be granted qos 1, got %d, %v", granted, err)
+	}
+
+	publisher, err := mqtt.Dial(ctx, url, mqtt.Options{Username: "device", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Close()
+	for _, msg := range []mqtt.Message{
+		{Topic: "sensors/kitchen/humidity", Payload: []byte("40"), QoS: 1},
+		{Topic: "sensors/kitchen/temperature", Payload: []byte("21.5"), QoS: 1},
+		{Topic: "sensors/garage/temperature", Payload: []byte("12"), QoS: 0},
+	} {
+		if err := publisher.Publish(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, want := range []mqtt.Message{
+		{Topic: "sensors/kitchen/temperature", Payload: []byte("21.5"), QoS: 1},
+		{Topic: "sensors/garage/temperature", Payload: []byte("12"), QoS: 0},
+	} {
+		select {
+		case msg := <-messages:
+			if msg.Topic != want.Topic || string(msg.Payload) != string(want.Payload) || msg.QoS != want.QoS {
+				t.Errorf("unexpected message %+v, want %+v", msg, want)
+			}
+		case <-ctx.Done():
+			t.Fatal("message was not delivered")
+		}
+	}
+
+	publisher.Close()
+	if err := publisher.Err(); err != mqtt.ErrClosed {
+		t.Errorf("closed client must report mqtt.ErrClosed, got %v", err)
+	}
+}
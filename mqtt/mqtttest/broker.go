@@ -0,0 +1,291 @@
+// Package mqtttest provides an in-process MQTT broker for tests of MQTT clients
+package mqtttest
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/yamajik/kess/mqtt"
+	"github.com/yamajik/kess/mqtt/internal/packet"
+)
+
+// writeTimeout bounds every write to a client
+const writeTimeout = 10 * time.Second
+
+// Broker is a minimal in-process MQTT 3.1.1 broker for tests, it grants QoS 1 at most,
+// keeps no sessions and retains no messages
+type Broker struct {
+	// Optional authentication of clients, every client is accepted when nil
+	Authenticate func(clientID, username, password string) bool
+
+	mu        sync.Mutex
+	listeners map[net.Listener]bool
+	sessions  map[*session]bool
+	closed    bool
+}
+
+type session struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+
+	mu            sync.Mutex
+	nextID        uint16
+	subscriptions map[string]byte
+}
+
+// Serve accepts clients on l until the broker is closed
+func (b *Broker) Serve(l net.Listener) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		l.Close()
+		return mqtt.ErrClosed
+	}
+	if b.listeners == nil {
+		b.listeners = make(map[net.Listener]bool)
+		b.sessions = make(map[*session]bool)
+	}
+	b.listeners[l] = true
+	b.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			b.mu.Lock()
+			closed := b.closed
+			b.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		go b.handle(conn)
+	}
+}
+
+// Close stops serving and disconnects every client
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for l := range b.listeners {
+		l.Close()
+	}
+	for s := range b.sessions {
+		s.conn.Close()
+	}
+	return nil
+}
+
+func (b *Broker) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(writeTimeout))
+	p, err := packet.Read(reader)
+	if err != nil || p.Type != packet.Connect {
+		return
+	}
+	keepAlive, code, err := b.accept(p)
+	if err != nil {
+		return
+	}
+	if err := packet.Write(conn, packet.Packet{Type: packet.Connack, Body: []byte{0, code}}); err != nil || code != 0 {
+		return
+	}
+
+	s := &session{conn: conn, subscriptions: make(map[string]byte)}
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.sessions[s] = true
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.sessions, s)
+		b.mu.Unlock()
+	}()
+
+	for {
+		deadline := time.Time{}
+		if keepAlive > 0 {
+			deadline = time.Now().Add(keepAlive * 3 / 2)
+		}
+		conn.SetReadDeadline(deadline)
+		p, err := packet.Read(reader)
+		if err != nil {
+			return
+		}
+
+		switch p.Type {
+		case packet.Publish:
+			pub, err := packet.DecodePublish(p)
+			if err != nil || mqtt.ValidateTopic(pub.Topic) != nil {
+				return
+			}
+			switch pub.QoS {
+			case 1:
+				s.write(packet.Ack(packet.Puback, pub.PacketID))
+			case 2:
+				s.write(packet.Ack(packet.Pubrec, pub.PacketID))
+			}
+			b.route(mqtt.Message(pub.Message))
+		case packet.Pubrel:
+			id, err := packet.ID(p)
+			if err != nil {
+				return
+			}
+			s.write(packet.Ack(packet.Pubcomp, id))
+		case packet.Subscribe:
+			if !s.subscribe(p) {
+				return
+			}
+		case packet.Unsubscribe:
+			if !s.unsubscribe(p) {
+				return
+			}
+		case packet.Pingreq:
+			s.write(packet.Packet{Type: packet.Pingresp})
+		case packet.Puback, packet.Pubrec, packet.Pubcomp:
+		default:
+			return
+		}
+	}
+}
+
+// accept decodes a CONNECT packet and returns the keep alive interval and the return code of CONNACK, the
+// connection is closed without CONNACK on errors
+func (b *Broker) accept(p packet.Packet) (time.Duration, byte, error) {
+	d := &packet.Decoder{Buf: p.Body}
+	protocol, level, flags := d.Text(), d.Byte(), d.Byte()
+	keepAlive := time.Duration(d.Uint16()) * time.Second
+	clientID := d.Text()
+	if flags&0x04 != 0 {
+		d.Text()
+		d.Bytes()
+	}
+	if flags&0x40 != 0 && flags&0x80 == 0 {
+		// a password without a user name is a protocol violation, MQTT 3.1.1 section 3.1.2.9
+		return 0, 0, packet.ErrMalformed
+	}
+	var username, password string
+	if flags&0x80 != 0 {
+		username = d.Text()
+	}
+	if flags&0x40 != 0 {
+		password = d.Text()
+	}
+
+	switch {
+	case d.Err != nil:
+		return 0, 0, d.Err
+	case protocol != "MQTT" || level != 4:
+		return 0, 1, nil
+	case b.Authenticate != nil && !b.Authenticate(clientID, username, password):
+		return 0, 4, nil
+	}
+	return keepAlive, 0, nil
+}
+
+// route sends msg to every session subscribed to its topic, once at the highest QoS of matching subscriptions
+func (b *Broker) route(msg mqtt.Message) {
+	msg.Retain, msg.Duplicate = false, false
+
+	b.mu.Lock()
+	sessions := make([]*session, 0, len(b.sessions))
+	for s := range b.sessions {
+		sessions = append(sessions, s)
+	}
+	b.mu.Unlock()
+
+	for _, s := range sessions {
+		s.mu.Lock()
+		matched, qos := false, byte(0)
+		for filter, granted := range s.subscriptions {
+			if mqtt.Match(filter, msg.Topic) {
+				matched = true
+				if granted > qos {
+					qos = granted
+				}
+			}
+		}
+		if !matched {
+			s.mu.Unlock()
+			continue
+		}
+		pub := packet.PublishPacket{Message: packet.Message(msg)}
+		if msg.QoS < qos {
+			qos = msg.QoS
+		}
+		pub.QoS = qos
+		if qos > 0 {
+			s.nextID++
+			if s.nextID == 0 {
+				s.nextID++
+			}
+			pub.PacketID = s.nextID
+		}
+		s.mu.Unlock()
+
+		s.write(pub.Packet())
+	}
+}
+
+func (s *session) subscribe(p packet.Packet) bool {
+	d := &packet.Decoder{Buf: p.Body}
+	id := d.Uint16()
+	e := (&packet.Encoder{}).Uint16(id)
+	for d.Err == nil && len(d.Buf) > 0 {
+		filter, qos := d.Text(), d.Byte()
+		if d.Err != nil {
+			break
+		}
+		if mqtt.ValidateFilter(filter) != nil || qos > 2 {
+			e.Byte(0x80)
+			continue
+		}
+		if qos > 1 {
+			qos = 1
+		}
+		s.mu.Lock()
+		s.subscriptions[filter] = qos
+		s.mu.Unlock()
+		e.Byte(qos)
+	}
+	if d.Err != nil {
+		return false
+	}
+	s.write(packet.Packet{Type: packet.Suback, Body: e.Buf})
+	return true
+}
+
+func (s *session) unsubscribe(p packet.Packet) bool {
+	d := &packet.Decoder{Buf: p.Body}
+	id := d.Uint16()
+	for d.Err == nil && len(d.Buf) > 0 {
+		filter := d.Text()
+		s.mu.Lock()
+		delete(s.subscriptions, filter)
+		s.mu.Unlock()
+	}
+	if d.Err != nil {
+		return false
+	}
+	s.write(packet.Ack(packet.Unsuback, id))
+	return true
+}
+
+func (s *session) write(p packet.Packet) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := packet.Write(s.conn, p); err != nil {
+		s.conn.Close()
+	}
+}
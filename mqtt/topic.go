@@ -0,0 +1,54 @@
+package mqtt
+
+import (
+	"errors"
+	"strings"
+)
+
+// ValidateFilter checks a topic filter, + matches a single level and # the remaining levels
+func ValidateFilter(filter string) error {
+	if filter == "" {
+		return errors.New("topic filter must not be empty")
+	}
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if strings.Contains(level, "#") && (level != "#" || i != len(levels)-1) {
+			return errors.New("# must be the last level of topic filter on its own")
+		}
+		if strings.Contains(level, "+") && level != "+" {
+			return errors.New("+ must be a level of topic filter on its own")
+		}
+	}
+	return nil
+}
+
+// ValidateTopic checks a topic name messages are published to
+func ValidateTopic(topic string) error {
+	if topic == "" {
+		return errors.New("topic must not be empty")
+	}
+	if strings.ContainsAny(topic, "+#") {
+		return errors.New("topic must not contain wildcards")
+	}
+	return nil
+}
+
+// Match reports whether topic matches filter, wildcards at the first level do not match topics starting with $
+func Match(filter, topic string) bool {
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+
+	filters, topics := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, f := range filters {
+		switch {
+		case f == "#":
+			return true
+		case i >= len(topics):
+			return false
+		case f != "+" && f != topics[i]:
+			return false
+		}
+	}
+	return len(filters) == len(topics)
+}
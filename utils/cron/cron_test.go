@@ -0,0 +1,51 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	from := time.Date(2021, time.January, 30, 10, 17, 30, 0, time.UTC) // Saturday
+
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2021, time.January, 30, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2021, time.January, 30, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17 * * mon-fri", time.Date(2021, time.February, 1, 9, 0, 0, 0, time.UTC)},
+		{"30 6 1 * *", time.Date(2021, time.February, 1, 6, 30, 0, 0, time.UTC)},
+		{"0 0 31 2-3 *", time.Date(2021, time.March, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 * 7", time.Date(2021, time.January, 31, 12, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2021, time.January, 30, 11, 0, 0, 0, time.UTC)},
+		{"5,10 10 * * *", time.Date(2021, time.January, 31, 10, 5, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		s, err := Parse(c.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", c.spec, err)
+		}
+		if next := s.Next(from); !next.Equal(c.want) {
+			t.Errorf("%s: next of %s is %s, want %s", c.spec, from, next, c.want)
+		}
+	}
+}
+
+func TestNextLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	s, _ := Parse("0 9 * * *")
+	next := s.Next(time.Date(2021, time.January, 30, 0, 0, 0, 0, time.UTC).In(loc))
+	if want := time.Date(2021, time.January, 30, 1, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("schedule must follow the location of t, got %s, want %s", next.UTC(), want)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("%q must be rejected", spec)
+		}
+	}
+}
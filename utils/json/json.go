@@ -0,0 +1,42 @@
+package json
+
+import (
+	"encoding/json"
+)
+
+// MarshalWithoutNulls marshals v and drops every null value, so that the result can be
+// used as a merge patch without deleting the fields which are merely unset in v
+func MarshalWithoutNulls(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var m interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(pruneNulls(m))
+}
+
+func pruneNulls(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if value == nil {
+				delete(v, key)
+				continue
+			}
+			v[key] = pruneNulls(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = pruneNulls(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
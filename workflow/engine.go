@@ -0,0 +1,290 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+)
+
+// MaxForeachItems bounds the items of foreach steps, so that the nodes of a run fit in its status
+const MaxForeachItems = 256
+
+// Invocation is a function step ready to be invoked with its json input
+type Invocation struct {
+	ID       string
+	Step     string
+	Function *corev1.WorkflowFunction
+	Input    []byte
+}
+
+// Result is the progress of a workflow run
+type Result struct {
+	// Finished reports whether run finished, it failed when Error is set
+	Finished bool
+	Output   []byte
+	Error    string
+
+	// Invocations are the function steps to invoke before run progresses further
+	Invocations []Invocation
+}
+
+// Evaluate walks the steps of workflow from its start step with the json input of run, the outputs of the
+// function steps which finished are taken from nodes, so a run resumes wherever it stopped, function steps
+// reached but not finished yet are returned to be invoked
+func Evaluate(spec *corev1.WorkflowSpec, input []byte, nodes []corev1.WorkflowNodeStatus) Result {
+	var value interface{}
+	if err := json.Unmarshal(input, &value); err != nil {
+		return Result{Finished: true, Error: fmt.Sprintf("invalid input: %s", err)}
+	}
+
+	e := &evaluator{spec: spec, nodes: make(map[string]*corev1.WorkflowNodeStatus, len(nodes))}
+	for i := range nodes {
+		e.nodes[nodes[i].ID] = &nodes[i]
+	}
+
+	start := spec.Start
+	if start == "" && len(spec.Steps) > 0 {
+		start = spec.Steps[0].Name
+	}
+	o := e.eval(start, start, value)
+	switch {
+	case o.err != nil:
+		return Result{Finished: true, Error: o.err.Error()}
+	case !o.done:
+		return Result{Invocations: e.invocations}
+	}
+
+	output, err := json.Marshal(o.output)
+	if err != nil {
+		return Result{Finished: true, Error: err.Error()}
+	}
+	return Result{Finished: true, Output: output}
+}
+
+// outcome is the output of a step which is done, or why it failed
+type outcome struct {
+	done   bool
+	output interface{}
+	err    error
+}
+
+func failed(err error) outcome {
+	return outcome{done: true, err: err}
+}
+
+type evaluator struct {
+	spec        *corev1.WorkflowSpec
+	nodes       map[string]*corev1.WorkflowNodeStatus
+	invocations []Invocation
+}
+
+// childID identifies the i-th child of the node id, which runs step
+func childID(id string, i int, step string) string {
+	return fmt.Sprintf("%s/%d/%s", id, i, step)
+}
+
+func (e *evaluator) eval(id, name string, input interface{}) outcome {
+	step := e.spec.Step(name)
+	if step == nil {
+		return failed(fmt.Errorf("step %q is not found", name))
+	}
+
+	inputPath, err := corev1.SplitWorkflowPath(step.InputPath)
+	if err != nil {
+		return failed(fmt.Errorf("step %q: %s", name, err))
+	}
+	stepInput, ok := lookup(input, inputPath)
+	if !ok {
+		return failed(fmt.Errorf("step %q: input path %q is not found", name, step.InputPath))
+	}
+
+	var o outcome
+	switch {
+	case step.Function != nil:
+		o = e.function(id, step, stepInput)
+	case len(step.Sequence) > 0:
+		o = e.sequence(id, step, stepInput)
+	case len(step.Parallel) > 0:
+		o = e.parallel(id, step, stepInput)
+	case step.Foreach != nil:
+		o = e.foreach(id, step, stepInput)
+	case step.Switch != nil:
+		o = e.branch(id, step, stepInput)
+	default:
+		return failed(fmt.Errorf("step %q has nothing to run", name))
+	}
+	if !o.done || o.err != nil || step.ResultPath == "" {
+		return o
+	}
+
+	resultPath, err := corev1.SplitWorkflowPath(step.ResultPath)
+	if err != nil {
+		return failed(fmt.Errorf("step %q: %s", name, err))
+	}
+	o.output = set(input, resultPath, o.output)
+	return o
+}
+
+// function returns the output of the node id, which is invoked when it did not finish yet
+func (e *evaluator) function(id string, step *corev1.WorkflowStep, input interface{}) outcome {
+	node, ok := e.nodes[id]
+	if !ok {
+		body, err := json.Marshal(input)
+		if err != nil {
+			return failed(fmt.Errorf("step %q: %s", step.Name, err))
+		}
+		e.invocations = append(e.invocations, Invocation{ID: id, Step: step.Name, Function: step.Function, Input: body})
+		return outcome{}
+	}
+
+	if node.Phase == corev1.FailedWorkflowRunPhase {
+		return failed(fmt.Errorf("step %q failed: %s", step.Name, node.Error))
+	}
+	var output interface{}
+	if node.Output != nil && len(node.Output.Raw) > 0 {
+		if err := json.Unmarshal(node.Output.Raw, &output); err != nil {
+			return failed(fmt.Errorf("step %q: invalid output: %s", step.Name, err))
+		}
+	}
+	return outcome{done: true, output: output}
+}
+
+// sequence passes the output of every step to the next one
+func (e *evaluator) sequence(id string, step *corev1.WorkflowStep, input interface{}) outcome {
+	for i, name := range step.Sequence {
+		o := e.eval(childID(id, i, name), name, input)
+		if !o.done || o.err != nil {
+			return o
+		}
+		input = o.output
+	}
+	return outcome{done: true, output: input}
+}
+
+// parallel runs every step with the same input, it fails as soon as one of them fails
+func (e *evaluator) parallel(id string, step *corev1.WorkflowStep, input interface{}) outcome {
+	var (
+		done    = true
+		outputs = make(map[string]interface{}, len(step.Parallel))
+	)
+	for i, name := range step.Parallel {
+		o := e.eval(childID(id, i, name), name, input)
+		if o.err != nil {
+			return o
+		}
+		if !o.done {
+			done = false
+			continue
+		}
+		outputs[name] = o.output
+	}
+	if !done {
+		return outcome{}
+	}
+	return outcome{done: true, output: outputs}
+}
+
+// foreach runs its step for every item, at most concurrency of them at once
+func (e *evaluator) foreach(id string, step *corev1.WorkflowStep, input interface{}) outcome {
+	foreach := step.Foreach
+	itemsPath, err := corev1.SplitWorkflowPath(foreach.ItemsPath)
+	if err != nil {
+		return failed(fmt.Errorf("step %q: %s", step.Name, err))
+	}
+	value, _ := lookup(input, itemsPath)
+	items, ok := value.([]interface{})
+	if !ok {
+		return failed(fmt.Errorf("step %q: items path %q is not an array", step.Name, foreach.ItemsPath))
+	}
+	if len(items) > MaxForeachItems {
+		return failed(fmt.Errorf("step %q: %d items exceed the limit of %d", step.Name, len(items), MaxForeachItems))
+	}
+
+	var (
+		done     = true
+		running  int32
+		outputs  = make([]interface{}, len(items))
+		parallel = foreach.Concurrency
+	)
+	for i, item := range items {
+		if parallel > 0 && running >= parallel {
+			done = false
+			break
+		}
+		o := e.eval(childID(id, i, foreach.Step), foreach.Step, item)
+		if o.err != nil {
+			return o
+		}
+		if !o.done {
+			running++
+			done = false
+			continue
+		}
+		outputs[i] = o.output
+	}
+	if !done {
+		return outcome{}
+	}
+	return outcome{done: true, output: outputs}
+}
+
+// branch runs the step of the first case holding, the input is passed through when no case holds without default
+func (e *evaluator) branch(id string, step *corev1.WorkflowStep, input interface{}) outcome {
+	cases := step.Switch.Cases
+	for i := range cases {
+		if matchAll(input, cases[i].Conditions) {
+			return e.eval(childID(id, i, cases[i].Step), cases[i].Step, input)
+		}
+	}
+	if name := step.Switch.Default; name != "" {
+		return e.eval(childID(id, len(cases), name), name, input)
+	}
+	return outcome{done: true, output: input}
+}
+
+func matchAll(input interface{}, conditions []corev1.WorkflowCondition) bool {
+	for i := range conditions {
+		if !match(input, &conditions[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// match reports whether condition holds for input, values compare as json and thresholds as numbers
+func match(input interface{}, condition *corev1.WorkflowCondition) bool {
+	path, err := corev1.SplitWorkflowPath(condition.Path)
+	if err != nil {
+		return false
+	}
+	value, found := lookup(input, path)
+
+	var expected interface{}
+	if err := json.Unmarshal([]byte(condition.Value), &expected); err != nil {
+		expected = condition.Value
+	}
+
+	switch condition.Operator {
+	case corev1.ExistsWorkflowOperator:
+		return found
+	case corev1.NotExistsWorkflowOperator:
+		return !found
+	case corev1.EqualsWorkflowOperator:
+		return found && reflect.DeepEqual(value, expected)
+	case corev1.NotEqualsWorkflowOperator:
+		return !found || !reflect.DeepEqual(value, expected)
+	case corev1.GreaterThanWorkflowOperator, corev1.LessThanWorkflowOperator:
+		number, ok := value.(float64)
+		threshold, isNumber := expected.(float64)
+		if !found || !ok || !isNumber {
+			return false
+		}
+		if condition.Operator == corev1.GreaterThanWorkflowOperator {
+			return number > threshold
+		}
+		return number < threshold
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/invoker"
+)
+
+func testFunction(name, function string, retries int32) corev1.WorkflowStep {
+	return corev1.WorkflowStep{
+		Name:     name,
+		Function: &corev1.WorkflowFunction{TriggerTarget: corev1.TriggerTarget{Function: function}, Retries: retries},
+	}
+}
+
+// run evaluates and executes run until it finished, returning the invocations of every wave
+func run(t *testing.T, executor *Executor, spec *corev1.WorkflowSpec, input string) (Result, [][]string) {
+	spec.Default()
+	r := &corev1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "orders-1", Namespace: "kess-samples"},
+		Spec:       corev1.WorkflowRunSpec{Workflow: "orders"},
+	}
+
+	var waves [][]string
+	for len(waves) < 20 {
+		result := Evaluate(spec, []byte(input), r.Status.Nodes)
+		if result.Finished {
+			return result, waves
+		}
+		var wave []string
+		for _, inv := range result.Invocations {
+			wave = append(wave, inv.ID)
+		}
+		waves = append(waves, wave)
+		r.UpdateStatusNodes(executor.RunAll(context.Background(), r, result.Invocations))
+	}
+	t.Fatalf("run did not finish after %d waves", len(waves))
+	return Result{}, nil
+}
+
+func TestEvaluate(t *testing.T) {
+	var flaky int32
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var input map[string]interface{}
+		body, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(body, &input)
+
+		switch strings.TrimPrefix(req.URL.Path, "/fn/kess-samples/") {
+		case "price/latest":
+			if req.Header.Get(HeaderWorkflowRun) != "orders-1" || req.Header.Get(HeaderWorkflowStep) != "price" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(input["quantity"].(float64) * 2)
+		case "flaky/latest":
+			if atomic.AddInt32(&flaky, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("checked"))
+		case "ship/latest":
+			json.NewEncoder(w).Encode(map[string]interface{}{"carrier": "express", "total": input["total"]})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer gateway.Close()
+	executor := NewExecutor(invoker.New(gateway.URL))
+	executor.RetryBackoff = 10 * time.Millisecond
+
+	spec := &corev1.WorkflowSpec{Steps: []corev1.WorkflowStep{
+		{Name: "main", Sequence: []string{"prepare", "route"}},
+		{Name: "prepare", Parallel: []string{"prices", "check"}},
+		{Name: "prices", Foreach: &corev1.WorkflowForeach{ItemsPath: "order.items", Step: "price", Concurrency: 2}},
+		testFunction("price", "price", 0),
+		testFunction("check", "flaky", 1),
+		{Name: "route", InputPath: "prices", ResultPath: "shipping", Switch: &corev1.WorkflowSwitch{
+			Cases: []corev1.WorkflowSwitchCase{{
+				Conditions: []corev1.WorkflowCondition{{Path: "0", Operator: corev1.GreaterThanWorkflowOperator, Value: "4"}},
+				Step:       "ship",
+			}},
+		}},
+		testFunction("ship", "ship", 0),
+	}}
+
+	result, waves := run(t, executor, spec, `{"order": {"items": [{"quantity": 3}, {"quantity": 1}, {"quantity": 2}]}}`)
+	if result.Error != "" {
+		t.Fatalf("run must succeed, got %s", result.Error)
+	}
+	wantWaves := [][]string{
+		{"main/0/prepare/0/prices/0/price", "main/0/prepare/0/prices/1/price", "main/0/prepare/1/check"},
+		{"main/0/prepare/0/prices/2/price"},
+		{"main/1/route/0/ship"},
+	}
+	if got, _ := json.Marshal(waves); string(got) != mustMarshal(wantWaves) {
+		t.Errorf("unexpected waves %s, want %s", got, mustMarshal(wantWaves))
+	}
+	want := `{"prices":[6,2,4],"check":"checked","shipping":{"carrier":"express","total":null}}`
+	if !jsonEqual(result.Output, want) {
+		t.Errorf("unexpected output %s, want %s", result.Output, want)
+	}
+
+	spec.Steps[0].Sequence = []string{"broken"}
+	spec.Steps = append(spec.Steps, testFunction("broken", "broken", 0))
+	if result, _ := run(t, executor, spec, `{}`); !result.Finished || result.Error != `step "broken" failed: function answered 500` {
+		t.Errorf("failed function must fail run, got %+v", result)
+	}
+
+	spec.Steps[0].Sequence = []string{"prices"}
+	if result, _ := run(t, executor, spec, `{"order": {}}`); result.Error != `step "prices": items path "order.items" is not an array` {
+		t.Errorf("missing items must fail run, got %+v", result)
+	}
+	items := `[` + strings.Repeat(`{"quantity": 1},`, MaxForeachItems) + `{"quantity": 1}]`
+	if result, _ := run(t, executor, spec, `{"order": {"items": `+items+`}}`); !strings.Contains(result.Error, "exceed the limit") {
+		t.Errorf("too many items must fail run, got %+v", result)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		w.Write([]byte(`"done"`))
+	}))
+	defer gateway.Close()
+	executor := NewExecutor(invoker.New(gateway.URL))
+	stop := make(chan struct{})
+	defer close(stop)
+	go executor.Start(stop)
+
+	r := &corev1.WorkflowRun{ObjectMeta: metav1.ObjectMeta{Name: "orders-1", Namespace: "kess-samples", UID: "1"}}
+	step := testFunction("price", "price", 0)
+	invocations := []Invocation{{ID: "main", Step: "price", Function: step.Function, Input: []byte(`{}`)}}
+	recorded := make(chan corev1.WorkflowNodeStatus, 4)
+	record := func(node corev1.WorkflowNodeStatus) error {
+		recorded <- node
+		return nil
+	}
+
+	executor.Dispatch(r, invocations, record)
+	executor.Dispatch(r, invocations, record)
+	close(release)
+	select {
+	case node := <-recorded:
+		if node.ID != "main" || node.Phase != corev1.SucceededWorkflowRunPhase || string(node.Output.Raw) != `"done"` {
+			t.Errorf("unexpected node %+v", node)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("node was not recorded")
+	}
+	if event := <-executor.events; event.Meta.GetName() != "orders-1" {
+		t.Errorf("run must be notified, got %s", event.Meta.GetName())
+	}
+
+	executor.Dispatch(r, invocations, record)
+	time.Sleep(50 * time.Millisecond)
+	if calls := atomic.LoadInt32(&calls); calls != 1 {
+		t.Errorf("running and recorded nodes must not be invoked again, got %d calls", calls)
+	}
+}
+
+func mustMarshal(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func jsonEqual(data []byte, want string) bool {
+	var got, expected interface{}
+	json.Unmarshal(data, &got)
+	json.Unmarshal([]byte(want), &expected)
+	return mustMarshal(got) == mustMarshal(expected)
+}
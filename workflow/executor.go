@@ -0,0 +1,239 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	corev1 "github.com/yamajik/kess/api/v1"
+	"github.com/yamajik/kess/invoker"
+)
+
+// Headers sent along with the invocations of function steps
+const (
+	HeaderWorkflow     = "X-Kess-Workflow"
+	HeaderWorkflowRun  = "X-Kess-Workflow-Run"
+	HeaderWorkflowStep = "X-Kess-Workflow-Step"
+	HeaderWorkflowNode = "X-Kess-Workflow-Node"
+)
+
+// MaxOutput bounds the output of function steps, which is kept in the status of runs
+const MaxOutput = 64 << 10
+
+// Executor invokes the function steps of workflow runs, either at once with RunAll or in the background with
+// Dispatch
+type Executor struct {
+	Invoker *invoker.Invoker
+
+	// Concurrency bounds the invocations of a RunAll, or of every dispatched run, at once, unbounded when zero
+	Concurrency int
+
+	// RetryBackoff is how long to wait before retrying a failed invocation
+	RetryBackoff time.Duration
+
+	events chan event.GenericEvent
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu    sync.Mutex
+	slots chan struct{}
+	runs  map[types.NamespacedName]*dispatched
+}
+
+// dispatched are the nodes of a run invoked in the background
+type dispatched struct {
+	uid types.UID
+
+	// running nodes are being invoked, done nodes were recorded and are not invoked again
+	running map[string]bool
+	done    map[string]bool
+}
+
+// NewExecutor bulabula
+func NewExecutor(inv *invoker.Invoker) *Executor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Executor{
+		Invoker:      inv,
+		Concurrency:  16,
+		RetryBackoff: time.Second,
+		events:       make(chan event.GenericEvent, 128),
+		ctx:          ctx,
+		cancel:       cancel,
+		runs:         make(map[types.NamespacedName]*dispatched),
+	}
+}
+
+// Source emits an event for a run whenever one of its dispatched nodes finished
+func (e *Executor) Source() source.Source {
+	return &source.Channel{Source: e.events}
+}
+
+// NeedLeaderElection is true, runs are only progressed by the leader so that functions are invoked once
+func (e *Executor) NeedLeaderElection() bool {
+	return true
+}
+
+// Start waits for stop and then interrupts the dispatched invocations
+func (e *Executor) Start(stop <-chan struct{}) error {
+	<-stop
+	e.cancel()
+	return nil
+}
+
+// Dispatch invokes the invocations of run in the background, skipping the ones which are running or were
+// recorded already, record is called with the node of every invocation once it finished, the node is not invoked
+// again unless record failed, then run is notified through Source
+func (e *Executor) Dispatch(run *corev1.WorkflowRun, invocations []Invocation, record func(corev1.WorkflowNodeStatus) error) {
+	run = run.DeepCopy()
+	key := run.NamespacedName()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.slots == nil && e.Concurrency > 0 {
+		e.slots = make(chan struct{}, e.Concurrency)
+	}
+	d := e.runs[key]
+	if d == nil || d.uid != run.UID {
+		d = &dispatched{uid: run.UID, running: make(map[string]bool), done: make(map[string]bool)}
+		e.runs[key] = d
+	}
+
+	for i := range invocations {
+		inv := invocations[i]
+		if d.running[inv.ID] || d.done[inv.ID] {
+			continue
+		}
+		d.running[inv.ID] = true
+		go e.dispatch(d, run, &inv, record)
+	}
+}
+
+func (e *Executor) dispatch(d *dispatched, run *corev1.WorkflowRun, inv *Invocation, record func(corev1.WorkflowNodeStatus) error) {
+	if e.slots != nil {
+		select {
+		case e.slots <- struct{}{}:
+			defer func() { <-e.slots }()
+		case <-e.ctx.Done():
+			return
+		}
+	}
+
+	node := e.Run(e.ctx, run, inv)
+	recorded := node.Phase != "" && record(node) == nil
+
+	e.mu.Lock()
+	delete(d.running, inv.ID)
+	if recorded {
+		d.done[inv.ID] = true
+	}
+	e.mu.Unlock()
+
+	select {
+	case e.events <- event.GenericEvent{Meta: run, Object: run}:
+	case <-e.ctx.Done():
+	}
+}
+
+// Forget drops what is known of the nodes dispatched for the run key, once it finished or was deleted
+func (e *Executor) Forget(key types.NamespacedName) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.runs, key)
+}
+
+// RunAll invokes all of invocations of run and returns their nodes in the same order, nodes interrupted by
+// the cancellation of ctx have no phase
+func (e *Executor) RunAll(ctx context.Context, run *corev1.WorkflowRun, invocations []Invocation) []corev1.WorkflowNodeStatus {
+	concurrency := e.Concurrency
+	if concurrency <= 0 {
+		concurrency = len(invocations)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		nodes = make([]corev1.WorkflowNodeStatus, len(invocations))
+		slots = make(chan struct{}, concurrency)
+	)
+	for i := range invocations {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			nodes[i] = e.Run(ctx, run, &invocations[i])
+		}(i)
+	}
+	wg.Wait()
+	return nodes
+}
+
+// Run invokes the function of a step, retrying failed invocations, a response which is not json is
+// the json string of its body, the node has no phase when ctx is cancelled before it finished
+func (e *Executor) Run(ctx context.Context, run *corev1.WorkflowRun, inv *Invocation) corev1.WorkflowNodeStatus {
+	node := corev1.WorkflowNodeStatus{
+		ID:        inv.ID,
+		Step:      inv.Step,
+		StartTime: &metav1.Time{Time: time.Now()},
+	}
+
+	header := http.Header{}
+	header.Set(HeaderWorkflow, run.Spec.Workflow)
+	header.Set(HeaderWorkflowRun, run.Name)
+	header.Set(HeaderWorkflowStep, inv.Step)
+	header.Set(HeaderWorkflowNode, inv.ID)
+	header.Set("Content-Type", "application/json")
+
+	for {
+		node.Attempts++
+		invocation, response := e.Invoker.Call(ctx, run.Namespace, &inv.Function.TriggerTarget, inv.Input, header, MaxOutput)
+		if ctx.Err() != nil {
+			return corev1.WorkflowNodeStatus{ID: inv.ID, Step: inv.Step}
+		}
+		if invocation.Succeeded() {
+			node.Phase = corev1.SucceededWorkflowRunPhase
+			node.Error = ""
+			node.Output = &apiextensionsv1.JSON{Raw: output(response)}
+			break
+		}
+
+		node.Phase = corev1.FailedWorkflowRunPhase
+		node.Error = invocation.Error
+		if node.Error == "" {
+			node.Error = fmt.Sprintf("function answered %d", invocation.StatusCode)
+		}
+		if node.Attempts > inv.Function.Retries {
+			break
+		}
+		select {
+		case <-time.After(e.RetryBackoff):
+		case <-ctx.Done():
+			return corev1.WorkflowNodeStatus{ID: inv.ID, Step: inv.Step}
+		}
+	}
+
+	node.CompletionTime = &metav1.Time{Time: time.Now()}
+	return node
+}
+
+// output returns response if it is json, otherwise the json string of response, an empty response is null
+func output(response []byte) []byte {
+	if len(response) == 0 {
+		return []byte("null")
+	}
+	if json.Valid(response) {
+		return response
+	}
+	data, _ := json.Marshal(string(response))
+	return data
+}
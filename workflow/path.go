@@ -0,0 +1,53 @@
+package workflow
+
+import (
+	"strconv"
+)
+
+// lookup returns the value at the keys and array indexes of path in value
+func lookup(value interface{}, path []string) (interface{}, bool) {
+	for _, segment := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			child, ok := v[segment]
+			if !ok {
+				return nil, false
+			}
+			value = child
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// set returns a copy of value with v at path, objects are created along path and replace values which are not
+// objects or arrays, value itself is not modified
+func set(value interface{}, path []string, v interface{}) interface{} {
+	if len(path) == 0 {
+		return v
+	}
+	segment, rest := path[0], path[1:]
+
+	if array, ok := value.([]interface{}); ok {
+		if i, err := strconv.Atoi(segment); err == nil && i >= 0 && i < len(array) {
+			out := append([]interface{}(nil), array...)
+			out[i] = set(array[i], rest, v)
+			return out
+		}
+	}
+
+	object, _ := value.(map[string]interface{})
+	out := make(map[string]interface{}, len(object)+1)
+	for key, child := range object {
+		out[key] = child
+	}
+	out[segment] = set(object[segment], rest, v)
+	return out
+}